@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 
 	"github.com/alexflint/go-arg"
 
@@ -23,10 +24,46 @@ type CLI struct {
 }
 
 type IngestCmd struct {
-	Directory string `arg:"positional,required" help:"Directory path to process"`
-	Model     string `arg:"--model" help:"Ollama model name" default:"nomic-embed-text"`
-	Output    string `arg:"--output" help:"Output file path" default:"storage/vectors.jsonl"`
-	ChunkSize int    `arg:"--chunk-size" help:"Chunk size in words" default:"300"`
+	Directory        string `arg:"positional,required" help:"Directory path to process"`
+	Model            string `arg:"--model" help:"Ollama model name" default:"nomic-embed-text"`
+	Output           string `arg:"--output" help:"Output file path" default:"storage/vectors.jsonl"`
+	ChunkSize        int    `arg:"--chunk-size" help:"Chunk size in words (or tokens with --chunk-unit=tokens)" default:"300"`
+	ChunkStrategy    string `arg:"--chunk-strategy" help:"Chunking strategy: word, sentence, semantic or recursive" default:"word"`
+	ChunkOverlap     int    `arg:"--chunk-overlap" help:"Words, tokens or sentences repeated from the previous chunk" default:"0"`
+	ChunkUnit        string `arg:"--chunk-unit" help:"Unit of --chunk-size: words or tokens" default:"words"`
+	Tokenizer        string `arg:"--tokenizer" help:"Path to a Hugging Face tokenizer.json used to count tokens"`
+	Code             bool   `arg:"--code" help:"Also ingest source code files, split on top-level declarations"`
+	MaxChars         int    `arg:"--max-chars" help:"Character limit of recursive chunks (0 for no limit)" default:"0"`
+	Normalize        string `arg:"--normalize" help:"Comma-separated normalization stages: nfc, nfkc, controls, quotes, dehyphenate, whitespace, lowercase"`
+	StripBoilerplate bool   `arg:"--strip-boilerplate" help:"Remove page numbers and repeated page headers and footers from .txt files before chunking"`
+	Languages        string `arg:"--languages" help:"Comma-separated ISO 639-1 codes of the languages kept, e.g. en,de; text chunks in other languages are skipped"`
+	IncludeExt       string `arg:"--include-ext" help:"Comma-separated extensions of the document formats ingested, e.g. .txt,.md,.html (default: every supported format)"`
+	Encoding         string `arg:"--encoding" help:"Input encoding: auto, utf-8, utf-16le, utf-16be, windows-1252 or iso-8859-1" default:"auto"`
+	HeaderTemplate   string `arg:"--header-template" help:"Go text/template embedded in place of each chunk, e.g. '{{.SourceFile}} — {{.Section}}\\n\\n{{.Text}}'"`
+
+	TextColumns     string `arg:"--text-columns" help:"Go text/template building the text of each CSV, TSV or JSONL row from its columns, e.g. '{{.name}}: {{.description}}' (default: every column)"`
+	IDColumn        string `arg:"--id-column" help:"Column of CSV, TSV or JSONL rows holding a stable ID used as the record id"`
+	MetadataColumns string `arg:"--metadata-columns" help:"Comma-separated columns of CSV, TSV or JSONL rows copied into each record's metadata"`
+
+	MinChunkSize   int    `arg:"--min-chunk-size" help:"Smallest final chunk of a file, in chunk units; smaller ones are absorbed (0 disables)" default:"0"`
+	MinChunkPolicy string `arg:"--min-chunk-policy" help:"How an undersized final chunk is absorbed: merge or rebalance" default:"merge"`
+
+	SemanticThreshold float64 `arg:"--semantic-threshold" help:"Percentile of sentence distances above which the semantic strategy cuts" default:"95"`
+	SemanticWindow    int     `arg:"--semantic-window" help:"Neighboring sentences on each side embedded with each sentence" default:"1"`
+	SemanticMinSize   int     `arg:"--semantic-min-size" help:"Smallest chunk, in chunk units, that a topic shift may end" default:"0"`
+
+	RedactMode  string `arg:"--redact-mode" help:"Handle personal data before it is embedded or written: mask, drop-chunk or report-only (disabled when empty)"`
+	RedactRules string `arg:"--redact-rules" help:"File of custom redaction rules, one 'TYPE regex' per line"`
+
+	SecretPolicy string `arg:"--secret-policy" help:"Handle API keys, tokens and private keys: skip-chunk, skip-file or mask (disabled when empty)"`
+	SecretReport string `arg:"--secret-report" help:"File the secrets found are appended to (defaults to <output>.secrets.jsonl)"`
+
+	Dedup          string  `arg:"--dedup" help:"Handle near-duplicate chunks: skip, link or count (disabled when empty)"`
+	DedupMethod    string  `arg:"--dedup-method" help:"Near-duplicate signature: simhash or minhash" default:"simhash"`
+	DedupThreshold float64 `arg:"--dedup-threshold" help:"Similarity from 0 to 1 above which chunks are near-duplicates" default:"0.9"`
+
+	ParentSize   int  `arg:"--parent-size" help:"Also emit parent chunks of this size, with --chunk-size children cut from them (0 disables)" default:"0"`
+	EmbedParents bool `arg:"--embed-parents" help:"Embed parent chunks instead of storing them as text only"`
 }
 
 func main() {
@@ -51,10 +88,46 @@ func main() {
 
 	// Create configuration
 	cfg := &config.Config{
-		Directory: cli.Ingest.Directory,
-		Model:     cli.Ingest.Model,
-		Output:    cli.Ingest.Output,
-		ChunkSize: cli.Ingest.ChunkSize,
+		Directory:        cli.Ingest.Directory,
+		Model:            cli.Ingest.Model,
+		Output:           cli.Ingest.Output,
+		ChunkSize:        cli.Ingest.ChunkSize,
+		ChunkStrategy:    cli.Ingest.ChunkStrategy,
+		ChunkOverlap:     cli.Ingest.ChunkOverlap,
+		ChunkUnit:        cli.Ingest.ChunkUnit,
+		TokenizerPath:    cli.Ingest.Tokenizer,
+		CodeFiles:        cli.Ingest.Code,
+		MaxChars:         cli.Ingest.MaxChars,
+		HeaderTemplate:   cli.Ingest.HeaderTemplate,
+		Normalize:        splitList(cli.Ingest.Normalize),
+		StripBoilerplate: cli.Ingest.StripBoilerplate,
+		Encoding:         cli.Ingest.Encoding,
+		IncludeExt:       splitList(cli.Ingest.IncludeExt),
+		Languages:        splitList(cli.Ingest.Languages),
+
+		TextColumns:     cli.Ingest.TextColumns,
+		IDColumn:        cli.Ingest.IDColumn,
+		MetadataColumns: splitList(cli.Ingest.MetadataColumns),
+
+		MinChunkSize:   cli.Ingest.MinChunkSize,
+		MinChunkPolicy: cli.Ingest.MinChunkPolicy,
+
+		SemanticThreshold: cli.Ingest.SemanticThreshold,
+		SemanticWindow:    cli.Ingest.SemanticWindow,
+		SemanticMinSize:   cli.Ingest.SemanticMinSize,
+
+		RedactMode:  cli.Ingest.RedactMode,
+		RedactRules: cli.Ingest.RedactRules,
+
+		SecretPolicy: cli.Ingest.SecretPolicy,
+		SecretReport: cli.Ingest.SecretReport,
+
+		Dedup:          cli.Ingest.Dedup,
+		DedupMethod:    cli.Ingest.DedupMethod,
+		DedupThreshold: cli.Ingest.DedupThreshold,
+
+		ParentSize:   cli.Ingest.ParentSize,
+		EmbedParents: cli.Ingest.EmbedParents,
 	}
 
 	// Validate configuration
@@ -73,6 +146,17 @@ func main() {
 	slog.Info("Processing completed successfully")
 }
 
+// splitList splits a comma-separated flag value, ignoring blank items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func printVersion() {
 	fmt.Printf("wafer v%s (%s, built %s)\n", version, gitCommit, buildTime)
 }
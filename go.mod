@@ -5,6 +5,7 @@ go 1.24
 require (
 	github.com/alexflint/go-arg v1.5.1
 	github.com/google/uuid v1.6.0
+	golang.org/x/text v0.28.0
 )
 
 require github.com/alexflint/go-scalar v1.2.0 // indirect
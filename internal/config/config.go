@@ -4,14 +4,151 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"text/template"
+)
+
+// Supported chunking strategies
+const (
+	StrategyWord      = "word"      // Fixed-size word windows
+	StrategySentence  = "sentence"  // Whole sentences packed up to the word budget
+	StrategySemantic  = "semantic"  // Cuts where the topic shifts between sentences
+	StrategyRecursive = "recursive" // Splits on paragraphs, lines, sentences then spaces
+)
+
+// Policies for a final chunk smaller than the minimum chunk size
+const (
+	MinChunkMerge     = "merge"     // Fold it into the chunk before it
+	MinChunkRebalance = "rebalance" // Split the last two chunks evenly
+)
+
+// Text normalization stages
+const (
+	NormalizeNFC         = "nfc"         // Canonical Unicode composition
+	NormalizeNFKC        = "nfkc"        // Compatibility composition, folding ligatures and full-width forms
+	NormalizeControls    = "controls"    // Strip zero-width, formatting and control characters
+	NormalizeQuotes      = "quotes"      // Fold typographic quotes to ASCII
+	NormalizeDehyphenate = "dehyphenate" // Join words hyphenated across line breaks
+	NormalizeWhitespace  = "whitespace"  // Collapse runs of whitespace
+	NormalizeLowercase   = "lowercase"   // Lowercase all text
+)
+
+// NormalizeStages lists the normalization stages in the order they are applied
+var NormalizeStages = []string{
+	NormalizeNFC,
+	NormalizeNFKC,
+	NormalizeControls,
+	NormalizeQuotes,
+	NormalizeDehyphenate,
+	NormalizeWhitespace,
+	NormalizeLowercase,
+}
+
+// Ways of handling a chunk that holds personal data
+const (
+	RedactMask       = "mask"        // Replace each match with a typed placeholder such as [EMAIL]
+	RedactDropChunk  = "drop-chunk"  // Neither embed nor write the chunk
+	RedactReportOnly = "report-only" // Keep the text, only recording the matches
+)
+
+// Ways of handling text that holds a credential
+const (
+	SecretSkipChunk = "skip-chunk" // Neither embed nor write the chunk
+	SecretSkipFile  = "skip-file"  // Skip every chunk of the file
+	SecretMask      = "mask"       // Replace each secret with a typed placeholder such as [PRIVATE_KEY]
+)
+
+// SupportedLanguages lists the ISO 639-1 codes of the natural languages
+// identified in chunk text
+var SupportedLanguages = []string{
+	"en", "de", "fr", "es", "it", "pt", "nl", "sv", "pl", "tr",
+	"ru", "uk", "el", "ar", "he", "hi", "th", "ko", "ja", "zh",
+}
+
+// Ways of handling a chunk that nearly duplicates an earlier one
+const (
+	DedupSkip  = "skip"  // Neither embed nor write it
+	DedupLink  = "link"  // Write it unembedded with duplicate_of set to the earlier chunk
+	DedupCount = "count" // Embed and write it as usual, only counting it
+)
+
+// Signatures used to find near-duplicate chunks
+const (
+	DedupSimHash = "simhash" // 64-bit SimHash compared by Hamming distance
+	DedupMinHash = "minhash" // MinHash signature estimating Jaccard similarity
+)
+
+// Character encodings of input files
+const (
+	EncodingAuto        = "auto"         // Detect from the byte order mark or the bytes themselves
+	EncodingUTF8        = "utf-8"        // UTF-8, with invalid sequences replaced
+	EncodingUTF16LE     = "utf-16le"     // UTF-16, little-endian
+	EncodingUTF16BE     = "utf-16be"     // UTF-16, big-endian
+	EncodingWindows1252 = "windows-1252" // Western European Windows code page
+	EncodingLatin1      = "iso-8859-1"   // Latin-1
+)
+
+// DefaultDedupThreshold is the similarity above which chunks are near-duplicates
+// when none is configured
+const DefaultDedupThreshold = 0.9
+
+// DefaultSemanticThreshold is the distance percentile above which the
+// semantic strategy cuts when none is configured
+const DefaultSemanticThreshold = 95
+
+// Supported chunk size units
+const (
+	UnitWords  = "words"  // Chunk size counts words
+	UnitTokens = "tokens" // Chunk size counts model tokens from the tokenizer file
 )
 
 // Config holds the configuration for the wafer CLI tool
 type Config struct {
-	Directory string // Directory to process
-	Model     string // Ollama model name
-	Output    string // Output file path
-	ChunkSize int    // Chunk size in words
+	Directory        string   // Directory to process
+	Model            string   // Ollama model name
+	Output           string   // Output file path
+	ChunkSize        int      // Chunk size in words (or tokens)
+	ChunkStrategy    string   // Chunking strategy (defaults to word)
+	ChunkOverlap     int      // Words, tokens or sentences repeated from the previous chunk
+	ChunkUnit        string   // Unit of ChunkSize and ChunkOverlap (defaults to words)
+	MinChunkSize     int      // Smallest final chunk kept on its own, in chunk units (0 keeps any size)
+	MinChunkPolicy   string   // How a smaller final chunk is absorbed (defaults to merge)
+	TokenizerPath    string   // Path to a Hugging Face tokenizer.json file
+	CodeFiles        bool     // Also ingest source code, split on top-level declarations
+	MaxChars         int      // Character limit of recursive chunks (0 for no limit)
+	HeaderTemplate   string   // text/template rendered for each chunk and embedded in place of its text
+	Normalize        []string // Normalization stages applied to text before chunking
+	StripBoilerplate bool     // Cut page numbers and repeated headers and footers from plain text
+	Encoding         string   // Character encoding of input files (defaults to auto)
+	IncludeExt       []string // Extensions of the document formats ingested (empty ingests every supported format)
+	Languages        []string // Languages of the text chunks kept; others are skipped (empty keeps all)
+
+	// Dataset rows
+	TextColumns     string   // text/template over the columns of a CSV, TSV or JSONL row that builds its text (empty lists every column)
+	IDColumn        string   // Column holding the stable ID of a row's records (empty for random IDs)
+	MetadataColumns []string // Columns copied into the metadata of a row's records
+
+	// Personal data redaction
+	RedactMode  string // Handling of chunks holding personal data: mask, drop-chunk or report-only (empty disables)
+	RedactRules string // File of custom redaction rules, one placeholder type and pattern per line
+
+	// Secret scanning
+	SecretPolicy string // Handling of text holding credentials: skip-chunk, skip-file or mask (empty disables)
+	SecretReport string // File the secrets found are appended to (defaults to one next to the output)
+
+	// Semantic chunking
+	SemanticThreshold float64 // Percentile of sentence distances that marks a cut (0 uses the default)
+	SemanticWindow    int     // Neighboring sentences on each side embedded with a sentence
+	SemanticMinSize   int     // Smallest chunk, in chunk units, that a topic shift may end
+
+	// Near-duplicate detection
+	Dedup          string  // Handling of near-duplicate chunks: skip, link or count (empty disables)
+	DedupMethod    string  // Signature used to find near-duplicates (defaults to simhash)
+	DedupThreshold float64 // Similarity from 0 to 1 above which chunks are near-duplicates (0 uses the default)
+
+	// Hierarchical chunking
+	ParentSize   int  // Size of parent chunks that child chunks are cut from (0 disables parents)
+	EmbedParents bool // Embed parent chunks instead of storing them as text only
 }
 
 // Validate checks if the configuration is valid
@@ -35,6 +172,149 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("chunk size must be positive, got: %d", c.ChunkSize)
 	}
 
+	// Validate chunk strategy
+	switch c.ChunkStrategy {
+	case "", StrategyWord, StrategySentence, StrategySemantic, StrategyRecursive:
+	default:
+		return fmt.Errorf("unknown chunk strategy: %s", c.ChunkStrategy)
+	}
+
+	// Validate chunk unit and tokenizer
+	switch c.ChunkUnit {
+	case "", UnitWords:
+	case UnitTokens:
+		if c.TokenizerPath == "" {
+			return fmt.Errorf("chunk unit %q requires a tokenizer file", UnitTokens)
+		}
+	default:
+		return fmt.Errorf("unknown chunk unit: %s", c.ChunkUnit)
+	}
+	if c.TokenizerPath != "" {
+		if _, err := os.Stat(c.TokenizerPath); err != nil {
+			return fmt.Errorf("cannot access tokenizer file: %w", err)
+		}
+	}
+
+	// Validate chunk overlap
+	if c.ChunkOverlap < 0 {
+		return fmt.Errorf("chunk overlap cannot be negative, got: %d", c.ChunkOverlap)
+	}
+	if c.ChunkOverlap >= c.ChunkSize {
+		return fmt.Errorf("chunk overlap (%d) must be smaller than chunk size (%d)", c.ChunkOverlap, c.ChunkSize)
+	}
+
+	// Validate minimum chunk size
+	if c.MinChunkSize < 0 || c.MinChunkSize >= c.ChunkSize {
+		return fmt.Errorf("minimum chunk size (%d) must be between 0 and chunk size (%d)", c.MinChunkSize, c.ChunkSize)
+	}
+	switch c.MinChunkPolicy {
+	case "", MinChunkMerge, MinChunkRebalance:
+	default:
+		return fmt.Errorf("unknown minimum chunk policy: %s", c.MinChunkPolicy)
+	}
+
+	// Validate character limit
+	if c.MaxChars < 0 {
+		return fmt.Errorf("max chars cannot be negative, got: %d", c.MaxChars)
+	}
+
+	// Validate semantic chunking settings
+	if c.SemanticThreshold < 0 || c.SemanticThreshold > 100 {
+		return fmt.Errorf("semantic threshold must be a percentile between 0 and 100, got: %g", c.SemanticThreshold)
+	}
+	if c.SemanticWindow < 0 {
+		return fmt.Errorf("semantic window cannot be negative, got: %d", c.SemanticWindow)
+	}
+	if c.SemanticMinSize < 0 || c.SemanticMinSize >= c.ChunkSize {
+		return fmt.Errorf("semantic minimum size (%d) must be between 0 and chunk size (%d)", c.SemanticMinSize, c.ChunkSize)
+	}
+
+	// Validate normalization stages
+	for _, stage := range c.Normalize {
+		if !slices.Contains(NormalizeStages, stage) {
+			return fmt.Errorf("unknown normalization stage: %s", stage)
+		}
+	}
+	if slices.Contains(c.Normalize, NormalizeNFC) && slices.Contains(c.Normalize, NormalizeNFKC) {
+		return fmt.Errorf("normalization stages %q and %q cannot be combined", NormalizeNFC, NormalizeNFKC)
+	}
+
+	// Validate input encoding
+	switch c.Encoding {
+	case "", EncodingAuto, EncodingUTF8, EncodingUTF16LE, EncodingUTF16BE, EncodingWindows1252, EncodingLatin1:
+	default:
+		return fmt.Errorf("unknown encoding: %s", c.Encoding)
+	}
+
+	// Validate language filter
+	for _, language := range c.Languages {
+		if !slices.Contains(SupportedLanguages, language) {
+			return fmt.Errorf("unsupported language: %s", language)
+		}
+	}
+
+	// Validate personal data redaction
+	switch c.RedactMode {
+	case "", RedactMask, RedactDropChunk, RedactReportOnly:
+	default:
+		return fmt.Errorf("unknown redact mode: %s", c.RedactMode)
+	}
+	if c.RedactRules != "" {
+		if c.RedactMode == "" {
+			return fmt.Errorf("redaction rules require a redact mode")
+		}
+		if _, err := os.Stat(c.RedactRules); err != nil {
+			return fmt.Errorf("cannot access redaction rules: %w", err)
+		}
+	}
+
+	// Validate secret scanning
+	switch c.SecretPolicy {
+	case "", SecretSkipChunk, SecretSkipFile, SecretMask:
+	default:
+		return fmt.Errorf("unknown secret policy: %s", c.SecretPolicy)
+	}
+	if c.SecretReport != "" && c.SecretPolicy == "" {
+		return fmt.Errorf("a secret report requires a secret policy")
+	}
+
+	// Validate near-duplicate detection
+	switch c.Dedup {
+	case "", DedupSkip, DedupLink, DedupCount:
+	default:
+		return fmt.Errorf("unknown dedup mode: %s", c.Dedup)
+	}
+	switch c.DedupMethod {
+	case "", DedupSimHash, DedupMinHash:
+	default:
+		return fmt.Errorf("unknown dedup method: %s", c.DedupMethod)
+	}
+	if c.DedupThreshold < 0 || c.DedupThreshold > 1 {
+		return fmt.Errorf("dedup threshold must be between 0 and 1, got: %g", c.DedupThreshold)
+	}
+
+	// Validate header template syntax
+	if c.HeaderTemplate != "" {
+		if _, err := template.New("header").Parse(c.HeaderTemplate); err != nil {
+			return fmt.Errorf("invalid header template: %w", err)
+		}
+	}
+
+	// Validate text columns template syntax
+	if c.TextColumns != "" {
+		if _, err := template.New("text").Parse(c.TextColumns); err != nil {
+			return fmt.Errorf("invalid text columns template: %w", err)
+		}
+	}
+
+	// Validate parent chunk size
+	if c.ParentSize < 0 {
+		return fmt.Errorf("parent size cannot be negative, got: %d", c.ParentSize)
+	}
+	if c.ParentSize > 0 && c.ParentSize <= c.ChunkSize {
+		return fmt.Errorf("parent size (%d) must be larger than chunk size (%d)", c.ParentSize, c.ChunkSize)
+	}
+
 	// Ensure output directory exists
 	outputDir := filepath.Dir(c.Output)
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
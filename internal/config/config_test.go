@@ -43,6 +43,464 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "sentence strategy",
+			config: &Config{
+				Directory:     tmpDir,
+				Model:         "test-model",
+				Output:        filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:     300,
+				ChunkStrategy: StrategySentence,
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown strategy",
+			config: &Config{
+				Directory:     tmpDir,
+				Model:         "test-model",
+				Output:        filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:     300,
+				ChunkStrategy: "paragraph",
+			},
+			wantErr: true,
+		},
+		{
+			name: "overlap smaller than chunk size",
+			config: &Config{
+				Directory:    tmpDir,
+				Model:        "test-model",
+				Output:       filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:    300,
+				ChunkOverlap: 50,
+			},
+			wantErr: false,
+		},
+		{
+			name: "overlap equal to chunk size",
+			config: &Config{
+				Directory:    tmpDir,
+				Model:        "test-model",
+				Output:       filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:    300,
+				ChunkOverlap: 300,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative overlap",
+			config: &Config{
+				Directory:    tmpDir,
+				Model:        "test-model",
+				Output:       filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:    300,
+				ChunkOverlap: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "token unit without tokenizer",
+			config: &Config{
+				Directory: tmpDir,
+				Model:     "test-model",
+				Output:    filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize: 300,
+				ChunkUnit: UnitTokens,
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing tokenizer file",
+			config: &Config{
+				Directory:     tmpDir,
+				Model:         "test-model",
+				Output:        filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:     300,
+				ChunkUnit:     UnitTokens,
+				TokenizerPath: filepath.Join(tmpDir, "tokenizer.json"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown chunk unit",
+			config: &Config{
+				Directory: tmpDir,
+				Model:     "test-model",
+				Output:    filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize: 300,
+				ChunkUnit: "characters",
+			},
+			wantErr: true,
+		},
+		{
+			name: "semantic strategy",
+			config: &Config{
+				Directory:         tmpDir,
+				Model:             "test-model",
+				Output:            filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:         300,
+				ChunkStrategy:     StrategySemantic,
+				SemanticThreshold: 90,
+				SemanticWindow:    1,
+				SemanticMinSize:   50,
+			},
+			wantErr: false,
+		},
+		{
+			name: "semantic threshold above 100",
+			config: &Config{
+				Directory:         tmpDir,
+				Model:             "test-model",
+				Output:            filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:         300,
+				ChunkStrategy:     StrategySemantic,
+				SemanticThreshold: 150,
+			},
+			wantErr: true,
+		},
+		{
+			name: "semantic minimum size not below chunk size",
+			config: &Config{
+				Directory:       tmpDir,
+				Model:           "test-model",
+				Output:          filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:       300,
+				ChunkStrategy:   StrategySemantic,
+				SemanticMinSize: 300,
+			},
+			wantErr: true,
+		},
+		{
+			name: "parent chunks",
+			config: &Config{
+				Directory:  tmpDir,
+				Model:      "test-model",
+				Output:     filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:  150,
+				ParentSize: 1000,
+			},
+			wantErr: false,
+		},
+		{
+			name: "parent size not above chunk size",
+			config: &Config{
+				Directory:  tmpDir,
+				Model:      "test-model",
+				Output:     filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:  300,
+				ParentSize: 300,
+			},
+			wantErr: true,
+		},
+		{
+			name: "recursive strategy with character limit",
+			config: &Config{
+				Directory:     tmpDir,
+				Model:         "test-model",
+				Output:        filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:     300,
+				ChunkStrategy: StrategyRecursive,
+				MaxChars:      2000,
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative max chars",
+			config: &Config{
+				Directory:     tmpDir,
+				Model:         "test-model",
+				Output:        filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:     300,
+				ChunkStrategy: StrategyRecursive,
+				MaxChars:      -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "minimum chunk size with rebalance",
+			config: &Config{
+				Directory:      tmpDir,
+				Model:          "test-model",
+				Output:         filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:      300,
+				MinChunkSize:   50,
+				MinChunkPolicy: MinChunkRebalance,
+			},
+			wantErr: false,
+		},
+		{
+			name: "minimum chunk size not below chunk size",
+			config: &Config{
+				Directory:    tmpDir,
+				Model:        "test-model",
+				Output:       filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:    300,
+				MinChunkSize: 300,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown minimum chunk policy",
+			config: &Config{
+				Directory:      tmpDir,
+				Model:          "test-model",
+				Output:         filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:      300,
+				MinChunkSize:   10,
+				MinChunkPolicy: "drop",
+			},
+			wantErr: true,
+		},
+		{
+			name: "normalization stages",
+			config: &Config{
+				Directory: tmpDir,
+				Model:     "test-model",
+				Output:    filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize: 300,
+				Normalize: []string{NormalizeNFKC, NormalizeDehyphenate, NormalizeLowercase},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown normalization stage",
+			config: &Config{
+				Directory: tmpDir,
+				Model:     "test-model",
+				Output:    filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize: 300,
+				Normalize: []string{"stem"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "nfc combined with nfkc",
+			config: &Config{
+				Directory: tmpDir,
+				Model:     "test-model",
+				Output:    filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize: 300,
+				Normalize: []string{NormalizeNFC, NormalizeNFKC},
+			},
+			wantErr: true,
+		},
+		{
+			name: "explicit encoding",
+			config: &Config{
+				Directory: tmpDir,
+				Model:     "test-model",
+				Output:    filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize: 300,
+				Encoding:  EncodingWindows1252,
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown encoding",
+			config: &Config{
+				Directory: tmpDir,
+				Model:     "test-model",
+				Output:    filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize: 300,
+				Encoding:  "ebcdic",
+			},
+			wantErr: true,
+		},
+		{
+			name: "language filter",
+			config: &Config{
+				Directory: tmpDir,
+				Model:     "test-model",
+				Output:    filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize: 300,
+				Languages: []string{"en", "de"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unsupported language",
+			config: &Config{
+				Directory: tmpDir,
+				Model:     "test-model",
+				Output:    filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize: 300,
+				Languages: []string{"en", "english"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "redact mode",
+			config: &Config{
+				Directory:  tmpDir,
+				Model:      "test-model",
+				Output:     filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:  300,
+				RedactMode: RedactMask,
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown redact mode",
+			config: &Config{
+				Directory:  tmpDir,
+				Model:      "test-model",
+				Output:     filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:  300,
+				RedactMode: "erase",
+			},
+			wantErr: true,
+		},
+		{
+			name: "redaction rules without mode",
+			config: &Config{
+				Directory:   tmpDir,
+				Model:       "test-model",
+				Output:      filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:   300,
+				RedactRules: filepath.Join(tmpDir, "rules.txt"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing redaction rules",
+			config: &Config{
+				Directory:   tmpDir,
+				Model:       "test-model",
+				Output:      filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:   300,
+				RedactMode:  RedactDropChunk,
+				RedactRules: filepath.Join(tmpDir, "missing-rules.txt"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "secret policy",
+			config: &Config{
+				Directory:    tmpDir,
+				Model:        "test-model",
+				Output:       filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:    300,
+				SecretPolicy: SecretSkipFile,
+				SecretReport: filepath.Join(tmpDir, "secrets.jsonl"),
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown secret policy",
+			config: &Config{
+				Directory:    tmpDir,
+				Model:        "test-model",
+				Output:       filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:    300,
+				SecretPolicy: "quarantine",
+			},
+			wantErr: true,
+		},
+		{
+			name: "secret report without policy",
+			config: &Config{
+				Directory:    tmpDir,
+				Model:        "test-model",
+				Output:       filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:    300,
+				SecretReport: filepath.Join(tmpDir, "secrets.jsonl"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "dedup with minhash",
+			config: &Config{
+				Directory:      tmpDir,
+				Model:          "test-model",
+				Output:         filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:      300,
+				Dedup:          DedupLink,
+				DedupMethod:    DedupMinHash,
+				DedupThreshold: 0.8,
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown dedup mode",
+			config: &Config{
+				Directory: tmpDir,
+				Model:     "test-model",
+				Output:    filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize: 300,
+				Dedup:     "merge",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown dedup method",
+			config: &Config{
+				Directory:   tmpDir,
+				Model:       "test-model",
+				Output:      filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:   300,
+				Dedup:       DedupSkip,
+				DedupMethod: "md5",
+			},
+			wantErr: true,
+		},
+		{
+			name: "dedup threshold above one",
+			config: &Config{
+				Directory:      tmpDir,
+				Model:          "test-model",
+				Output:         filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:      300,
+				Dedup:          DedupCount,
+				DedupThreshold: 1.5,
+			},
+			wantErr: true,
+		},
+		{
+			name: "header template",
+			config: &Config{
+				Directory:      tmpDir,
+				Model:          "test-model",
+				Output:         filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:      300,
+				HeaderTemplate: "{{.SourceFile}} — {{.Section}}\n\n{{.Text}}",
+			},
+			wantErr: false,
+		},
+		{
+			name: "malformed header template",
+			config: &Config{
+				Directory:      tmpDir,
+				Model:          "test-model",
+				Output:         filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:      300,
+				HeaderTemplate: "{{.SourceFile",
+			},
+			wantErr: true,
+		},
+		{
+			name: "text columns template",
+			config: &Config{
+				Directory:       tmpDir,
+				Model:           "test-model",
+				Output:          filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:       300,
+				TextColumns:     `{{.name}}: {{index . "product description"}}`,
+				IDColumn:        "sku",
+				MetadataColumns: []string{"category"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "malformed text columns template",
+			config: &Config{
+				Directory:   tmpDir,
+				Model:       "test-model",
+				Output:      filepath.Join(tmpDir, "output.jsonl"),
+				ChunkSize:   300,
+				TextColumns: "{{.name",
+			},
+			wantErr: true,
+		},
 		{
 			name: "empty model",
 			config: &Config{
@@ -0,0 +1,177 @@
+package ingest
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// boilerplateEdgeLines is how many non-blank lines next to a page break
+	// are checked for running headers and footers
+	boilerplateEdgeLines = 3
+
+	// boilerplateMinRepeats is the fewest pages a header or footer must
+	// repeat on, and boilerplateMinShare the smallest share of all pages
+	boilerplateMinRepeats = 3
+	boilerplateMinShare   = 0.4
+
+	// boilerplateMaxWords is the longest line taken for a header or footer
+	boilerplateMaxWords = 12
+)
+
+// pageNumberRE matches a line holding only a page number, such as "12",
+// "- 12 -", "Page 3" or "3 of 10"
+var pageNumberRE = regexp.MustCompile(`(?i)^[-–—\s]*(?:page\s+)?\d{1,4}(?:\s*(?:of|/)\s*\d{1,4})?[-–—\s]*$`)
+
+// findBoilerplate returns the lines of a PDF-to-text export that hold page
+// numbers or the headers and footers repeated across its pages. Pages end at
+// form feeds and page numbers; lines near those breaks that recur, with
+// their digits ignored, on enough pages are running headers and footers.
+func findBoilerplate(text string) []span {
+	lines := splitLines(text)
+	remove := make([]bool, len(lines))
+
+	// Page breaks fall before the line they are numbered, so a form feed
+	// starts a page and a page number ends one. The start and end of the
+	// document count as breaks.
+	breaks := []int{0}
+	for i, line := range lines {
+		content := text[line.start:line.end]
+		if pageNumberRE.MatchString(strings.TrimSpace(content)) {
+			remove[i] = true
+			breaks = append(breaks, i+1)
+		} else if strings.ContainsRune(content, '\f') {
+			breaks = append(breaks, i)
+		}
+	}
+	breaks = append(breaks, len(lines))
+
+	// Only pages with text count, such as before a trailing form feed
+	pages := 0
+	for j := 1; j < len(breaks); j++ {
+		for i := breaks[j-1]; i < breaks[j]; i++ {
+			if !remove[i] && strings.TrimSpace(text[lines[i].start:lines[i].end]) != "" {
+				pages++
+				break
+			}
+		}
+	}
+	if pages < boilerplateMinRepeats {
+		return collectLines(text, lines, remove)
+	}
+
+	// Count the pages each line near a break recurs on
+	type pageLine struct {
+		signature string
+		page      int
+	}
+	candidates := map[int]string{}
+	counted := map[pageLine]bool{}
+	repeats := map[string]int{}
+	for j, b := range breaks {
+		for _, dir := range []int{-1, 1} {
+			first, page := b, j
+			if dir < 0 {
+				first, page = b-1, j-1
+			}
+			seen := 0
+			for i := first; i >= 0 && i < len(lines) && seen < boilerplateEdgeLines; i += dir {
+				signature := lineSignature(text[lines[i].start:lines[i].end])
+				if signature == "" || remove[i] {
+					continue
+				}
+				seen++
+				if len(strings.Fields(signature)) > boilerplateMaxWords {
+					continue
+				}
+				candidates[i] = signature
+				if key := (pageLine{signature, page}); !counted[key] {
+					counted[key] = true
+					repeats[signature]++
+				}
+			}
+		}
+	}
+
+	threshold := max(boilerplateMinRepeats, int(boilerplateMinShare*float64(pages)+0.5))
+	for i, signature := range candidates {
+		if repeats[signature] >= threshold {
+			remove[i] = true
+		}
+	}
+	return collectLines(text, lines, remove)
+}
+
+// collectLines returns the lines marked for removal with their line breaks
+func collectLines(text string, lines []span, remove []bool) []span {
+	var removed []span
+	for i, line := range lines {
+		if !remove[i] {
+			continue
+		}
+		if line.end < len(text) {
+			line.end++ // The line break goes with the line
+		}
+		removed = append(removed, line)
+	}
+	return removed
+}
+
+// lineSignature returns a line with its digits masked and whitespace
+// collapsed, so a header with a changing page or date still matches itself
+func lineSignature(line string) string {
+	masked := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return '#'
+		}
+		return r
+	}, line)
+	return strings.Join(strings.Fields(masked), " ")
+}
+
+// lineCuts translates offsets and line numbers in text with whole lines cut
+// out of it back to the original text
+type lineCuts struct {
+	at    []int // Offsets in the cut text where lines were removed
+	bytes []int // Bytes removed at or before each cut
+	lines []int // Line breaks removed at or before each cut
+}
+
+// cutLines removes the given lines, which must be in order, from text
+func cutLines(text string, lines []span) (string, *lineCuts) {
+	c := &lineCuts{}
+	var b strings.Builder
+	last, bytes, breaks := 0, 0, 0
+	for _, line := range lines {
+		b.WriteString(text[last:line.start])
+		bytes += line.end - line.start
+		breaks += countLineBreaks(text[line.start:line.end])
+		c.at = append(c.at, b.Len())
+		c.bytes = append(c.bytes, bytes)
+		c.lines = append(c.lines, breaks)
+		last = line.end
+	}
+	b.WriteString(text[last:])
+	return b.String(), c
+}
+
+// removed returns the number of lines that were cut
+func (c *lineCuts) removed() int {
+	return len(c.at)
+}
+
+// restore maps the offsets and line numbers of a chunk of the cut text back
+// to the original text
+func (c *lineCuts) restore(chunk *Chunk) {
+	// Lines cut right before the chunk precede it, those right after follow it
+	if k := sort.SearchInts(c.at, chunk.StartByte+1); k > 0 {
+		chunk.StartByte += c.bytes[k-1]
+		chunk.StartLine += c.lines[k-1]
+	}
+	if k := sort.SearchInts(c.at, chunk.EndByte); k > 0 {
+		chunk.EndByte += c.bytes[k-1]
+		chunk.EndLine += c.lines[k-1]
+	}
+}
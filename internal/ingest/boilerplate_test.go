@@ -0,0 +1,114 @@
+package ingest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"wafer/internal/config"
+)
+
+// pdfExport builds a PDF-to-text style document whose pages repeat a header
+// and footer around numbered pages
+func pdfExport(pages int, pageBreak func(page int) string) string {
+	topics := []string{"revenue", "staffing", "outlook", "risks", "markets", "research"}
+
+	var b strings.Builder
+	for page := 1; page <= pages; page++ {
+		topic := topics[(page-1)%len(topics)]
+		fmt.Fprintf(&b, "ACME Corp   Annual Report %d\n\n", 2020+page%2)
+		fmt.Fprintf(&b, "This page explains our %s in some detail.\r\n", topic)
+		fmt.Fprintf(&b, "It continues with a second line about %s.\n\n", topic)
+		fmt.Fprintf(&b, "Confidential\n%s", pageBreak(page))
+	}
+	return b.String()
+}
+
+func TestFindBoilerplate(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string // Trimmed lines expected to be removed
+	}{
+		{
+			name: "page numbers end pages",
+			text: pdfExport(5, func(page int) string { return fmt.Sprintf("- %d -\n", page) }),
+			want: []string{"ACME Corp Annual Report", "Confidential", "- -"},
+		},
+		{
+			name: "form feeds end pages",
+			text: pdfExport(4, func(int) string { return "\f" }),
+			want: []string{"ACME Corp Annual Report", "Confidential"},
+		},
+		{
+			name: "page of total",
+			text: pdfExport(3, func(page int) string { return fmt.Sprintf("Page %d of 3\n", page) }),
+			want: []string{"ACME Corp Annual Report", "Confidential", "Page of"},
+		},
+		{
+			name: "too few pages to repeat",
+			text: pdfExport(2, func(int) string { return "\f" }),
+			want: nil,
+		},
+		{
+			name: "no page breaks",
+			text: strings.Repeat("Yes.\nThe same short line.\n", 10),
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removed := map[string]int{}
+			for _, line := range findBoilerplate(tt.text) {
+				if line.end < len(tt.text) && tt.text[line.end-1] != '\n' {
+					t.Errorf("line %d-%d does not include its line break", line.start, line.end)
+				}
+				removed[strings.Join(strings.Fields(strings.Map(func(r rune) rune {
+					if r >= '0' && r <= '9' {
+						return -1
+					}
+					return r
+				}, tt.text[line.start:line.end])), " ")]++
+			}
+
+			if len(removed) != len(tt.want) {
+				t.Errorf("removed %v, want %v", removed, tt.want)
+			}
+			for _, line := range tt.want {
+				if removed[line] == 0 {
+					t.Errorf("line %q was kept, removed %v", line, removed)
+				}
+			}
+		})
+	}
+}
+
+func TestLineCuts_Restore(t *testing.T) {
+	text := pdfExport(6, func(page int) string { return fmt.Sprintf("%d\n", page) })
+	content, cuts := cutLines(text, findBoilerplate(text))
+	if cuts.removed() != 18 {
+		t.Errorf("removed %d lines, want 18", cuts.removed())
+	}
+	if strings.Contains(content, "ACME") || strings.Contains(content, "Confidential") {
+		t.Fatalf("boilerplate left in %q", content)
+	}
+
+	for _, size := range []int{3, 9, 40} {
+		for _, chunk := range NewChunker(&config.Config{ChunkSize: size}).ChunkText(content) {
+			cuts.restore(&chunk)
+			source := text[chunk.StartByte:chunk.EndByte]
+			words := strings.Fields(chunk.Text)
+			if !strings.HasPrefix(source, words[0]) || !strings.HasSuffix(source, words[len(words)-1]) {
+				t.Errorf("size %d chunk %d: %d-%d holds %q, want %q",
+					size, chunk.Index, chunk.StartByte, chunk.EndByte, source, chunk.Text)
+			}
+			if want := 1 + countLineBreaks(text[:chunk.StartByte]); chunk.StartLine != want {
+				t.Errorf("size %d chunk %d: start line %d, want %d", size, chunk.Index, chunk.StartLine, want)
+			}
+			if want := 1 + countLineBreaks(text[:chunk.EndByte-1]); chunk.EndLine != want {
+				t.Errorf("size %d chunk %d: end line %d, want %d", size, chunk.Index, chunk.EndLine, want)
+			}
+		}
+	}
+}
@@ -1,123 +1,509 @@
 package ingest
 
 import (
-	"bufio"
-	"fmt"
-	"io"
-	"os"
+	"path/filepath"
 	"strings"
 	"unicode"
+	"unicode/utf8"
+
+	"wafer/internal/config"
 )
 
 // Chunk represents a text chunk with metadata
 type Chunk struct {
-	Text      string
-	WordCount int
-	Index     int
+	Text       string // Passage of the source document, with line endings normalized
+	WordCount  int
+	CharCount  int // Characters in Text
+	TokenCount int // Model tokens in Text, set when a tokenizer is configured
+	Index      int
+	Section    string // Heading path of the chunk, such as "Install > Linux"
+	Symbol     string // Top-level declaration a code chunk belongs to
+	Language   string // ISO 639-1 code of the natural language of a text chunk
+
+	// CodeLanguage is the programming language of a code chunk, such as "go"
+	CodeLanguage string
+
+	// LanguageConfidence is the confidence, from 0 to 1, that a text chunk is
+	// written in Language
+	LanguageConfidence float64
+
+	// Overlap describes the leading part of Text repeated from the previous chunk
+	OverlapWords int // Words repeated from the previous chunk
+	OverlapChars int // Length in characters of the repeated prefix of Text
+
+	// Location of Text in the original document
+	StartByte int // Offset of the first byte
+	EndByte   int // Offset just past the last byte
+	StartLine int // 1-based line of the first byte
+	EndLine   int // 1-based line of the last byte
+
+	// EmbeddingText is the normalized text sent to the embedder when it
+	// differs from Text
+	EmbeddingText string
+
+	// ContextText is the chunk rendered through the header template, embedded
+	// in place of the chunk text when a template is configured
+	ContextText string
+
+	// Hierarchical chunking
+	ID       string // Record ID, generated when the chunk is written if empty
+	ParentID string // ID of the parent chunk a child chunk was cut from
+	Level    string // ChunkParent or ChunkChild, empty without parent chunks
+
+	// DuplicateOf is the ID of an earlier chunk this one nearly duplicates
+	DuplicateOf string
+
+	// Encoding is the character encoding the source file was decoded from
+	Encoding string
+
+	// Redactions counts the personal data found in Text by placeholder type
+	Redactions map[string]int
+
+	// Metadata describes the document the chunk was extracted from, such as
+	// its title
+	Metadata map[string]string
+
+	// PageNumber is the 1-based page of a paged document, such as a PDF,
+	// the chunk was extracted from
+	PageNumber int
+
+	// continues marks a piece of a sentence split across chunks after the first
+	continues bool
+}
+
+// Levels of hierarchical chunks
+const (
+	ChunkParent = "parent" // Large chunk handed to the LLM as context
+	ChunkChild  = "child"  // Small chunk used for retrieval
+)
+
+// embeddingInput returns the text sent to the embedder for the chunk
+func (c *Chunk) embeddingInput() string {
+	if c.ContextText != "" {
+		return c.ContextText
+	}
+	if c.EmbeddingText != "" {
+		return c.EmbeddingText
+	}
+	return c.Text
+}
+
+// Strategy decides where chunk boundaries fall in normalized text
+type Strategy interface {
+	// Split returns the chunks for the text with StartByte and EndByte set
+	// relative to text; indices and line numbers are assigned by the Chunker
+	Split(text string) []Chunk
 }
 
 // Chunker handles text chunking operations
 type Chunker struct {
-	chunkSize int
+	budget    *budget
+	strategy  Strategy
+	markdown  Strategy
+	normalize *normalizer // Normalization applied before splitting, or nil
+	window    int         // Bytes read ahead when streaming
+	minSize   int         // Smallest final chunk kept on its own, or 0
+	minPolicy string      // How a smaller final chunk is absorbed
 }
 
-// NewChunker creates a new chunker with the specified chunk size
-func NewChunker(chunkSize int) *Chunker {
+// NewChunker creates a new chunker using the strategy selected in the configuration
+func NewChunker(cfg *config.Config) *Chunker {
+	b := &budget{
+		size:    cfg.ChunkSize,
+		overlap: cfg.ChunkOverlap,
+		unit:    cfg.ChunkUnit,
+	}
+
+	var strategy Strategy
+	switch cfg.ChunkStrategy {
+	case config.StrategySentence:
+		strategy = &sentenceStrategy{budget: b}
+	case config.StrategySemantic:
+		threshold := cfg.SemanticThreshold
+		if threshold == 0 {
+			threshold = config.DefaultSemanticThreshold
+		}
+		strategy = &semanticStrategy{
+			budget:     b,
+			percentile: threshold,
+			window:     cfg.SemanticWindow,
+			minSize:    cfg.SemanticMinSize,
+		}
+	case config.StrategyRecursive:
+		strategy = &recursiveStrategy{budget: b, maxChars: cfg.MaxChars}
+	default:
+		strategy = &wordStrategy{budget: b}
+	}
+
 	return &Chunker{
-		chunkSize: chunkSize,
+		budget:    b,
+		strategy:  strategy,
+		markdown:  &markdownStrategy{budget: b},
+		normalize: newNormalizer(cfg.Normalize),
+		window:    streamWindow,
+		minSize:   cfg.MinChunkSize,
+		minPolicy: cfg.MinChunkPolicy,
+	}
+}
+
+// SetTokenizer sets the tokenizer used to count chunk tokens and, when the
+// chunk unit is tokens, to size chunks
+func (c *Chunker) SetTokenizer(tokenizer Tokenizer) {
+	c.budget.tokenizer = tokenizer
+}
+
+// SetEmbedder sets the embedder the semantic strategy uses to compare sentences
+func (c *Chunker) SetEmbedder(embedder TextEmbedder) {
+	if semantic, ok := c.strategy.(*semanticStrategy); ok {
+		semantic.embedder = embedder
 	}
 }
 
+// Normalization returns the normalization stages applied before chunking,
+// in the order they run
+func (c *Chunker) Normalization() []string {
+	if c.normalize == nil {
+		return nil
+	}
+	return c.normalize.names
+}
+
 // ChunkFile reads a file and splits it into chunks
 func (c *Chunker) ChunkFile(filePath string) ([]Chunk, error) {
-	file, err := os.Open(filePath)
+	chunks := []Chunk{}
+	err := c.StreamFile(filePath, func(chunk Chunk) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file %s: %w", filePath, err)
+		return nil, err
 	}
-	defer file.Close()
+	return chunks, nil
+}
 
-	content, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file %s: %w", filePath, err)
+// ChunkText splits text into chunks according to the configured strategy
+func (c *Chunker) ChunkText(text string) []Chunk {
+	return c.chunkText(c.strategy, text)
+}
+
+// strategyFor returns the strategy for a file, switching to structure-aware
+// chunking for Markdown documents and source code
+func (c *Chunker) strategyFor(filePath string) Strategy {
+	if isMarkdownFile(filePath) {
+		return c.markdown
 	}
+	if language := codeLanguage(filePath); language != "" {
+		return &codeStrategy{budget: c.budget, language: language}
+	}
+	return c.strategy
+}
 
-	return c.ChunkText(string(content)), nil
+// textStrategy returns the strategy for text extracted from a document,
+// which is split on its headings when it is Markdown
+func (c *Chunker) textStrategy(markdown bool) Strategy {
+	if markdown {
+		return c.markdown
+	}
+	return c.strategy
 }
 
-// ChunkText splits text into chunks of approximately the specified word count
-func (c *Chunker) ChunkText(text string) []Chunk {
-	// Clean and normalize the text
-	text = strings.TrimSpace(text)
+// chunkText normalizes a whole document and splits it with the given strategy
+func (c *Chunker) chunkText(strategy Strategy, text string) []Chunk {
+	return c.chunkWindow(strategy, text, 0, true)
+}
+
+// chunkWindow normalizes text and splits it with the given strategy. Overlap
+// is the number of words at the start of text that a chunk of an earlier
+// window repeats from the chunk before it. Final marks text that runs to the
+// end of the document, whose last chunk may be absorbed when it is smaller
+// than the minimum size.
+func (c *Chunker) chunkWindow(strategy Strategy, text string, overlap int, final bool) []Chunk {
+	// Trim and normalize line endings to Unix style for consistent
+	// cross-platform behavior, remembering where each byte came from
+	text, source := normalizeDocument(text)
+
+	// Source code is kept verbatim
+	document := text
+	var rewritten *rewriteMap
+	if _, code := strategy.(*codeStrategy); c.normalize != nil && !code {
+		text, rewritten = c.normalize.normalize(text)
+	}
 	if text == "" {
 		return []Chunk{}
 	}
 
-	// Normalize line endings to Unix style for consistent cross-platform behavior
-	text = strings.ReplaceAll(text, "\r\n", "\n") // Windows -> Unix
-	text = strings.ReplaceAll(text, "\r", "\n")   // Old Mac -> Unix
-
-	// Split text into words while preserving whitespace information
-	words := c.tokenizeWords(text)
-	if len(words) == 0 {
+	chunks := strategy.Split(text)
+	if len(chunks) == 0 {
 		return []Chunk{}
 	}
+	if chunks[0].StartByte == 0 && chunks[0].OverlapWords == 0 {
+		chunks[0].OverlapWords = min(overlap, chunks[0].WordCount)
+	}
+	if final {
+		chunks = c.absorbRunt(strategy, text, chunks)
+	}
 
-	// If the text has fewer words than chunk size, return as single chunk
-	if len(words) <= c.chunkSize {
-		return []Chunk{{
-			Text:      text,
-			WordCount: len(words),
-			Index:     0,
-		}}
+	for i := range chunks {
+		chunk := &chunks[i]
+		chunk.Index = i
+		start, end := chunk.StartByte, chunk.EndByte
+		if rewritten != nil {
+			// Text is the passage of the document; its normalized form is
+			// only embedded
+			normalized := chunk.embeddingInput()
+			start, end = rewritten.start(start), rewritten.end(end)
+			if chunk.OverlapChars > 0 {
+				overlapEnd := rewritten.end(chunk.StartByte + prefixBytes(chunk.Text, chunk.OverlapChars))
+				chunk.OverlapChars = utf8.RuneCountInString(document[start:overlapEnd])
+			}
+			chunk.Text = document[start:end]
+			chunk.EmbeddingText = ""
+			if normalized != chunk.Text {
+				chunk.EmbeddingText = normalized
+			}
+		}
+		chunk.StartLine = source.line(start)
+		chunk.EndLine = source.line(end - 1)
+		chunk.StartByte, chunk.EndByte = source.startByte(start), source.endByte(end)
+		chunk.CharCount = utf8.RuneCountInString(chunk.Text)
+		if c.budget.tokenizer != nil {
+			chunk.TokenCount = len(c.budget.tokenizer.Tokenize(chunk.embeddingInput()))
+		}
 	}
 
+	return chunks
+}
+
+// absorbRunt folds a final chunk smaller than the minimum size into the chunk
+// before it or, with the rebalance policy, splits the two evenly. Only the
+// word strategy cuts mid-sentence, so the others always merge; chunks from
+// different sections or declarations are left apart.
+func (c *Chunker) absorbRunt(strategy Strategy, text string, chunks []Chunk) []Chunk {
+	if c.minSize == 0 || len(chunks) < 2 {
+		return chunks
+	}
+	prev, last := chunks[len(chunks)-2], chunks[len(chunks)-1]
+	if prev.Section != last.Section || prev.Symbol != last.Symbol {
+		return chunks
+	}
+
+	// The words repeated from the previous chunk do not count towards the runt
+	lastWords := wordSpans(text, span{last.StartByte, last.EndByte})
+	if sum(c.budget.spanCosts(text, lastWords[min(last.OverlapWords, len(lastWords)):])) >= c.minSize {
+		return chunks
+	}
+
+	region := span{prev.StartByte, last.EndByte}
+	words := wordSpans(text, region)
+	chunks = chunks[:len(chunks)-2]
+
+	if _, ok := strategy.(*wordStrategy); ok && c.minPolicy == config.MinChunkRebalance {
+		return append(chunks, rebalance(text, prev, region, words, c.budget.spanCosts(text, words), c.budget.overlap)...)
+	}
+
+	merged := prev
+	merged.Text = text[region.start:region.end]
+	merged.EndByte = region.end
+	merged.WordCount = prev.WordCount + last.WordCount - last.OverlapWords
+	if prev.EmbeddingText != "" {
+		merged.EmbeddingText = joinWords(text, words)
+	}
+	return append(chunks, merged)
+}
+
+// rebalance splits the words of the two chunks spanning region into halves of
+// equal cost, the second starting with the trailing words of the first worth
+// up to overlap. The words prev repeats from the chunk before it stay at its
+// start and are not counted in either half.
+func rebalance(text string, prev Chunk, region span, words []span, costs []int, overlap int) []Chunk {
+	own := min(prev.OverlapWords, len(words)-1)
+	total := sum(costs[own:])
+	half, k := 0, own
+	for k < len(words)-1 && 2*(half+costs[k]) <= total {
+		half += costs[k]
+		k++
+	}
+	k = max(k, own+1)
+
+	next := k
+	repeated := 0
+	for next-1 > 0 && repeated+costs[next-1] <= overlap {
+		next--
+		repeated += costs[next]
+	}
+
+	first := prev
+	first.Text = text[prev.StartByte:words[k-1].end]
+	first.EndByte = words[k-1].end
+	first.WordCount = k
+	first.OverlapWords = own
+	first.EmbeddingText = joinWords(text, words[:k])
+
+	second := Chunk{
+		Text:          text[words[next].start:region.end],
+		WordCount:     len(words) - next,
+		StartByte:     words[next].start,
+		EndByte:       region.end,
+		EmbeddingText: joinWords(text, words[next:]),
+	}
+	if carried := k - next; carried > 0 {
+		second.OverlapWords = carried
+		second.OverlapChars = utf8.RuneCountInString(text[words[next].start:words[k-1].end])
+	}
+
+	return []Chunk{first, second}
+}
+
+// isMarkdownFile reports whether the file extension marks a Markdown document
+func isMarkdownFile(filePath string) bool {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// budget measures text against the configured chunk size, in words or in
+// model tokens
+type budget struct {
+	size      int
+	overlap   int
+	unit      string
+	tokenizer Tokenizer
+}
+
+// costs returns how much of the budget each word consumes: one per word, or
+// its token count when chunks are sized in tokens
+func (b *budget) costs(words []string) []int {
+	costs := make([]int, len(words))
+	for i, word := range words {
+		costs[i] = b.cost(word, true)
+	}
+	return costs
+}
+
+// spanCosts is costs for words located in text, which knows whether each
+// word follows a space or is written up against the previous one
+func (b *budget) spanCosts(text string, words []span) []int {
+	costs := make([]int, len(words))
+	for i, word := range words {
+		costs[i] = b.cost(text[word.start:word.end], i == 0 || words[i-1].end < word.start)
+	}
+	return costs
+}
+
+// cost returns the budget consumed by one word
+func (b *budget) cost(word string, spaced bool) int {
+	if b.unit != config.UnitTokens || b.tokenizer == nil {
+		return 1
+	}
+	if spaced {
+		// Words appear after a space inside a chunk, which byte-level BPE encodes
+		word = " " + word
+	}
+	return len(b.tokenizer.Tokenize(word))
+}
+
+// wordStrategy cuts text whenever the word budget is used up regardless of
+// sentence structure, sliding the window back by the overlap at each cut
+type wordStrategy struct {
+	budget *budget
+}
+
+// Split implements Strategy
+func (s *wordStrategy) Split(text string) []Chunk {
+	region := span{0, len(text)}
+	words := wordSpans(text, region)
+	if len(words) == 0 {
+		return nil
+	}
+	costs := s.budget.spanCosts(text, words)
+
+	// Text that fits within the chunk size comes back as a single chunk
+	return packWords(text, region, words, costs, s.budget.size, s.budget.overlap)
+}
+
+// packWords groups the words of region into windows whose costs fit within
+// size, each starting with trailing words of the previous window worth up to
+// overlap. Chunk text is sliced from the source, with the first and last
+// chunks reaching the edges of region; the words with whitespace collapsed
+// are what gets embedded.
+func packWords(text string, region span, words []span, costs []int, size, overlap int) []Chunk {
 	var chunks []Chunk
-	chunkIndex := 0
+	carried := 0
 
-	for i := 0; i < len(words); i += c.chunkSize {
-		end := i + c.chunkSize
-		if end > len(words) {
-			end = len(words)
+	for start := 0; start < len(words); {
+		// Every window takes at least one word so oversized words still progress
+		end := start
+		total := 0
+		for end < len(words) && (end == start || total+costs[end] <= size) {
+			total += costs[end]
+			end++
 		}
 
-		chunkWords := words[i:end]
-		chunkText := strings.Join(chunkWords, " ")
+		from, to := words[start].start, words[end-1].end
+		if start == 0 {
+			from = region.start
+		}
+		if end == len(words) {
+			to = region.end
+		}
 
-		// Clean up extra whitespace
-		chunkText = strings.TrimSpace(chunkText)
+		chunk := Chunk{
+			Text:          text[from:to],
+			WordCount:     end - start,
+			StartByte:     from,
+			EndByte:       to,
+			EmbeddingText: joinWords(text, words[start:end]),
+		}
+		if carried > 0 {
+			chunk.OverlapWords = carried
+			chunk.OverlapChars = utf8.RuneCountInString(text[words[start].start:words[start+carried-1].end])
+		}
+		chunks = append(chunks, chunk)
+
+		if end == len(words) {
+			break
+		}
 
-		if chunkText != "" {
-			chunks = append(chunks, Chunk{
-				Text:      chunkText,
-				WordCount: len(chunkWords),
-				Index:     chunkIndex,
-			})
-			chunkIndex++
+		// Step back over the overlap, always leaving the next window new words
+		next := end
+		repeated := 0
+		for next-1 > start && repeated+costs[next-1] <= overlap {
+			next--
+			repeated += costs[next]
 		}
+		carried = end - next
+		start = next
 	}
 
 	return chunks
 }
 
-// tokenizeWords splits text into words while preserving word boundaries
-func (c *Chunker) tokenizeWords(text string) []string {
-	var words []string
-	scanner := bufio.NewScanner(strings.NewReader(text))
-	scanner.Split(bufio.ScanWords)
-
-	for scanner.Scan() {
-		word := scanner.Text()
-		// Only include non-empty words that contain at least one letter or digit
-		if c.isValidWord(word) {
-			words = append(words, word)
-		}
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
 	}
+	return total
+}
 
-	return words
+// tokenizeWords splits text into words while preserving word boundaries
+func tokenizeWords(text string) []string {
+	return spanTexts(text, wordSpans(text, span{0, len(text)}))
+}
+
+// spanTexts returns the text of each span
+func spanTexts(text string, spans []span) []string {
+	texts := make([]string, len(spans))
+	for i, s := range spans {
+		texts[i] = text[s.start:s.end]
+	}
+	return texts
 }
 
 // isValidWord checks if a word is valid (contains at least one alphanumeric character)
-func (c *Chunker) isValidWord(word string) bool {
+func isValidWord(word string) bool {
 	if word == "" {
 		return false
 	}
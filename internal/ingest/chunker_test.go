@@ -1,14 +1,17 @@
 package ingest
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"wafer/internal/config"
 )
 
 func TestChunker_ChunkText(t *testing.T) {
-	chunker := NewChunker(10) // Small chunk size for testing
+	chunker := NewChunker(&config.Config{ChunkSize: 10}) // Small chunk size for testing
 
 	tests := []struct {
 		name      string
@@ -71,7 +74,7 @@ func TestChunker_ChunkFile(t *testing.T) {
 		t.Fatalf("Failed to create test file: %v", err)
 	}
 
-	chunker := NewChunker(300)
+	chunker := NewChunker(&config.Config{ChunkSize: 300})
 	chunks, err := chunker.ChunkFile(testFile)
 	if err != nil {
 		t.Fatalf("ChunkFile() error = %v", err)
@@ -87,7 +90,7 @@ func TestChunker_ChunkFile(t *testing.T) {
 }
 
 func TestChunker_ChunkFile_NonExistent(t *testing.T) {
-	chunker := NewChunker(300)
+	chunker := NewChunker(&config.Config{ChunkSize: 300})
 	_, err := chunker.ChunkFile("/non/existent/file.txt")
 	if err == nil {
 		t.Error("ChunkFile() expected error for non-existent file")
@@ -96,7 +99,7 @@ func TestChunker_ChunkFile_NonExistent(t *testing.T) {
 
 func TestNewChunker(t *testing.T) {
 	chunkSize := 500
-	chunker := NewChunker(chunkSize)
+	chunker := NewChunker(&config.Config{ChunkSize: chunkSize})
 
 	if chunker == nil {
 		t.Error("NewChunker() returned nil")
@@ -112,7 +115,7 @@ func TestNewChunker(t *testing.T) {
 }
 
 func TestChunker_LineEndingNormalization(t *testing.T) {
-	chunker := NewChunker(300)
+	chunker := NewChunker(&config.Config{ChunkSize: 300})
 
 	tests := []struct {
 		name     string
@@ -155,3 +158,175 @@ func TestChunker_LineEndingNormalization(t *testing.T) {
 		})
 	}
 }
+
+func TestChunker_Overlap(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 4, ChunkOverlap: 2})
+
+	chunks := chunker.ChunkText("a1 b2 c3 d4 e5 f6 g7 h8")
+
+	want := []string{"a1 b2 c3 d4", "c3 d4 e5 f6", "e5 f6 g7 h8"}
+	if len(chunks) != len(want) {
+		t.Fatalf("ChunkText() got %d chunks, want %d", len(chunks), len(want))
+	}
+
+	for i, chunk := range chunks {
+		if chunk.Text != want[i] {
+			t.Errorf("chunk %d: got %q, want %q", i, chunk.Text, want[i])
+		}
+
+		wantOverlap := 2
+		if i == 0 {
+			wantOverlap = 0
+		}
+		if chunk.OverlapWords != wantOverlap {
+			t.Errorf("chunk %d: got OverlapWords %d, want %d", i, chunk.OverlapWords, wantOverlap)
+		}
+		if i > 0 && chunk.Text[:chunk.OverlapChars] != chunks[i-1].Text[len(chunks[i-1].Text)-chunk.OverlapChars:] {
+			t.Errorf("chunk %d: overlap prefix %q does not match previous tail", i, chunk.Text[:chunk.OverlapChars])
+		}
+	}
+}
+
+func TestChunker_SourceOffsets(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 4})
+
+	original := "\r\n  alpha beta\r\ngamma   delta\r\n\r\nepsilon zeta eta\rtheta  \n"
+	chunks := chunker.ChunkText(original)
+
+	want := []struct {
+		text      string
+		embedded  string
+		startLine int
+		endLine   int
+	}{
+		{"alpha beta\ngamma   delta", "alpha beta gamma delta", 2, 3},
+		{"epsilon zeta eta\ntheta", "epsilon zeta eta theta", 5, 6},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("ChunkText() got %d chunks, want %d: %+v", len(chunks), len(want), chunks)
+	}
+
+	for i, w := range want {
+		chunk := chunks[i]
+		if chunk.Text != w.text {
+			t.Errorf("chunk %d: got text %q, want %q", i, chunk.Text, w.text)
+		}
+		if chunk.embeddingInput() != w.embedded {
+			t.Errorf("chunk %d: got embedding input %q, want %q", i, chunk.embeddingInput(), w.embedded)
+		}
+		if chunk.StartLine != w.startLine || chunk.EndLine != w.endLine {
+			t.Errorf("chunk %d: got lines %d-%d, want %d-%d", i, chunk.StartLine, chunk.EndLine, w.startLine, w.endLine)
+		}
+
+		// Offsets address the exact passage in the original bytes
+		passage := original[chunk.StartByte:chunk.EndByte]
+		normalized := strings.ReplaceAll(strings.ReplaceAll(passage, "\r\n", "\n"), "\r", "\n")
+		if normalized != w.text {
+			t.Errorf("chunk %d: original[%d:%d] = %q, want %q", i, chunk.StartByte, chunk.EndByte, passage, w.text)
+		}
+	}
+}
+
+func TestChunker_MinChunkSize(t *testing.T) {
+	words := make([]string, 303)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%d", i)
+	}
+	text := strings.Join(words, " ")
+
+	tests := []struct {
+		name      string
+		cfg       config.Config
+		text      string // Text to chunk, the 303 words when empty
+		wantWords []int  // Word count of each chunk
+		wantOver  []int  // Overlap words of each chunk
+	}{
+		{
+			name:      "runt kept without a minimum",
+			cfg:       config.Config{ChunkSize: 300},
+			wantWords: []int{300, 3},
+			wantOver:  []int{0, 0},
+		},
+		{
+			name:      "runt merged into previous chunk",
+			cfg:       config.Config{ChunkSize: 300, MinChunkSize: 10},
+			wantWords: []int{303},
+			wantOver:  []int{0},
+		},
+		{
+			name:      "last two chunks rebalanced",
+			cfg:       config.Config{ChunkSize: 300, MinChunkSize: 10, MinChunkPolicy: config.MinChunkRebalance},
+			wantWords: []int{151, 152},
+			wantOver:  []int{0, 0},
+		},
+		{
+			name:      "final chunk at the minimum is kept",
+			cfg:       config.Config{ChunkSize: 300, MinChunkSize: 3},
+			wantWords: []int{300, 3},
+			wantOver:  []int{0, 0},
+		},
+		{
+			name:      "overlap does not count towards the runt",
+			cfg:       config.Config{ChunkSize: 300, ChunkOverlap: 50, MinChunkSize: 10},
+			wantWords: []int{303},
+			wantOver:  []int{0},
+		},
+		{
+			name:      "rebalanced halves keep the overlap",
+			cfg:       config.Config{ChunkSize: 300, ChunkOverlap: 20, MinChunkSize: 30, MinChunkPolicy: config.MinChunkRebalance},
+			wantWords: []int{151, 172},
+			wantOver:  []int{0, 20},
+		},
+		{
+			name:      "rebalancing keeps the overlap of the previous chunk",
+			cfg:       config.Config{ChunkSize: 8, ChunkOverlap: 6, MinChunkSize: 2, MinChunkPolicy: config.MinChunkRebalance},
+			text:      "a b c d e f g h i j k",
+			wantWords: []int{8, 7, 8},
+			wantOver:  []int{0, 6, 6},
+		},
+		{
+			name:      "sentence strategy merges instead of rebalancing",
+			cfg:       config.Config{ChunkSize: 300, ChunkStrategy: config.StrategySentence, MinChunkSize: 10, MinChunkPolicy: config.MinChunkRebalance},
+			wantWords: []int{303},
+			wantOver:  []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := text
+			if tt.text != "" {
+				text = tt.text
+			}
+			chunks := NewChunker(&tt.cfg).ChunkText(text)
+
+			if len(chunks) != len(tt.wantWords) {
+				t.Fatalf("ChunkText() got %d chunks, want %d", len(chunks), len(tt.wantWords))
+			}
+			for i, chunk := range chunks {
+				if chunk.WordCount != tt.wantWords[i] || chunk.OverlapWords != tt.wantOver[i] {
+					t.Errorf("chunk %d has %d words (%d repeated), want %d (%d repeated)",
+						i, chunk.WordCount, chunk.OverlapWords, tt.wantWords[i], tt.wantOver[i])
+				}
+				if text[chunk.StartByte:chunk.EndByte] != chunk.Text {
+					t.Errorf("chunk %d offsets do not locate its text", i)
+				}
+				if got := len(strings.Fields(chunk.EmbeddingText)); got != chunk.WordCount {
+					t.Errorf("chunk %d embeds %d words, want %d", i, got, chunk.WordCount)
+				}
+			}
+			if last := chunks[len(chunks)-1]; !strings.HasSuffix(text, last.Text) {
+				t.Errorf("last chunk does not reach the end of the text: %q", last.Text)
+			}
+		})
+	}
+}
+
+func TestChunker_MinChunkSize_KeepsSections(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 300, MinChunkSize: 10})
+
+	chunks := chunker.chunkText(chunker.markdown, "# One\n\nA full section of text.\n\n# Two\n\nTiny.")
+	if len(chunks) != 2 {
+		t.Errorf("a runt from another section should not be merged, got %d chunks", len(chunks))
+	}
+}
@@ -0,0 +1,430 @@
+package ingest
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// codeLanguages maps source file extensions to language names
+var codeLanguages = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".java":  "java",
+	".kt":    "kotlin",
+	".scala": "scala",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".rs":    "rust",
+	".swift": "swift",
+	".php":   "php",
+	".rb":    "ruby",
+	".sh":    "shell",
+}
+
+var (
+	declKeywordRE = regexp.MustCompile(`\b(?:class|struct|interface|enum|trait|impl|fn|func|function|def|module|namespace|object|type)\s+([A-Za-z_$][\w$.:]*)`)
+	callableRE    = regexp.MustCompile(`([A-Za-z_$][\w$.:]*)\s*\(`)
+	assignmentRE  = regexp.MustCompile(`^(?:export\s+)?(?:const|let|var|val)?\s*([A-Za-z_$][\w$]*)\s*[:=]`)
+)
+
+// codeLanguage returns the language of a source file, or "" if it is not code
+func codeLanguage(filePath string) string {
+	return codeLanguages[strings.ToLower(filepath.Ext(filePath))]
+}
+
+// codeSegment is a top-level declaration together with its leading comments
+type codeSegment struct {
+	span
+	symbol string
+}
+
+// codeStrategy splits source code on top-level declarations, keeping the
+// original text verbatim
+type codeStrategy struct {
+	budget   *budget
+	language string
+}
+
+// Split implements Strategy
+func (s *codeStrategy) Split(text string) []Chunk {
+	var segments []codeSegment
+	if s.language == "go" {
+		segments = goSegments(text)
+	}
+	if segments == nil {
+		if s.language == "python" {
+			segments = indentSegments(text)
+		} else {
+			segments = braceSegments(text)
+		}
+	}
+
+	var chunks []Chunk
+	for _, segment := range segments {
+		for _, part := range s.splitSegment(text, segment.span) {
+			fields := strings.Fields(text[part.start:part.end])
+			if len(fields) == 0 {
+				continue
+			}
+			chunks = append(chunks, Chunk{
+				Text:         text[part.start:part.end],
+				WordCount:    len(fields),
+				Symbol:       segment.symbol,
+				CodeLanguage: s.language,
+				StartByte:    part.start,
+				EndByte:      part.end,
+			})
+		}
+	}
+
+	return chunks
+}
+
+// splitSegment cuts a declaration that exceeds the budget at line boundaries.
+// Code is measured in whitespace-separated fields so punctuation counts too.
+func (s *codeStrategy) splitSegment(text string, segment span) []span {
+	if sum(s.budget.costs(strings.Fields(text[segment.start:segment.end]))) <= s.budget.size {
+		return []span{segment}
+	}
+
+	var parts []span
+	start := segment.start
+	cost := 0
+	for _, line := range splitLines(text[segment.start:segment.end]) {
+		lineStart, lineEnd := segment.start+line.start, segment.start+line.end
+		lineCost := sum(s.budget.costs(strings.Fields(text[lineStart:lineEnd])))
+		if cost > 0 && cost+lineCost > s.budget.size {
+			parts = append(parts, span{start, lineStart - 1})
+			start = lineStart
+			cost = 0
+		}
+		cost += lineCost
+	}
+	parts = append(parts, span{start, segment.end})
+
+	return parts
+}
+
+// goSegments splits Go source on its top-level declarations using go/parser.
+// It returns nil when the source does not parse.
+func goSegments(text string) []codeSegment {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", text, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+
+	// The package clause and imports form the file header
+	var segments []codeSegment
+	headerEnd := offset(file.Name.End())
+	decls := file.Decls
+	for len(decls) > 0 {
+		gen, ok := decls[0].(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			break
+		}
+		headerEnd = offset(gen.End())
+		decls = decls[1:]
+	}
+	segments = append(segments, codeSegment{
+		span:   span{0, headerEnd},
+		symbol: "package " + file.Name.Name,
+	})
+
+	// Free-floating comments between declarations lead into the next one
+	prevEnd := headerEnd
+	for _, decl := range decls {
+		end := offset(decl.End())
+		segments = append(segments, codeSegment{
+			span:   span{skipSpace(text, prevEnd), end},
+			symbol: goDeclSymbol(decl),
+		})
+		prevEnd = end
+	}
+
+	// Trailing comments belong to the last declaration
+	if end := len(strings.TrimRightFunc(text, unicode.IsSpace)); end > prevEnd {
+		segments[len(segments)-1].end = end
+	}
+
+	return segments
+}
+
+// goDeclSymbol names a Go declaration, e.g. "Chunker.ChunkText" or "const (A, B)"
+func goDeclSymbol(decl ast.Decl) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			return receiverName(d.Recv.List[0].Type) + "." + d.Name.Name
+		}
+		return d.Name.Name
+
+	case *ast.GenDecl:
+		var names []string
+		for _, spec := range d.Specs {
+			switch sp := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, sp.Name.Name)
+			case *ast.ValueSpec:
+				for _, name := range sp.Names {
+					names = append(names, name.Name)
+				}
+			}
+		}
+		if d.Tok == token.TYPE && len(names) == 1 {
+			return names[0]
+		}
+		return fmt.Sprintf("%s (%s)", d.Tok, strings.Join(names, ", "))
+	}
+
+	return ""
+}
+
+// receiverName returns the type name of a method receiver
+func receiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(e.X)
+	case *ast.IndexExpr:
+		return receiverName(e.X)
+	case *ast.IndexListExpr:
+		return receiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// braceSegments splits C-family source into top-level blocks by tracking
+// brace depth; declarations end where a block closes or at a blank line
+// outside any block
+func braceSegments(text string) []codeSegment {
+	var segments []codeSegment
+	depth := 0
+	inBlockComment := false
+	start := -1
+
+	closeSegment := func(end int) {
+		if start >= 0 {
+			segments = append(segments, codeSegment{
+				span:   span{start, end},
+				symbol: symbolFromLines(text[start:end]),
+			})
+		}
+		start = -1
+	}
+
+	for _, line := range splitLines(text) {
+		content := text[line.start:line.end]
+		if strings.TrimSpace(content) == "" {
+			if depth == 0 {
+				closeSegment(previousLineEnd(text, line.start))
+			}
+			continue
+		}
+		if start < 0 {
+			start = line.start
+		}
+
+		wasNested := depth > 0
+		depth, inBlockComment = braceDepth(content, depth, inBlockComment)
+		if depth < 0 {
+			depth = 0
+		}
+		if wasNested && depth == 0 {
+			closeSegment(line.end)
+		}
+	}
+	closeSegment(len(text))
+
+	return mergeCommentSegments(text, segments)
+}
+
+// braceDepth updates the brace depth with one line, skipping braces inside
+// string literals and comments
+func braceDepth(line string, depth int, inBlockComment bool) (int, bool) {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inBlockComment:
+			if c == '*' && i+1 < len(line) && line[i+1] == '/' {
+				inBlockComment = false
+				i++
+			}
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '/' && i+1 < len(line) && line[i+1] == '/':
+			return depth, false
+		case c == '#' && strings.TrimSpace(line[:i]) == "":
+			// Preprocessor directives and shell-style comments
+			return depth, false
+		case c == '/' && i+1 < len(line) && line[i+1] == '*':
+			inBlockComment = true
+			i++
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		}
+	}
+	return depth, inBlockComment
+}
+
+// indentSegments splits indentation-structured source such as Python into
+// top-level definitions, keeping decorators and leading comments attached
+func indentSegments(text string) []codeSegment {
+	var segments []codeSegment
+	start := -1
+	prevIndented := false
+	prevDecorator := false
+	lastEnd := 0
+
+	lines := splitLines(text)
+	for i, line := range lines {
+		content := text[line.start:line.end]
+		trimmed := strings.TrimSpace(content)
+		if trimmed == "" {
+			continue
+		}
+
+		topLevel := content[0] != ' ' && content[0] != '\t'
+		definition := strings.HasPrefix(trimmed, "def ") || strings.HasPrefix(trimmed, "async def ") ||
+			strings.HasPrefix(trimmed, "class ") || strings.HasPrefix(trimmed, "@")
+
+		if start >= 0 && topLevel && (prevIndented || (definition && !prevDecorator)) {
+			// Pull contiguous comment lines above the definition into it
+			cut := line.start
+			for j := i - 1; j >= 0; j-- {
+				prev := strings.TrimSpace(text[lines[j].start:lines[j].end])
+				if !strings.HasPrefix(prev, "#") || lines[j].start <= start {
+					break
+				}
+				cut = lines[j].start
+			}
+			if cut > start {
+				segments = append(segments, codeSegment{
+					span:   span{start, previousLineEnd(text, cut)},
+					symbol: symbolFromLines(text[start:cut]),
+				})
+				start = cut
+			}
+		}
+		if start < 0 {
+			start = line.start
+		}
+
+		prevIndented = !topLevel
+		prevDecorator = topLevel && strings.HasPrefix(trimmed, "@")
+		lastEnd = line.end
+	}
+	if start >= 0 {
+		segments = append(segments, codeSegment{
+			span:   span{start, lastEnd},
+			symbol: symbolFromLines(text[start:lastEnd]),
+		})
+	}
+
+	return segments
+}
+
+// mergeCommentSegments attaches comment-only segments to the declaration that follows them
+func mergeCommentSegments(text string, segments []codeSegment) []codeSegment {
+	var merged []codeSegment
+	pendingStart := -1
+
+	for _, segment := range segments {
+		if isCommentOnly(text[segment.start:segment.end]) {
+			if pendingStart < 0 {
+				pendingStart = segment.start
+			}
+			continue
+		}
+		if pendingStart >= 0 {
+			segment.start = pendingStart
+			pendingStart = -1
+		}
+		merged = append(merged, segment)
+	}
+	if pendingStart >= 0 {
+		merged = append(merged, codeSegment{span: span{pendingStart, segments[len(segments)-1].end}})
+	}
+
+	return merged
+}
+
+// isCommentOnly reports whether every line of code is a comment
+func isCommentOnly(code string) bool {
+	for _, line := range strings.Split(code, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if !strings.HasPrefix(trimmed, "//") && !strings.HasPrefix(trimmed, "/*") &&
+			!strings.HasPrefix(trimmed, "*") && !isHashComment(trimmed) {
+			return false
+		}
+	}
+	return true
+}
+
+// isHashComment reports whether line is a "#" comment rather than a
+// preprocessor directive such as #include
+func isHashComment(line string) bool {
+	if !strings.HasPrefix(line, "#") {
+		return false
+	}
+	return len(line) == 1 || !unicode.IsLetter(rune(line[1]))
+}
+
+// symbolFromLines guesses the declared name from the first code line of a segment
+func symbolFromLines(code string) string {
+	for _, line := range strings.Split(code, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == '@' || isCommentOnly(trimmed) {
+			continue
+		}
+		if m := declKeywordRE.FindStringSubmatch(trimmed); m != nil {
+			return strings.TrimRight(m[1], ":.")
+		}
+		if m := callableRE.FindStringSubmatch(trimmed); m != nil {
+			return strings.TrimRight(m[1], ":.")
+		}
+		if m := assignmentRE.FindStringSubmatch(trimmed); m != nil {
+			return m[1]
+		}
+		return ""
+	}
+	return ""
+}
+
+// previousLineEnd returns the end of the line before the one starting at lineStart
+func previousLineEnd(text string, lineStart int) int {
+	end := lineStart
+	for end > 0 && (text[end-1] == '\n' || text[end-1] == ' ' || text[end-1] == '\t') {
+		end--
+	}
+	return end
+}
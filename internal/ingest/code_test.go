@@ -0,0 +1,155 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wafer/internal/config"
+)
+
+const sampleGo = `// Package shapes has geometry helpers.
+package shapes
+
+import "math"
+
+// Pi is re-exported for convenience.
+const (
+	Pi = math.Pi
+	E  = math.E
+)
+
+// Circle is a round shape.
+type Circle struct {
+	R float64
+}
+
+// Area returns the area of the circle.
+func (c *Circle) Area() float64 {
+	if c.R <= 0 {
+		return 0
+	}
+	return Pi * c.R * c.R
+}
+
+func New(r float64) *Circle { return &Circle{R: r} }
+`
+
+func TestCodeStrategy_Go(t *testing.T) {
+	strategy := &codeStrategy{budget: &budget{size: 300}, language: "go"}
+	chunks := strategy.Split(sampleGo)
+
+	wantSymbols := []string{"package shapes", "const (Pi, E)", "Circle", "Circle.Area", "New"}
+	if len(chunks) != len(wantSymbols) {
+		t.Fatalf("Split() got %d chunks, want %d: %+v", len(chunks), len(wantSymbols), chunks)
+	}
+	for i, chunk := range chunks {
+		if chunk.Symbol != wantSymbols[i] {
+			t.Errorf("chunk %d: got symbol %q, want %q", i, chunk.Symbol, wantSymbols[i])
+		}
+		if chunk.CodeLanguage != "go" || chunk.Language != "" {
+			t.Errorf("chunk %d: got code language %q and language %q, want go only", i, chunk.CodeLanguage, chunk.Language)
+		}
+	}
+
+	// Declarations keep their doc comments, indentation and punctuation verbatim
+	wantArea := "// Area returns the area of the circle.\nfunc (c *Circle) Area() float64 {\n\tif c.R <= 0 {\n\t\treturn 0\n\t}\n\treturn Pi * c.R * c.R\n}"
+	if chunks[3].Text != wantArea {
+		t.Errorf("method chunk = %q, want %q", chunks[3].Text, wantArea)
+	}
+}
+
+func TestCodeStrategy_BraceFallback(t *testing.T) {
+	source := `#include <stdio.h>
+
+/* Adds two numbers. */
+int add(int a, int b) {
+    return a + b; /* } in a comment */
+}
+
+// Entry point.
+int main(void) {
+    printf("{ %d }\n", add(1, 2));
+    return 0;
+}
+`
+	strategy := &codeStrategy{budget: &budget{size: 300}, language: "c"}
+	chunks := strategy.Split(source)
+
+	wantSymbols := []string{"", "add", "main"}
+	if len(chunks) != len(wantSymbols) {
+		t.Fatalf("Split() got %d chunks, want %d: %+v", len(chunks), len(wantSymbols), chunks)
+	}
+	for i, chunk := range chunks {
+		if chunk.Symbol != wantSymbols[i] {
+			t.Errorf("chunk %d: got symbol %q, want %q", i, chunk.Symbol, wantSymbols[i])
+		}
+	}
+	if !strings.HasPrefix(chunks[1].Text, "/* Adds two numbers. */") {
+		t.Errorf("leading comment should stay with its function, got %q", chunks[1].Text)
+	}
+}
+
+func TestCodeStrategy_IndentFallback(t *testing.T) {
+	source := `import os
+
+# Reads the config.
+@cache
+def load(path):
+    with open(path) as f:
+        return f.read()
+
+class Store:
+    def get(self, key):
+        return key
+`
+	strategy := &codeStrategy{budget: &budget{size: 300}, language: "python"}
+	chunks := strategy.Split(source)
+
+	wantSymbols := []string{"", "load", "Store"}
+	if len(chunks) != len(wantSymbols) {
+		t.Fatalf("Split() got %d chunks, want %d: %+v", len(chunks), len(wantSymbols), chunks)
+	}
+	for i, chunk := range chunks {
+		if chunk.Symbol != wantSymbols[i] {
+			t.Errorf("chunk %d: got symbol %q, want %q", i, chunk.Symbol, wantSymbols[i])
+		}
+	}
+	if !strings.HasPrefix(chunks[1].Text, "# Reads the config.\n@cache\ndef load") {
+		t.Errorf("comment and decorator should stay with the function, got %q", chunks[1].Text)
+	}
+}
+
+func TestCodeStrategy_SplitsOversizedDeclaration(t *testing.T) {
+	source := "func Long() {\n" + strings.Repeat("\tx := 1\n", 20) + "}\n"
+	strategy := &codeStrategy{budget: &budget{size: 12}, language: "go"}
+	chunks := strategy.Split("package p\n\n" + source)
+
+	if len(chunks) < 3 {
+		t.Fatalf("expected the function to be split, got %d chunks", len(chunks))
+	}
+	for _, chunk := range chunks[1:] {
+		if chunk.Symbol != "Long" {
+			t.Errorf("split part has symbol %q, want Long", chunk.Symbol)
+		}
+		if chunk.WordCount > 12 {
+			t.Errorf("split part exceeds budget with %d fields", chunk.WordCount)
+		}
+	}
+}
+
+func TestChunker_ChunkFile_Code(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shapes.go")
+	if err := os.WriteFile(path, []byte(sampleGo), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	chunks, err := NewChunker(&config.Config{ChunkSize: 300}).ChunkFile(path)
+	if err != nil {
+		t.Fatalf("ChunkFile() error = %v", err)
+	}
+	if len(chunks) != 5 || chunks[3].Symbol != "Circle.Area" {
+		t.Errorf("ChunkFile() should use the Go code strategy, got %+v", chunks)
+	}
+}
@@ -0,0 +1,225 @@
+package ingest
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+
+	"wafer/internal/config"
+)
+
+const (
+	// shingleWords is the number of consecutive words hashed as one MinHash
+	// feature; SimHash features are single words
+	shingleWords = 3
+
+	// minHashes is the number of hash functions in a MinHash signature,
+	// compared in minHashBands bands of minHashRows rows
+	minHashes    = 128
+	minHashRows  = 4
+	minHashBands = minHashes / minHashRows
+)
+
+// Deduplicator recognizes chunks whose text nearly repeats a chunk seen
+// earlier in the run, remembering the first of each group as canonical
+type Deduplicator struct {
+	method    string
+	threshold float64 // Smallest similarity, from 0 to 1, of a near-duplicate
+
+	ids     []string            // ID of each canonical chunk
+	simhash []uint64            // SimHash of each canonical chunk
+	minhash [][minHashes]uint32 // MinHash signature of each canonical chunk
+	buckets map[bandKey][]int   // Canonical chunks sharing a band of their signature
+	bands   int                 // Bands a SimHash is split into
+}
+
+// bandKey identifies the value of one band of a signature
+type bandKey struct {
+	band  int
+	value uint64
+}
+
+// NewDeduplicator creates a deduplicator comparing chunks with the given
+// method at the given similarity threshold (0 uses the default)
+func NewDeduplicator(method string, threshold float64) *Deduplicator {
+	if method == "" {
+		method = config.DedupSimHash
+	}
+	if threshold == 0 {
+		threshold = config.DefaultDedupThreshold
+	}
+
+	// A SimHash within the threshold differs in at most maxDistance bits, so
+	// splitting it into one band more than that guarantees a band matches
+	maxDistance := int((1 - threshold) * 64)
+	return &Deduplicator{
+		method:    method,
+		threshold: threshold,
+		buckets:   map[bandKey][]int{},
+		bands:     min(maxDistance+1, 64),
+	}
+}
+
+// dedupSignature is the signature of a chunk and the bands it is filed under
+type dedupSignature struct {
+	keys    []bandKey
+	simhash uint64
+	minhash [minHashes]uint32
+}
+
+// Check returns the ID of the canonical chunk that text nearly duplicates.
+// Otherwise it returns "" and the signature of text, which Add records once
+// the chunk is written; the signature is nil when text has no words.
+func (d *Deduplicator) Check(text string) (string, *dedupSignature) {
+	size := 1
+	if d.method == config.DedupMinHash {
+		size = shingleWords
+	}
+	shingles := shingleHashes(text, size)
+	if len(shingles) == 0 {
+		return "", nil
+	}
+
+	if d.method == config.DedupMinHash {
+		return d.checkMinHash(shingles)
+	}
+	return d.checkSimHash(shingles)
+}
+
+// Add records the chunk with the given ID and signature as canonical
+func (d *Deduplicator) Add(id string, signature *dedupSignature) {
+	if signature == nil {
+		return
+	}
+	d.ids = append(d.ids, id)
+	if d.method == config.DedupMinHash {
+		d.minhash = append(d.minhash, signature.minhash)
+	} else {
+		d.simhash = append(d.simhash, signature.simhash)
+	}
+	for _, key := range signature.keys {
+		d.buckets[key] = append(d.buckets[key], len(d.ids)-1)
+	}
+}
+
+// checkSimHash compares the SimHash of the shingles with the canonical chunks
+func (d *Deduplicator) checkSimHash(shingles []uint64) (string, *dedupSignature) {
+	signature := &dedupSignature{keys: make([]bandKey, d.bands), simhash: simHash(shingles)}
+	for band := range signature.keys {
+		signature.keys[band] = bandKey{band, bandBits(signature.simhash, band, d.bands)}
+		for _, i := range d.buckets[signature.keys[band]] {
+			if 1-float64(bits.OnesCount64(signature.simhash^d.simhash[i]))/64 >= d.threshold {
+				return d.ids[i], nil
+			}
+		}
+	}
+	return "", signature
+}
+
+// checkMinHash compares the MinHash signature of the shingles with the
+// canonical chunks that share a band of rows with it
+func (d *Deduplicator) checkMinHash(shingles []uint64) (string, *dedupSignature) {
+	signature := &dedupSignature{keys: make([]bandKey, minHashBands), minhash: minHash(shingles)}
+	for band := range signature.keys {
+		h := fnv.New64a()
+		for _, value := range signature.minhash[band*minHashRows : (band+1)*minHashRows] {
+			h.Write([]byte{byte(value), byte(value >> 8), byte(value >> 16), byte(value >> 24)})
+		}
+		signature.keys[band] = bandKey{band, h.Sum64()}
+		for _, i := range d.buckets[signature.keys[band]] {
+			if jaccardEstimate(&signature.minhash, &d.minhash[i]) >= d.threshold {
+				return d.ids[i], nil
+			}
+		}
+	}
+	return "", signature
+}
+
+// shingleHashes hashes every run of size lowercased words of text, or the
+// whole text when it is shorter
+func shingleHashes(text string, size int) []uint64 {
+	var words []string
+	for _, word := range wordSpans(text, span{0, len(text)}) {
+		words = append(words, strings.ToLower(text[word.start:word.end]))
+	}
+	if len(words) == 0 {
+		return nil
+	}
+
+	n := min(size, len(words))
+	hashes := make([]uint64, 0, len(words)-n+1)
+	for i := 0; i+n <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+n], " ")))
+		hashes = append(hashes, mix64(h.Sum64()))
+	}
+	return hashes
+}
+
+// simHash combines feature hashes into one hash whose bits follow the
+// majority of the features, so similar texts get hashes a few bits apart
+func simHash(features []uint64) uint64 {
+	var weights [64]int
+	for _, feature := range features {
+		for bit := range weights {
+			if feature&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var hash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+// bandBits returns band number band of a hash split into n bands
+func bandBits(hash uint64, band, n int) uint64 {
+	start, end := band*64/n, (band+1)*64/n
+	return (hash >> start) & (1<<(end-start) - 1)
+}
+
+// minHash returns the smallest value of each of minHashes hash functions
+// over the features; two signatures agree in a share of positions that
+// estimates the Jaccard similarity of their feature sets
+func minHash(features []uint64) [minHashes]uint32 {
+	var signature [minHashes]uint32
+	for i := range signature {
+		signature[i] = ^uint32(0)
+	}
+	for _, feature := range features {
+		for i := range signature {
+			if h := uint32(mix64(feature^uint64(i)*0x9e3779b97f4a7c15) >> 32); h < signature[i] {
+				signature[i] = h
+			}
+		}
+	}
+	return signature
+}
+
+// mix64 is the splitmix64 finalizer, used to derive independent hash functions
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// jaccardEstimate returns the share of positions where two signatures agree
+func jaccardEstimate(a, b *[minHashes]uint32) float64 {
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / minHashes
+}
@@ -0,0 +1,110 @@
+package ingest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"wafer/internal/config"
+)
+
+// boilerplateParagraph is a long paragraph repeated across documents
+const boilerplateParagraph = "This document is provided for informational purposes only and does not " +
+	"constitute legal advice. The company makes no warranties, express or implied, regarding the " +
+	"accuracy or completeness of the information contained herein. Readers should consult a " +
+	"qualified professional before acting on any of the statements made in this publication, " +
+	"and the company accepts no liability for decisions made on the basis of its contents."
+
+// checkAndAdd checks text and records it as canonical when it is new, as the
+// processor does once the chunk is written
+func checkAndAdd(d *Deduplicator, id, text string) string {
+	canonical, signature := d.Check(text)
+	d.Add(id, signature)
+	return canonical
+}
+
+func TestDeduplicator_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		first   string
+		second  string
+		wantDup bool
+	}{
+		{"identical", boilerplateParagraph, boilerplateParagraph, true},
+		{"case and spacing", boilerplateParagraph, strings.ToUpper(strings.ReplaceAll(boilerplateParagraph, " ", "  \n")), true},
+		{"one word changed", boilerplateParagraph, strings.Replace(boilerplateParagraph, "legal", "financial", 1), true},
+		{"unrelated", boilerplateParagraph, "Quarterly revenue grew by twenty percent, driven by strong demand in the northern region and new product lines.", false},
+		{"half rewritten", boilerplateParagraph, boilerplateParagraph[:len(boilerplateParagraph)/2] + " Nothing else in this version of the notice matches the original wording at all, as every later sentence was replaced.", false},
+		{"no words", "--- ***", "--- ***", false},
+	}
+
+	for _, method := range []string{config.DedupSimHash, config.DedupMinHash} {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/%s", method, tt.name), func(t *testing.T) {
+				d := NewDeduplicator(method, 0)
+				if got := checkAndAdd(d, "first", tt.first); got != "" {
+					t.Fatalf("Check(first) = %q, want a new canonical chunk", got)
+				}
+				got := checkAndAdd(d, "second", tt.second)
+				if tt.wantDup && got != "first" {
+					t.Errorf("Check(second) = %q, want duplicate of first", got)
+				}
+				if !tt.wantDup && got != "" {
+					t.Errorf("Check(second) = %q, want a new canonical chunk", got)
+				}
+			})
+		}
+	}
+}
+
+func TestDeduplicator_Add(t *testing.T) {
+	d := NewDeduplicator(config.DedupSimHash, 0)
+
+	// A chunk checked but never added, such as one that failed to write, is
+	// not canonical
+	if got, _ := d.Check(boilerplateParagraph); got != "" {
+		t.Fatalf("Check() = %q, want a new canonical chunk", got)
+	}
+	got, signature := d.Check(boilerplateParagraph)
+	if got != "" || signature == nil {
+		t.Fatalf("Check() = %q, %v, want a new canonical chunk", got, signature)
+	}
+	d.Add("written", signature)
+	if got, _ := d.Check(boilerplateParagraph); got != "written" {
+		t.Errorf("Check() = %q, want duplicate of written", got)
+	}
+}
+
+func TestDeduplicator_Threshold(t *testing.T) {
+	changed := strings.Replace(strings.Replace(boilerplateParagraph, "legal", "financial", 1), "professional", "advisor", 1)
+
+	strict := NewDeduplicator(config.DedupMinHash, 1)
+	checkAndAdd(strict, "first", boilerplateParagraph)
+	if got := checkAndAdd(strict, "second", changed); got != "" {
+		t.Errorf("threshold 1: Check() = %q, want only exact matches", got)
+	}
+	if got := checkAndAdd(strict, "third", boilerplateParagraph); got != "first" {
+		t.Errorf("threshold 1: Check() = %q, want the exact copy matched", got)
+	}
+
+	loose := NewDeduplicator(config.DedupMinHash, 0.5)
+	checkAndAdd(loose, "first", boilerplateParagraph)
+	if got := checkAndAdd(loose, "second", changed); got != "first" {
+		t.Errorf("threshold 0.5: Check() = %q, want duplicate of first", got)
+	}
+}
+
+func TestBandBits(t *testing.T) {
+	hash := uint64(0xFEDCBA9876543210)
+
+	// The bands of a hash reassemble into it
+	for _, n := range []int{1, 3, 7, 64} {
+		var rebuilt uint64
+		for band := 0; band < n; band++ {
+			rebuilt |= bandBits(hash, band, n) << (band * 64 / n)
+		}
+		if rebuilt != hash {
+			t.Errorf("%d bands rebuild %x, want %x", n, rebuilt, hash)
+		}
+	}
+}
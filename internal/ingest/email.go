@@ -0,0 +1,321 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// emailMaxDepth bounds the nesting of multipart bodies, so malformed
+// messages cannot recurse without end
+const emailMaxDepth = 16
+
+// emailWordDecoder decodes the encoded words of headers, such as
+// "=?iso-8859-1?q?Caf=E9?=", in any charset the HTML standard knows
+var emailWordDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			return nil, err
+		}
+		return enc.NewDecoder().Reader(input), nil
+	},
+}
+
+// emailAttributionRE matches the line that introduces a quoted reply, such
+// as "On Mon, 3 Jun 2024, Ana wrote:"
+var emailAttributionRE = regexp.MustCompile(`(?i)^on\b.*\bwrote:$`)
+
+// emailSeparatorRE matches the lines that start a reply chain quoted without
+// "> " markers, such as "-----Original Message-----"
+var emailSeparatorRE = regexp.MustCompile(`(?i)^(-{2,}\s*original message\s*-{2,}|_{20,})$`)
+
+// mboxQuotedFromRE matches a body line starting with "From " that an mbox
+// archive quoted with ">"
+var mboxQuotedFromRE = regexp.MustCompile(`^>+From `)
+
+// emailExtractor extracts the messages of an RFC 5322 .eml file, or of an
+// mbox archive, each as a part of its own. The text comes from the
+// text/plain body of a message, or its text/html body when it has none,
+// decoded from quoted-printable or base64 and its charset; attachments are
+// left out, as are quoted replies. The sender, recipients, subject, date and
+// message ID are kept as metadata.
+type emailExtractor struct {
+	mbox bool // The file is an mbox archive rather than a single message
+}
+
+// Extract implements Extractor
+func (e emailExtractor) Extract(data []byte) ([]ExtractedText, error) {
+	messages := [][]byte{data}
+	if e.mbox {
+		messages = splitMbox(data)
+	}
+
+	var parts []ExtractedText
+	for i, raw := range messages {
+		part, err := parseEmail(raw)
+		if err != nil {
+			if e.mbox {
+				return nil, fmt.Errorf("failed to parse message %d: %w", i+1, err)
+			}
+			return nil, err
+		}
+		if part.Text != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts, nil
+}
+
+// splitMbox splits an mbox archive into its messages at the "From " lines
+// that start each, at the start of the archive or after a blank line, and
+// undoes the ">From " quoting of the lines of their bodies
+func splitMbox(data []byte) [][]byte {
+	var messages [][]byte
+	var message []byte
+	blank := true // The previous line was blank
+	for len(data) > 0 {
+		line := data
+		if end := bytes.IndexByte(data, '\n'); end >= 0 {
+			line = data[:end+1]
+		}
+		data = data[len(line):]
+
+		if blank && bytes.HasPrefix(line, []byte("From ")) {
+			if len(bytes.TrimSpace(message)) > 0 {
+				messages = append(messages, message)
+			}
+			message = nil
+			continue
+		}
+		blank = len(bytes.TrimSpace(line)) == 0
+		if mboxQuotedFromRE.Match(line) {
+			line = line[1:]
+		}
+		message = append(message, line...)
+	}
+	if len(bytes.TrimSpace(message)) > 0 {
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// parseEmail extracts the text and metadata of a message
+func parseEmail(data []byte) (ExtractedText, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return ExtractedText{}, fmt.Errorf("failed to parse message: %w", err)
+	}
+	body, _, err := emailBody(textproto.MIMEHeader(msg.Header), msg.Body, 0)
+	if err != nil {
+		return ExtractedText{}, err
+	}
+
+	metadata := map[string]string{}
+	set := func(key, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			metadata[key] = value
+		}
+	}
+	set("from", emailAddresses(msg.Header, "From"))
+	set("to", emailAddresses(msg.Header, "To"))
+	set("subject", emailHeader(msg.Header, "Subject"))
+	if date, err := msg.Header.Date(); err == nil {
+		set("date", date.Format(time.RFC3339))
+	} else {
+		set("date", msg.Header.Get("Date"))
+	}
+	set("message_id", strings.Trim(msg.Header.Get("Message-ID"), "<> \t"))
+
+	part := ExtractedText{Text: cleanLines(stripQuotedReplies(body))}
+	if len(metadata) > 0 {
+		part.Metadata = metadata
+	}
+	return part, nil
+}
+
+// emailHeader returns a header with its encoded words decoded
+func emailHeader(header mail.Header, key string) string {
+	value := header.Get(key)
+	if decoded, err := emailWordDecoder.DecodeHeader(value); err == nil {
+		value = decoded
+	}
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// emailAddresses returns the addresses of a header as a comma-separated
+// list of "Name <address>", or the decoded header when it does not parse
+func emailAddresses(header mail.Header, key string) string {
+	parser := mail.AddressParser{WordDecoder: emailWordDecoder}
+	list, err := parser.ParseList(header.Get(key))
+	if err != nil {
+		return emailHeader(header, key)
+	}
+	addresses := make([]string, len(list))
+	for i, addr := range list {
+		addresses[i] = addr.Address
+		if addr.Name != "" {
+			addresses[i] = addr.Name + " <" + addr.Address + ">"
+		}
+	}
+	return strings.Join(addresses, ", ")
+}
+
+// emailBody returns the text of a body part and whether it was converted
+// from HTML. Of the alternatives of a multipart/alternative body, the plain
+// text one is preferred; the parts of other multipart bodies are joined.
+func emailBody(header textproto.MIMEHeader, body io.Reader, depth int) (string, bool, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// A message without a content type is plain text
+		mediaType, params = "text/plain", nil
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		if depth >= emailMaxDepth || params["boundary"] == "" {
+			return "", false, nil
+		}
+		r := multipart.NewReader(body, params["boundary"])
+		var texts []string
+		plain, html := "", ""
+		for {
+			p, err := r.NextRawPart()
+			if err != nil {
+				// The end of the body, or a truncated or malformed boundary
+				// after which the parts read so far are kept
+				break
+			}
+			if disposition, _, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition")); disposition == "attachment" {
+				continue
+			}
+			text, isHTML, err := emailBody(p.Header, p, depth+1)
+			if err != nil {
+				return "", false, err
+			}
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			texts = append(texts, text)
+			if isHTML && html == "" {
+				html = text
+			} else if !isHTML && plain == "" {
+				plain = text
+			}
+		}
+		if mediaType == "multipart/alternative" {
+			if plain != "" {
+				return plain, false, nil
+			}
+			return html, html != "", nil
+		}
+		return strings.Join(texts, "\n\n"), plain == "" && html != "", nil
+
+	case mediaType == "text/plain" || mediaType == "text/html":
+		// Decoding stops at the first malformed sequence, keeping the text before it
+		data, _ := io.ReadAll(emailDecoder(header.Get("Content-Transfer-Encoding"), body))
+		text := emailCharset(data, params["charset"])
+		if mediaType == "text/plain" {
+			return text, false, nil
+		}
+		parts, err := htmlExtractor{}.Extract([]byte(text))
+		if err != nil || len(parts) == 0 {
+			return "", true, err
+		}
+		return parts[0].Text, true, nil
+	}
+	return "", false, nil
+}
+
+// emailDecoder returns a reader decoding a body of a content transfer
+// encoding; 7bit, 8bit and binary bodies are read as they are
+func emailDecoder(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	}
+	return body
+}
+
+// emailCharset decodes text of a charset to UTF-8. Text of an unknown
+// charset is read as UTF-8, with invalid bytes replaced.
+func emailCharset(data []byte, charset string) string {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8", "us-ascii":
+	default:
+		if enc, err := htmlindex.Get(charset); err == nil {
+			if decoded, err := enc.NewDecoder().Bytes(data); err == nil {
+				data = decoded
+			}
+		}
+	}
+	return strings.ToValidUTF8(strings.ReplaceAll(string(data), "\r\n", "\n"), "\uFFFD")
+}
+
+// stripQuotedReplies removes the replies quoted in a message: lines quoted
+// with ">", the line attributing them, and any reply chain quoted without
+// markers after an attribution or an "Original Message" separator, such as
+// Outlook's "From: ... Sent: ..." block
+func stripQuotedReplies(text string) string {
+	lines := strings.Split(text, "\n")
+	var kept []string
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, ">") {
+			continue
+		}
+
+		// An attribution may be wrapped onto a second line
+		attribution := 0
+		if emailAttributionRE.MatchString(line) {
+			attribution = 1
+		} else if i+1 < len(lines) && strings.HasPrefix(line, "On ") && emailAttributionRE.MatchString(line+" "+strings.TrimSpace(lines[i+1])) {
+			attribution = 2
+		}
+		if attribution > 0 {
+			// Lines quoted with ">" after it are dropped on their own, and a
+			// reply written below them is kept
+			next := i + attribution
+			for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+				next++
+			}
+			if next < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[next]), ">") {
+				i += attribution - 1
+				continue
+			}
+			break
+		}
+		if emailSeparatorRE.MatchString(line) || isOutlookReplyHeader(lines, i) {
+			break
+		}
+		kept = append(kept, lines[i])
+	}
+	return strings.Join(kept, "\n")
+}
+
+// isOutlookReplyHeader reports whether a "From:" line followed closely by a
+// "Sent:" line starts at line i, as Outlook quotes the message replied to
+func isOutlookReplyHeader(lines []string, i int) bool {
+	if !strings.HasPrefix(strings.TrimSpace(lines[i]), "From:") {
+		return false
+	}
+	for j := i + 1; j < len(lines) && j <= i+3; j++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[j]), "Sent:") {
+			return true
+		}
+	}
+	return false
+}
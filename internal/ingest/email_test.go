@@ -0,0 +1,179 @@
+package ingest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// crlf turns the line endings of a message into CRLF, as sent over SMTP
+func crlf(message string) string {
+	return strings.ReplaceAll(message, "\n", "\r\n")
+}
+
+func TestEmailExtractor(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		want     string
+		wantMeta map[string]string
+	}{
+		{
+			name: "plain text with encoded headers",
+			message: "From: =?iso-8859-1?q?Jos=E9?= <jose@example.com>\n" +
+				"To: support@example.com, \"Ops Team\" <ops@example.com>\n" +
+				"Subject: =?utf-8?b?UmXDp3UgbWlzc2luZw==?=\n" +
+				"Date: Mon, 3 Jun 2024 09:15:00 +0200\n" +
+				"Message-ID: <abc123@mail.example.com>\n" +
+				"\n" +
+				"My receipt never arrived.\nCan you resend it?\n",
+			want: "My receipt never arrived.\nCan you resend it?",
+			wantMeta: map[string]string{
+				"from":       "José <jose@example.com>",
+				"to":         "support@example.com, Ops Team <ops@example.com>",
+				"subject":    "Reçu missing",
+				"date":       "2024-06-03T09:15:00+02:00",
+				"message_id": "abc123@mail.example.com",
+			},
+		},
+		{
+			name: "alternative prefers plain text",
+			message: crlf("Subject: Invoice\n" +
+				"Content-Type: multipart/alternative; boundary=\"alt\"\n" +
+				"\n" +
+				"--alt\n" +
+				"Content-Type: text/html; charset=utf-8\n" +
+				"\n" +
+				"<p>HTML version</p>\n" +
+				"--alt\n" +
+				"Content-Type: text/plain; charset=iso-8859-1\n" +
+				"Content-Transfer-Encoding: quoted-printable\n" +
+				"\n" +
+				"Your invoice is attached, Andr=E9. This line is long and is soft=\n" +
+				" wrapped.\n" +
+				"--alt--\n"),
+			want:     "Your invoice is attached, André. This line is long and is soft wrapped.",
+			wantMeta: map[string]string{"subject": "Invoice"},
+		},
+		{
+			name: "html only, with attachments left out",
+			message: "Content-Type: multipart/mixed; boundary=mix\n" +
+				"\n" +
+				"--mix\n" +
+				"Content-Type: multipart/related; boundary=rel\n" +
+				"\n" +
+				"--rel\n" +
+				"Content-Type: text/html\n" +
+				"Content-Transfer-Encoding: base64\n" +
+				"\n" +
+				"PGgxPlN0YXR1czwvaDE+PHA+QWxsIHN5c3RlbXMg\nZ288L3A+\n" +
+				"--rel--\n" +
+				"--mix\n" +
+				"Content-Type: text/plain; name=log.txt\n" +
+				"Content-Disposition: attachment; filename=log.txt\n" +
+				"\n" +
+				"secret log lines\n" +
+				"--mix--\n",
+			want: "# Status\n\nAll systems go",
+		},
+		{
+			name: "quoted replies are stripped",
+			message: "Subject: Re: Refund\n\n" +
+				"Thanks, that worked.\n\n" +
+				"On Tue, 4 Jun 2024 at 10:00, Support Team <support@example.com>\nwrote:\n" +
+				"> Please try again.\n" +
+				">> Original question\n",
+			want:     "Thanks, that worked.",
+			wantMeta: map[string]string{"subject": "Re: Refund"},
+		},
+		{
+			name: "reply below the quote is kept",
+			message: "\n" +
+				"On Tue, Ana wrote:\n" +
+				"> Is the build green?\n" +
+				"\n" +
+				"Yes, since this morning.\n",
+			want: "Yes, since this morning.",
+		},
+		{
+			name: "outlook reply chain",
+			message: "\n" +
+				"Approved.\n\n" +
+				"From: Bo <bo@example.com>\n" +
+				"Sent: Monday, June 3, 2024 9:00 AM\n" +
+				"To: Ana\n" +
+				"Please approve the budget.\n",
+			want: "Approved.",
+		},
+		{
+			name: "original message separator",
+			message: "\n" +
+				"See below.\n" +
+				"-----Original Message-----\n" +
+				"Old text\n",
+			want: "See below.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := emailExtractor{}.Extract([]byte(tt.message))
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if len(parts) != 1 {
+				t.Fatalf("Extract() returned %d parts, want 1", len(parts))
+			}
+			if parts[0].Text != tt.want {
+				t.Errorf("Extract() = %q, want %q", parts[0].Text, tt.want)
+			}
+			if fmt.Sprint(parts[0].Metadata) != fmt.Sprint(tt.wantMeta) {
+				t.Errorf("metadata = %v, want %v", parts[0].Metadata, tt.wantMeta)
+			}
+		})
+	}
+
+	if _, err := (emailExtractor{}).Extract([]byte("not a header line\n\nbody")); err == nil {
+		t.Error("Extract() of a malformed message should fail")
+	}
+}
+
+func TestEmailExtractor_Mbox(t *testing.T) {
+	mbox := "From ana@example.com Mon Jun  3 09:15:00 2024\n" +
+		"From: ana@example.com\n" +
+		"Subject: First\n" +
+		"Message-ID: <1@example.com>\n" +
+		"\n" +
+		"Hello.\n" +
+		">From the start, this line was quoted.\n" +
+		"From here on, this line was not.\n" +
+		"\n" +
+		"From bo@example.com Mon Jun  3 10:00:00 2024\n" +
+		"From: bo@example.com\n" +
+		"Subject: Empty\n" +
+		"\n" +
+		"> Only a quote\n" +
+		"\n" +
+		"From cy@example.com Mon Jun  3 11:00:00 2024\n" +
+		"From: cy@example.com\n" +
+		"Subject: Third\n" +
+		"Message-ID: <3@example.com>\n" +
+		"\n" +
+		"Bye.\n"
+
+	parts, err := emailExtractor{mbox: true}.Extract([]byte(mbox))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	var got []string
+	for _, part := range parts {
+		got = append(got, part.Metadata["message_id"]+": "+part.Text)
+	}
+	want := []string{
+		"1@example.com: Hello.\nFrom the start, this line was quoted.\nFrom here on, this line was not.",
+		"3@example.com: Bye.",
+	}
+	if strings.Join(got, "\f") != strings.Join(want, "\f") {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,186 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"wafer/internal/config"
+)
+
+// byteOrderMarks maps the byte order mark that starts a file to its encoding
+var byteOrderMarks = []struct {
+	mark     []byte
+	encoding string
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, config.EncodingUTF8},
+	{[]byte{0xFF, 0xFE}, config.EncodingUTF16LE},
+	{[]byte{0xFE, 0xFF}, config.EncodingUTF16BE},
+}
+
+// fileEncoding is the character encoding a file is decoded from
+type fileEncoding struct {
+	name    string // One of the config.Encoding constants
+	bom     int    // Length of the byte order mark starting the file
+	invalid int    // Bytes that are not valid UTF-8, replaced when decoding
+}
+
+// plain reports whether the file is UTF-8 that can be chunked as it is
+func (e fileEncoding) plain() bool {
+	return e.name == config.EncodingUTF8 && e.bom == 0 && e.invalid == 0
+}
+
+// detectEncoding finds the encoding of a file. A byte order mark decides it
+// unless the override names another encoding; otherwise the file is UTF-8 if
+// it is mostly valid UTF-8, and a legacy 8-bit encoding if not.
+func detectEncoding(filePath, override string) (fileEncoding, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fileEncoding{}, fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	r := bufio.NewReaderSize(file, streamBlock)
+	head, err := r.Peek(3)
+	if err != nil && err != io.EOF {
+		return fileEncoding{}, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	enc := fileEncoding{name: override}
+	if override == "" || override == config.EncodingAuto {
+		enc.name = ""
+	}
+	for _, bom := range byteOrderMarks {
+		if bytes.HasPrefix(head, bom.mark) && (enc.name == "" || enc.name == bom.encoding) {
+			enc.name, enc.bom = bom.encoding, len(bom.mark)
+			break
+		}
+	}
+	if enc.name != "" && enc.name != config.EncodingUTF8 {
+		return enc, nil
+	}
+
+	scan, err := scanUTF8(r)
+	if err != nil {
+		return fileEncoding{}, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+	switch {
+	case enc.name == config.EncodingUTF8 || scan.invalid == 0 || scan.valid > scan.invalid:
+		// Damaged UTF-8 still has more valid multibyte characters than
+		// stray bytes; its invalid bytes are replaced and reported
+		enc.name = config.EncodingUTF8
+		enc.invalid = scan.invalid
+	case scan.windows1252 > 0:
+		enc.name = config.EncodingWindows1252
+	default:
+		// Without the printable characters Windows-1252 adds in 0x80-0x9F,
+		// the two code pages decode identically
+		enc.name = config.EncodingLatin1
+	}
+	return enc, nil
+}
+
+// utf8Scan counts the non-ASCII bytes of a file
+type utf8Scan struct {
+	valid       int // Valid multibyte UTF-8 characters
+	invalid     int // Bytes that are not part of a valid UTF-8 character
+	windows1252 int // Invalid bytes that Windows-1252 prints but Latin-1 reserves for controls
+}
+
+// scanUTF8 reads r to the end, checking that it is valid UTF-8
+func scanUTF8(r io.Reader) (utf8Scan, error) {
+	var scan utf8Scan
+	buf := make([]byte, streamBlock)
+	n := 0 // Bytes carried over from the previous read
+	for {
+		m, err := r.Read(buf[n:])
+		n += m
+		eof := err == io.EOF
+		if err != nil && !eof {
+			return scan, err
+		}
+
+		i := 0
+		for i < n {
+			if buf[i] < utf8.RuneSelf {
+				i++
+				continue
+			}
+			if !eof && !utf8.FullRune(buf[i:n]) {
+				break // Read the rest of the character first
+			}
+			if r, size := utf8.DecodeRune(buf[i:n]); r == utf8.RuneError && size == 1 {
+				scan.invalid++
+				if buf[i] < 0xA0 && charmap.Windows1252.DecodeByte(buf[i]) != rune(buf[i]) {
+					scan.windows1252++
+				}
+				i++
+			} else {
+				scan.valid++
+				i += size
+			}
+		}
+		n = copy(buf, buf[i:n])
+		if eof {
+			return scan, nil
+		}
+	}
+}
+
+// decodeText decodes the bytes of a file to UTF-8, dropping its byte order
+// mark and replacing invalid sequences with U+FFFD, and returns the map from
+// the decoded text back to the bytes
+func decodeText(data []byte, enc fileEncoding) (string, *rewriteMap) {
+	w := &rewriter{}
+	switch enc.name {
+	case config.EncodingUTF16LE, config.EncodingUTF16BE:
+		var order binary.ByteOrder = binary.LittleEndian
+		if enc.name == config.EncodingUTF16BE {
+			order = binary.BigEndian
+		}
+		for i := enc.bom; i < len(data); {
+			if i+2 > len(data) {
+				w.writeRune(utf8.RuneError, i) // Odd trailing byte
+				break
+			}
+			r, size := rune(order.Uint16(data[i:])), 2
+			if utf16.IsSurrogate(r) {
+				low := utf8.RuneError
+				if i+4 <= len(data) {
+					low = rune(order.Uint16(data[i+2:]))
+				}
+				if r = utf16.DecodeRune(r, low); r != utf8.RuneError {
+					size = 4
+				}
+			}
+			w.writeRune(r, i)
+			i += size
+		}
+	case config.EncodingWindows1252:
+		for i := enc.bom; i < len(data); i++ {
+			w.writeRune(charmap.Windows1252.DecodeByte(data[i]), i)
+		}
+	case config.EncodingLatin1:
+		for i := enc.bom; i < len(data); i++ {
+			w.writeRune(rune(data[i]), i)
+		}
+	default:
+		for i := enc.bom; i < len(data); {
+			r, size := utf8.DecodeRune(data[i:])
+			if r == utf8.RuneError && size == 1 {
+				w.writeRune(r, i)
+			} else {
+				w.write(string(data[i:i+size]), i)
+			}
+			i += size
+		}
+	}
+	return w.b.String(), &rewriteMap{from: append(w.from, len(data))}
+}
@@ -0,0 +1,148 @@
+package ingest
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"wafer/internal/config"
+)
+
+// encodeUTF16 encodes s as UTF-16 in the given byte order, after the byte
+// order mark when bom is set
+func encodeUTF16(s string, order binary.AppendByteOrder, bom bool) string {
+	var b []byte
+	if bom {
+		b = order.AppendUint16(b, 0xFEFF)
+	}
+	for _, unit := range utf16.Encode([]rune(s)) {
+		b = order.AppendUint16(b, unit)
+	}
+	return string(b)
+}
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		override string
+		want     fileEncoding
+	}{
+		{"ascii", "plain text\n", "", fileEncoding{name: config.EncodingUTF8}},
+		{"utf-8", "café, naïve — “quoted”\n", config.EncodingAuto, fileEncoding{name: config.EncodingUTF8}},
+		{"utf-8 bom", "\xEF\xBB\xBFcafé\n", "", fileEncoding{name: config.EncodingUTF8, bom: 3}},
+		{"utf-16le bom", encodeUTF16("café\n", binary.LittleEndian, true), "", fileEncoding{name: config.EncodingUTF16LE, bom: 2}},
+		{"utf-16be bom", encodeUTF16("café\n", binary.BigEndian, true), "", fileEncoding{name: config.EncodingUTF16BE, bom: 2}},
+		{"windows-1252", "\x93Caf\xe9\x94 \x96 50\x80\n", "", fileEncoding{name: config.EncodingWindows1252}},
+		{"latin-1", "Caf\xe9 na\xefve, \xbfqu\xe9?\n", "", fileEncoding{name: config.EncodingLatin1}},
+		{"damaged utf-8", "café, naïve, déjà vu, \xff\n", "", fileEncoding{name: config.EncodingUTF8, invalid: 1}},
+		{"override", "Caf\xe9\n", config.EncodingWindows1252, fileEncoding{name: config.EncodingWindows1252}},
+		{"override utf-8", "Caf\xe9\n", config.EncodingUTF8, fileEncoding{name: config.EncodingUTF8, invalid: 1}},
+		{"override keeps matching bom", encodeUTF16("x", binary.BigEndian, true), config.EncodingUTF16BE, fileEncoding{name: config.EncodingUTF16BE, bom: 2}},
+		{"override without bom", encodeUTF16("x", binary.BigEndian, false), config.EncodingUTF16BE, fileEncoding{name: config.EncodingUTF16BE}},
+		{"empty", "", "", fileEncoding{name: config.EncodingUTF8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			got, err := detectEncoding(path, tt.override)
+			if err != nil {
+				t.Fatalf("detectEncoding() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("detectEncoding() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanUTF8_SplitReads(t *testing.T) {
+	// A character split across two blocks is still valid
+	content := make([]byte, streamBlock+2)
+	for i := range content {
+		content[i] = 'a'
+	}
+	copy(content[streamBlock-1:], "é!")
+
+	file := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(file, content, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	got, err := detectEncoding(file, "")
+	if err != nil {
+		t.Fatalf("detectEncoding() error = %v", err)
+	}
+	if got.invalid != 0 {
+		t.Errorf("found %d invalid bytes, want 0", got.invalid)
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		enc  fileEncoding
+		want string
+		word string // A word of want whose offsets are checked
+	}{
+		{
+			name: "windows-1252",
+			data: "\x93Caf\xe9\x94 costs 5\x80",
+			enc:  fileEncoding{name: config.EncodingWindows1252},
+			want: "“Café” costs 5€",
+			word: "costs",
+		},
+		{
+			name: "latin-1",
+			data: "na\xefve caf\xe9",
+			enc:  fileEncoding{name: config.EncodingLatin1},
+			want: "naïve café",
+			word: "café",
+		},
+		{
+			name: "utf-16le with surrogates",
+			data: encodeUTF16("émoji 😀 text", binary.LittleEndian, true),
+			enc:  fileEncoding{name: config.EncodingUTF16LE, bom: 2},
+			want: "émoji 😀 text",
+			word: "text",
+		},
+		{
+			name: "utf-16be odd length",
+			data: encodeUTF16("ab", binary.BigEndian, false) + "\x00",
+			enc:  fileEncoding{name: config.EncodingUTF16BE},
+			want: "ab�",
+			word: "ab",
+		},
+		{
+			name: "utf-8 with bom and invalid bytes",
+			data: "\xEF\xBB\xBFcaf\xe9 au lait",
+			enc:  fileEncoding{name: config.EncodingUTF8, bom: 3, invalid: 1},
+			want: "caf� au lait",
+			word: "lait",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, m := decodeText([]byte(tt.data), tt.enc)
+			if got != tt.want {
+				t.Fatalf("decodeText() = %q, want %q", got, tt.want)
+			}
+
+			// The word maps back to its encoded bytes
+			start := strings.Index(got, tt.word)
+			source := tt.data[m.start(start):m.end(start+len(tt.word))]
+			if decoded, _ := decodeText([]byte(source), fileEncoding{name: tt.enc.name}); decoded != tt.word {
+				t.Errorf("word maps to %q, which decodes to %q, want %q", source, decoded, tt.word)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package ingest
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Extractor turns the content of a file into plain text for chunking
+type Extractor interface {
+	// Extract returns the text of the file as one or more parts, each
+	// chunked on its own
+	Extract(data []byte) ([]ExtractedText, error)
+}
+
+// ExtractedText is plain text extracted from a file, or from one part of it,
+// with the metadata copied to each of its chunks
+type ExtractedText struct {
+	Text     string
+	Metadata map[string]string
+	Page     int // 1-based page the text is from, 0 for formats without pages
+
+	// Rows holds the spreadsheet row of each line of the text, for chunks to
+	// record the rows they span
+	Rows []int
+
+	// ID is the stable ID of the records of the text, such as a dataset
+	// row's, or "" for random ones
+	ID string
+
+	// Markdown marks text whose Markdown headings give chunks their section
+	Markdown bool
+
+	// Verbatim marks text that keeps the byte positions of the file, so
+	// chunk offsets locate it in the file rather than in the extracted text
+	Verbatim bool
+}
+
+// metadataFor returns the metadata of a chunk of the text from line first
+// to line last, with the spreadsheet rows they hold as "row"
+func (t ExtractedText) metadataFor(first, last int) map[string]string {
+	if len(t.Rows) == 0 || first < 1 {
+		return t.Metadata
+	}
+	from := t.Rows[min(first, len(t.Rows))-1]
+	to := t.Rows[min(max(last, first), len(t.Rows))-1]
+
+	metadata := maps.Clone(t.Metadata)
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadata["row"] = strconv.Itoa(from)
+	if to != from {
+		metadata["row"] += "-" + strconv.Itoa(to)
+	}
+	return metadata
+}
+
+// chunkID returns the ID of the nth chunk of the text, from 0: the ID of the
+// text for the first and the ID with "#2", "#3" and so on for the others
+func (t ExtractedText) chunkID(n int) string {
+	if t.ID == "" || n == 0 {
+		return t.ID
+	}
+	return t.ID + "#" + strconv.Itoa(n+1)
+}
+
+// Reasons a file is skipped for holding no text that can be extracted
+const (
+	SkipEncrypted = "encrypted"
+	SkipImageOnly = "image-only"
+	SkipMalformed = "malformed"
+)
+
+// skipError is returned by an extractor for a file it skips, with the reason
+type skipError struct {
+	reason string
+}
+
+// Error implements error
+func (e *skipError) Error() string {
+	return "skipped: " + e.reason
+}
+
+// fileFormat is a document format wafer ingests
+type fileFormat struct {
+	name       string
+	extensions []string
+	mimeTypes  []string  // Sniffed content types that identify files without a known extension
+	extractor  Extractor // Turns the file into plain text, or nil when it is chunked as it is
+	binary     bool      // The extractor reads the raw bytes of the file rather than decoded text
+}
+
+// extractorRegistry finds the format of a file by its extension or, failing
+// that, by sniffing its content type
+type extractorRegistry struct {
+	formats     []*fileFormat
+	byExtension map[string]*fileFormat
+	byMIMEType  map[string]*fileFormat
+}
+
+// newExtractorRegistry creates a registry of the built-in formats, with the
+// rows of datasets turned into text by rows. When include lists file
+// extensions, only the formats of those are active.
+func newExtractorRegistry(include []string, rows *rowOptions) (*extractorRegistry, error) {
+	if rows == nil {
+		rows = &rowOptions{}
+	}
+	r := &extractorRegistry{}
+	r.register(&fileFormat{name: "text", extensions: []string{".txt"}})
+	r.register(&fileFormat{name: "markdown", extensions: []string{".md", ".markdown"}, extractor: markdownExtractor{}})
+	r.register(&fileFormat{name: "html", extensions: []string{".html", ".htm", ".xhtml"}, mimeTypes: []string{"text/html"}, extractor: htmlExtractor{}})
+	r.register(&fileFormat{name: "rst", extensions: []string{".rst", ".rest"}, extractor: rstExtractor{}})
+	r.register(&fileFormat{name: "xml", extensions: []string{".xml"}, mimeTypes: []string{"text/xml", "application/xml"}, extractor: xmlExtractor{}})
+	r.register(&fileFormat{name: "pdf", extensions: []string{".pdf"}, mimeTypes: []string{"application/pdf"}, extractor: pdfExtractor{}, binary: true})
+	r.register(&fileFormat{name: "csv", extensions: []string{".csv"}, extractor: rowExtractor{comma: ',', options: rows}})
+	r.register(&fileFormat{name: "tsv", extensions: []string{".tsv"}, extractor: rowExtractor{comma: '\t', options: rows}})
+	r.register(&fileFormat{name: "jsonl", extensions: []string{".jsonl", ".ndjson"}, extractor: rowExtractor{options: rows}})
+	r.register(&fileFormat{name: "email", extensions: []string{".eml"}, extractor: emailExtractor{}, binary: true})
+	r.register(&fileFormat{name: "mbox", extensions: []string{".mbox", ".mbx"}, extractor: emailExtractor{mbox: true}, binary: true})
+	r.register(&fileFormat{name: "docx", extensions: []string{".docx"}, extractor: docxExtractor{}, binary: true})
+	r.register(&fileFormat{name: "odt", extensions: []string{".odt"}, extractor: odtExtractor{}, binary: true})
+	r.register(&fileFormat{name: "pptx", extensions: []string{".pptx"}, extractor: pptxExtractor{}, binary: true})
+	r.register(&fileFormat{name: "xlsx", extensions: []string{".xlsx"}, extractor: xlsxExtractor{}, binary: true})
+
+	if len(include) > 0 {
+		if err := r.include(include); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// register adds a format to the registry
+func (r *extractorRegistry) register(format *fileFormat) {
+	if r.byExtension == nil {
+		r.byExtension = map[string]*fileFormat{}
+		r.byMIMEType = map[string]*fileFormat{}
+	}
+	r.formats = append(r.formats, format)
+	for _, ext := range format.extensions {
+		r.byExtension[ext] = format
+	}
+	for _, mimeType := range format.mimeTypes {
+		r.byMIMEType[mimeType] = format
+	}
+}
+
+// include deactivates every extension not listed, along with the content
+// types of formats left without an active extension
+func (r *extractorRegistry) include(extensions []string) error {
+	byExtension := map[string]*fileFormat{}
+	for _, ext := range extensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		format, ok := r.byExtension[ext]
+		if !ok {
+			return fmt.Errorf("unsupported extension: %s", ext)
+		}
+		byExtension[ext] = format
+	}
+
+	byMIMEType := map[string]*fileFormat{}
+	for mimeType, format := range r.byMIMEType {
+		for _, active := range byExtension {
+			if active == format {
+				byMIMEType[mimeType] = format
+			}
+		}
+	}
+	r.byExtension, r.byMIMEType = byExtension, byMIMEType
+	return nil
+}
+
+// extensions returns the active file extensions
+func (r *extractorRegistry) extensions() []string {
+	if r == nil {
+		return nil
+	}
+	var extensions []string
+	for _, format := range r.formats {
+		for _, ext := range format.extensions {
+			if r.byExtension[ext] != nil {
+				extensions = append(extensions, ext)
+			}
+		}
+	}
+	return extensions
+}
+
+// forExtension returns the active format of a file's extension, or nil
+func (r *extractorRegistry) forExtension(filePath string) *fileFormat {
+	return r.byExtension[strings.ToLower(filepath.Ext(filePath))]
+}
+
+// sniff returns the active format of a file's content type, or nil. Only
+// the first 512 bytes are read, as http.DetectContentType considers no more.
+func (r *extractorRegistry) sniff(filePath string) (*fileFormat, error) {
+	if len(r.byMIMEType) == 0 {
+		return nil, nil
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	mimeType, _, _ := strings.Cut(http.DetectContentType(head[:n]), ";")
+	return r.byMIMEType[mimeType], nil
+}
+
+// cleanLines trims the trailing whitespace of every line of text, drops
+// leading and trailing blank lines and collapses runs of blank lines into one
+func cleanLines(text string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,117 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractorRegistry(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"page":      "<!DOCTYPE html><html><body><p>Hello</p></body></html>",
+		"feed":      "<?xml version=\"1.0\"?><feed><title>News</title></feed>",
+		"notes":     "Just some plain text without an extension.",
+		"image.png": "\x89PNG\r\n\x1a\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		file    string
+		want    string // Format name, or "" for none
+	}{
+		{"extension", nil, "guide.md", "markdown"},
+		{"uppercase extension", nil, "INDEX.HTM", "html"},
+		{"restructured text", nil, "api.rst", "rst"},
+		{"unknown extension", nil, "main.go", ""},
+		{"sniffed html", nil, "page", "html"},
+		{"sniffed xml", nil, "feed", "xml"},
+		{"plain text is not sniffed", nil, "notes", ""},
+		{"binary", nil, "image.png", ""},
+		{"included", []string{".html", "md"}, "guide.md", "markdown"},
+		{"excluded", []string{".html", "md"}, "notes.txt", ""},
+		{"excluded format is not sniffed", []string{".md"}, "page", ""},
+		{"included format is sniffed", []string{"htm"}, "page", "html"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newExtractorRegistry(tt.include, nil)
+			if err != nil {
+				t.Fatalf("newExtractorRegistry() error = %v", err)
+			}
+			format := r.forExtension(tt.file)
+			if _, ok := files[tt.file]; ok && format == nil {
+				if format, err = r.sniff(filepath.Join(dir, tt.file)); err != nil {
+					t.Fatalf("sniff() error = %v", err)
+				}
+			}
+			got := ""
+			if format != nil {
+				got = format.name
+			}
+			if got != tt.want {
+				t.Errorf("format = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := newExtractorRegistry([]string{".html", ".exe"}, nil); err == nil {
+		t.Error("newExtractorRegistry() with an unsupported extension should fail")
+	}
+	r, _ := newExtractorRegistry([]string{"MD", ".txt"}, nil)
+	if got, want := r.extensions(), []string{".txt", ".md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("extensions() = %v, want %v", got, want)
+	}
+}
+
+func TestMarkdownExtractor(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantTitle string
+	}{
+		{"front matter", "---\ntitle: \"Release Notes\"\ntags: [go]\n---\n# Version 2\n\nFaster ingestion.\n", "Release Notes"},
+		{"first heading", "Intro line.\n\n## Setup\n\n# Guide\n\nText.\n", "Guide"},
+		{"no title", "Just a paragraph.\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := markdownExtractor{}.Extract([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			part := parts[0]
+			if part.Metadata["title"] != tt.wantTitle {
+				t.Errorf("title = %q, want %q", part.Metadata["title"], tt.wantTitle)
+			}
+
+			// Front matter is blanked in place, so offsets stay those of the file
+			if len(part.Text) != len(tt.content) || strings.Count(part.Text, "\n") != strings.Count(tt.content, "\n") {
+				t.Errorf("text %q does not keep the layout of %q", part.Text, tt.content)
+			}
+			if strings.Contains(part.Text, "tags:") {
+				t.Errorf("front matter kept in %q", part.Text)
+			}
+			if !part.Markdown || !part.Verbatim {
+				t.Errorf("part = %+v, want Markdown and Verbatim", part)
+			}
+		})
+	}
+}
+
+func TestCleanLines(t *testing.T) {
+	got := cleanLines("\n\n  indented  \n\n\n\nnext\t\n\n")
+	if want := "  indented\n\nnext"; got != want {
+		t.Errorf("cleanLines() = %q, want %q", got, want)
+	}
+}
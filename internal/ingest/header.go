@@ -0,0 +1,70 @@
+package ingest
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// HeaderData is the data a header template is rendered with
+type HeaderData struct {
+	SourceFile   string // Path of the file relative to the input directory
+	Section      string // Heading path of the chunk
+	Symbol       string // Top-level declaration of a code chunk
+	Language     string // ISO 639-1 code of the natural language of a text chunk
+	CodeLanguage string // Programming language of a code chunk
+	Index        int    // Position of the chunk in its file
+	StartLine    int
+	EndLine      int
+	Text         string // The chunk text as it would be embedded without a header
+}
+
+// escapes are the backslash sequences expanded in a template, since a
+// newline is awkward to type in a command-line flag
+var escapes = strings.NewReplacer(`\n`, "\n", `\t`, "\t")
+
+// HeaderTemplate renders the contextual header embedded with each chunk,
+// such as "{{.SourceFile}} — {{.Section}}\n\n{{.Text}}"
+type HeaderTemplate struct {
+	tmpl *template.Template
+}
+
+// NewHeaderTemplate parses a header template written with text/template
+// syntax over HeaderData
+func NewHeaderTemplate(text string) (*HeaderTemplate, error) {
+	tmpl, err := template.New("header").Option("missingkey=error").Parse(escapes.Replace(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse header template: %w", err)
+	}
+
+	// Catch references to unknown fields before any chunk is embedded
+	h := &HeaderTemplate{tmpl: tmpl}
+	if _, err := h.render(HeaderData{}); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Render returns the text embedded for a chunk of sourceFile
+func (h *HeaderTemplate) Render(sourceFile string, chunk Chunk) (string, error) {
+	return h.render(HeaderData{
+		SourceFile:   sourceFile,
+		Section:      chunk.Section,
+		Symbol:       chunk.Symbol,
+		Language:     chunk.Language,
+		CodeLanguage: chunk.CodeLanguage,
+		Index:        chunk.Index,
+		StartLine:    chunk.StartLine,
+		EndLine:      chunk.EndLine,
+		Text:         chunk.embeddingInput(),
+	})
+}
+
+// render executes the template with data
+func (h *HeaderTemplate) render(data HeaderData) (string, error) {
+	var b strings.Builder
+	if err := h.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render header template: %w", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,80 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHeaderTemplate_Render(t *testing.T) {
+	chunk := Chunk{
+		Text:          "it increased\nby 20%",
+		EmbeddingText: "it increased by 20%",
+		Index:         3,
+		Section:       "Results > Revenue",
+		StartLine:     12,
+		EndLine:       13,
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "source and section",
+			template: "{{.SourceFile}} — {{.Section}}\n\n{{.Text}}",
+			want:     "reports/q3.md — Results > Revenue\n\nit increased by 20%",
+		},
+		{
+			name:     "escaped newlines",
+			template: `{{.SourceFile}}\n{{.Text}}`,
+			want:     "reports/q3.md\nit increased by 20%",
+		},
+		{
+			name:     "conditional section",
+			template: "{{.SourceFile}}{{if .Symbol}} ({{.Symbol}}){{end}}: {{.Text}}",
+			want:     "reports/q3.md: it increased by 20%",
+		},
+		{
+			name:     "position",
+			template: "chunk {{.Index}} lines {{.StartLine}}-{{.EndLine}}",
+			want:     "chunk 3 lines 12-13",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, err := NewHeaderTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("NewHeaderTemplate() error = %v", err)
+			}
+			got, err := header.Render("reports/q3.md", chunk)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewHeaderTemplate_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{"syntax error", "{{.SourceFile", "failed to parse header template"},
+		{"unknown field", "{{.Title}}: {{.Text}}", "failed to render header template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHeaderTemplate(tt.template)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewHeaderTemplate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package ingest
+
+import (
+	"fmt"
+	"os"
+
+	"wafer/internal/config"
+)
+
+// Hierarchy splits documents into large parent chunks and cuts each parent
+// into small child chunks, so retrieval can match a child and hand its
+// parent to the LLM as context
+type Hierarchy struct {
+	parents  *Chunker
+	children *Chunker
+}
+
+// NewHierarchy creates a hierarchy whose parents are cfg.ParentSize long and
+// whose children are cut from them by the given chunker
+func NewHierarchy(cfg *config.Config, children *Chunker) *Hierarchy {
+	parentCfg := *cfg
+	parentCfg.ChunkSize = cfg.ParentSize
+	parentCfg.ChunkOverlap = 0
+
+	return &Hierarchy{
+		parents:  NewChunker(&parentCfg),
+		children: children,
+	}
+}
+
+// SetTokenizer sets the tokenizer used by both levels
+func (h *Hierarchy) SetTokenizer(tokenizer Tokenizer) {
+	h.parents.SetTokenizer(tokenizer)
+	h.children.SetTokenizer(tokenizer)
+}
+
+// SetEmbedder sets the embedder used by semantic chunking at both levels
+func (h *Hierarchy) SetEmbedder(embedder TextEmbedder) {
+	h.parents.SetEmbedder(embedder)
+	h.children.SetEmbedder(embedder)
+}
+
+// ChunkFile streams a file and calls fn with each parent chunk and the child
+// chunks cut from it, as soon as the parent's boundaries are settled. Parents
+// and children are indexed separately, each sequentially across the file.
+func (h *Hierarchy) ChunkFile(filePath string, fn func(parent Chunk, children []Chunk) error) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	childStrategy := h.children.strategyFor(filePath)
+	index := 0
+	return h.parents.chunkPassages(h.parents.strategyFor(filePath), file, func(parent Chunk, passage string) error {
+		return h.cutChildren(childStrategy, parent, passage, &index, fn)
+	})
+}
+
+// chunkContent splits the content of a file into parents and children
+func (h *Hierarchy) chunkContent(filePath, content string, fn func(parent Chunk, children []Chunk) error) error {
+	return h.chunkWith(h.parents.strategyFor(filePath), h.children.strategyFor(filePath), content, fn)
+}
+
+// chunkExtracted splits text extracted from a document into parents and children
+func (h *Hierarchy) chunkExtracted(part ExtractedText, fn func(parent Chunk, children []Chunk) error) error {
+	return h.chunkWith(h.parents.textStrategy(part.Markdown), h.children.textStrategy(part.Markdown), part.Text, fn)
+}
+
+// chunkWith splits content into parents and children with the given strategies
+func (h *Hierarchy) chunkWith(parentStrategy, childStrategy Strategy, content string, fn func(parent Chunk, children []Chunk) error) error {
+	index := 0
+	for _, parent := range h.parents.chunkText(parentStrategy, content) {
+		if err := h.cutChildren(childStrategy, parent, content[parent.StartByte:parent.EndByte], &index, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cutChildren cuts the children of a parent from passage, the original bytes
+// of the parent, so their offsets translate back to the document, and calls
+// fn with both. Index is the index of the next child in the document.
+func (h *Hierarchy) cutChildren(childStrategy Strategy, parent Chunk, passage string, index *int, fn func(parent Chunk, children []Chunk) error) error {
+	parent.Level = ChunkParent
+	children := h.children.chunkText(childStrategy, passage)
+	for i := range children {
+		child := &children[i]
+		child.Index = *index
+		*index++
+		child.Level = ChunkChild
+		child.StartByte += parent.StartByte
+		child.EndByte += parent.StartByte
+		child.StartLine += parent.StartLine - 1
+		child.EndLine += parent.StartLine - 1
+
+		// A parent never spans sections or declarations, so children
+		// share its place in the document structure
+		if parent.Section != "" {
+			child.Section = parent.Section
+		}
+		if parent.Symbol != "" {
+			child.Symbol = parent.Symbol
+			child.CodeLanguage = parent.CodeLanguage
+		}
+	}
+	return fn(parent, children)
+}
@@ -0,0 +1,101 @@
+package ingest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wafer/internal/config"
+)
+
+func TestHierarchy_ChunkFile(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 12; i++ {
+		b.WriteString("# Part\r\n\r\nEach part of the manual has a few words of its own text.\r\n\r\n")
+	}
+	content := b.String()
+
+	path := filepath.Join(t.TempDir(), "manual.md")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	cfg := &config.Config{ChunkSize: 5, ParentSize: 40}
+	hierarchy := NewHierarchy(cfg, NewChunker(cfg))
+
+	var parents []Chunk
+	var children []Chunk
+	err := hierarchy.ChunkFile(path, func(parent Chunk, family []Chunk) error {
+		parents = append(parents, parent)
+		children = append(children, family...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChunkFile() error = %v", err)
+	}
+
+	if len(parents) == 0 || len(children) <= len(parents) {
+		t.Fatalf("got %d parents and %d children", len(parents), len(children))
+	}
+	for i, child := range children {
+		if child.Index != i || child.Level != ChunkChild {
+			t.Errorf("child %d has index %d and level %q", i, child.Index, child.Level)
+		}
+		if child.Section != "Part" {
+			t.Errorf("child %d lost its section: %q", i, child.Section)
+		}
+
+		// Offsets point into the original document despite its "\r\n" endings
+		original := content[child.StartByte:child.EndByte]
+		if strings.ReplaceAll(original, "\r\n", "\n") != child.Text {
+			t.Errorf("child %d offsets %d-%d locate %q, want %q", i, child.StartByte, child.EndByte, original, child.Text)
+		}
+		if want := 1 + strings.Count(content[:child.StartByte], "\n"); child.StartLine != want {
+			t.Errorf("child %d starts on line %d, want %d", i, child.StartLine, want)
+		}
+	}
+}
+
+func TestHierarchy_ChunkFileStreams(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&b, "Sentence %d of the log has a handful of words.\r\n", i)
+	}
+	content := b.String()
+
+	path := filepath.Join(t.TempDir(), "log.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	cfg := &config.Config{ChunkSize: 8, ParentSize: 30}
+	collect := func(chunk func(fn func(parent Chunk, children []Chunk) error) error) []Chunk {
+		var chunks []Chunk
+		err := chunk(func(parent Chunk, children []Chunk) error {
+			chunks = append(append(chunks, parent), children...)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("chunking error = %v", err)
+		}
+		return chunks
+	}
+	whole := NewHierarchy(cfg, NewChunker(cfg))
+	want := collect(func(fn func(parent Chunk, children []Chunk) error) error {
+		return whole.chunkContent(path, content, fn)
+	})
+
+	// Parents are read a window at a time yet match those of the whole file
+	for _, window := range []int{64, 200, 1024} {
+		streamed := NewHierarchy(cfg, NewChunker(cfg))
+		streamed.parents.window = window
+		got := collect(func(fn func(parent Chunk, children []Chunk) error) error {
+			return streamed.ChunkFile(path, fn)
+		})
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("window %d: ChunkFile() = %v, want %v", window, got, want)
+		}
+	}
+}
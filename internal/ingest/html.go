@@ -0,0 +1,305 @@
+package ingest
+
+import (
+	"html"
+	"strings"
+)
+
+// htmlSkipped are elements whose content is not part of the document text:
+// scripts, styles, navigation and embedded objects
+var htmlSkipped = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"nav":      true,
+	"noscript": true,
+	"template": true,
+	"svg":      true,
+	"iframe":   true,
+	"object":   true,
+}
+
+// htmlRawText are elements whose content is never parsed as markup
+var htmlRawText = map[string]bool{
+	"script": true,
+	"style":  true,
+}
+
+// htmlBlocks are elements that start a new paragraph
+var htmlBlocks = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"body": true, "caption": true, "details": true, "div": true, "dl": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true,
+	"form": true, "header": true, "hr": true, "main": true, "ol": true,
+	"p": true, "section": true, "summary": true, "table": true, "ul": true,
+}
+
+// htmlLines are elements that start a new line
+var htmlLines = map[string]bool{
+	"br": true, "dd": true, "dt": true, "li": true, "tr": true,
+}
+
+// htmlExtractor extracts the text of an HTML document as Markdown: headings
+// become Markdown headings, so chunks get their section, list items become
+// bullets and preformatted text becomes fenced code. The <title> is kept as
+// metadata.
+type htmlExtractor struct{}
+
+// Extract implements Extractor
+func (htmlExtractor) Extract(data []byte) ([]ExtractedText, error) {
+	w := &htmlWriter{}
+	src := string(data)
+	skipping, depth := "", 0
+
+	for i := 0; i < len(src); {
+		lt := strings.IndexByte(src[i:], '<')
+		if lt < 0 {
+			if skipping == "" {
+				w.text(html.UnescapeString(src[i:]))
+			}
+			break
+		}
+		if lt > 0 && skipping == "" {
+			w.text(html.UnescapeString(src[i : i+lt]))
+		}
+		i += lt
+
+		// Comments, doctypes and processing instructions
+		if strings.HasPrefix(src[i:], "<!--") {
+			i = skipPast(src, i+4, "-->")
+			continue
+		}
+		if strings.HasPrefix(src[i:], "<!") || strings.HasPrefix(src[i:], "<?") {
+			i = skipPast(src, i+2, ">")
+			continue
+		}
+
+		name, end, closing, ok := parseHTMLTag(src, i)
+		if !ok {
+			// A "<" that opens no tag is text
+			if skipping == "" {
+				w.text("<")
+			}
+			i++
+			continue
+		}
+		i = end
+
+		if skipping != "" {
+			if name == skipping {
+				if closing {
+					depth--
+				} else {
+					depth++
+				}
+				if depth == 0 {
+					skipping = ""
+				}
+			}
+			continue
+		}
+		if closing {
+			w.end(name)
+			continue
+		}
+		if htmlRawText[name] {
+			i = skipPast(src, i, "</"+name)
+			i = skipPast(src, i, ">")
+			continue
+		}
+		if htmlSkipped[name] && !strings.HasSuffix(src[:end], "/>") {
+			skipping, depth = name, 1
+			continue
+		}
+		w.start(name)
+	}
+
+	part := ExtractedText{Text: w.String(), Markdown: true}
+	if title := strings.Join(strings.Fields(w.title.String()), " "); title != "" {
+		part.Metadata = map[string]string{"title": title}
+	}
+	return []ExtractedText{part}, nil
+}
+
+// parseHTMLTag parses the tag starting at src[i], returning its lowercased
+// name, the offset just past it and whether it is an end tag
+func parseHTMLTag(src string, i int) (string, int, bool, bool) {
+	j := i + 1
+	closing := j < len(src) && src[j] == '/'
+	if closing {
+		j++
+	}
+	start := j
+	for j < len(src) && (isASCIILetter(src[j]) || j > start && src[j] >= '0' && src[j] <= '9') {
+		j++
+	}
+	if j == start {
+		return "", 0, false, false
+	}
+	name := strings.ToLower(src[start:j])
+
+	// Find the closing bracket, skipping quoted attribute values
+	var quote byte
+	for ; j < len(src); j++ {
+		switch c := src[j]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return name, j + 1, closing, true
+		}
+	}
+	return name, len(src), closing, true
+}
+
+// isASCIILetter reports whether c is an ASCII letter
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// skipPast returns the offset just past the first case-insensitive match of
+// marker in src at or after i, or the end of src
+func skipPast(src string, i int, marker string) int {
+	k := strings.Index(strings.ToLower(src[i:]), strings.ToLower(marker))
+	if k < 0 {
+		return len(src)
+	}
+	return i + k + len(marker)
+}
+
+// htmlWriter renders HTML elements and text as Markdown
+type htmlWriter struct {
+	b        strings.Builder
+	title    strings.Builder
+	inTitle  bool
+	pre      int  // Depth of open <pre> elements
+	preStart bool // Nothing has been written since the outer <pre> opened
+	space    bool // Whitespace is pending before the next word
+	marker   bool // The current line holds only a bullet or heading marker
+	newlines int  // Newlines ending the output
+}
+
+// start handles a start tag
+func (w *htmlWriter) start(name string) {
+	switch {
+	case name == "title":
+		w.inTitle = true
+	case name == "pre":
+		w.breakLines(2)
+		if w.pre == 0 {
+			w.write("```\n")
+			w.preStart = true
+		}
+		w.pre++
+	case isHTMLHeading(name):
+		w.breakLines(2)
+		w.write(strings.Repeat("#", int(name[1]-'0')) + " ")
+		w.marker = true
+	case name == "li":
+		if !w.marker {
+			w.breakLines(1)
+			w.write("- ")
+			w.marker = true
+		}
+	case name == "td" || name == "th":
+		w.space = true
+	case htmlBlocks[name]:
+		w.breakLines(2)
+	case htmlLines[name]:
+		w.breakLines(1)
+	}
+}
+
+// end handles an end tag
+func (w *htmlWriter) end(name string) {
+	switch {
+	case name == "title":
+		w.inTitle = false
+	case name == "pre":
+		if w.pre > 0 {
+			w.pre--
+			if w.pre == 0 {
+				w.breakLines(1)
+				w.write("```")
+			}
+		}
+		w.breakLines(2)
+	case isHTMLHeading(name) || htmlBlocks[name]:
+		w.breakLines(2)
+	case htmlLines[name]:
+		w.breakLines(1)
+	}
+}
+
+// text writes text content, collapsing its whitespace outside <pre>
+func (w *htmlWriter) text(s string) {
+	if w.inTitle {
+		w.title.WriteString(s)
+		return
+	}
+	if w.pre > 0 {
+		// A newline right after <pre> is not part of its content
+		if w.preStart {
+			s = strings.TrimPrefix(strings.TrimPrefix(s, "\r"), "\n")
+			w.preStart = false
+		}
+		w.write(s)
+		return
+	}
+	if s != "" && isSpace(s[0]) {
+		w.space = true
+	}
+	for i, word := range strings.Fields(s) {
+		if (i > 0 || w.space) && w.b.Len() > 0 && w.newlines == 0 && !w.marker {
+			w.write(" ")
+		}
+		w.write(word)
+		w.space, w.marker = false, false
+	}
+	if s != "" && isSpace(s[len(s)-1]) {
+		w.space = true
+	}
+}
+
+// write appends s to the output, tracking the newlines that end it
+func (w *htmlWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	w.b.WriteString(s)
+	trailing := len(s) - len(strings.TrimRight(s, "\n"))
+	if trailing == len(s) {
+		w.newlines += trailing
+	} else {
+		w.newlines = trailing
+	}
+}
+
+// breakLines ends the current line and, for n of 2, leaves a blank line.
+// Nothing breaks the line of a marker still waiting for its text.
+func (w *htmlWriter) breakLines(n int) {
+	w.space = false
+	if w.b.Len() == 0 || w.marker {
+		return
+	}
+	for w.newlines < n {
+		w.write("\n")
+	}
+}
+
+// String returns the Markdown written
+func (w *htmlWriter) String() string {
+	return cleanLines(w.b.String())
+}
+
+// isHTMLHeading reports whether name is one of h1 to h6
+func isHTMLHeading(name string) bool {
+	return len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6'
+}
+
+// isSpace reports whether c is ASCII whitespace
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
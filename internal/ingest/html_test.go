@@ -0,0 +1,59 @@
+package ingest
+
+import "testing"
+
+func TestHTMLExtractor(t *testing.T) {
+	tests := []struct {
+		name      string
+		html      string
+		want      string
+		wantTitle string
+	}{
+		{
+			name:      "title and paragraphs",
+			html:      "<html><head><title> Release\n Notes </title></head><body><p>First <b>bold</b> line.</p><p>Second&nbsp;line &amp; more.</p></body></html>",
+			want:      "First bold line.\n\nSecond line & more.",
+			wantTitle: "Release Notes",
+		},
+		{
+			name: "scripts, styles and navigation",
+			html: "<nav><ul><li>Home</li></ul></nav><script>if (a < b) { x = '</p>'; }</script><style>p{}</style><p>Kept</p><noscript>Enable JS</noscript>",
+			want: "Kept",
+		},
+		{
+			name: "headings and lists",
+			html: "<h1>Guide</h1><p>Intro</p><h3>Steps</h3><ol><li>One</li><li><p>Two</p></li></ol>",
+			want: "# Guide\n\nIntro\n\n### Steps\n\n- One\n- Two",
+		},
+		{
+			name: "preformatted text",
+			html: "<p>Run:</p><pre>\n# install\n  make build\n</pre>",
+			want: "Run:\n\n```\n# install\n  make build\n```",
+		},
+		{
+			name: "comments, tables and line breaks",
+			html: "<!-- hidden --><table><tr><th>Flag</th><th>Default</th></tr><tr><td>--model</td><td>none</td></tr></table>a<br>b",
+			want: "Flag Default\n--model none\n\na\nb",
+		},
+		{
+			name: "stray angle bracket",
+			html: "<p>1 < 2 and 3 > 2</p>",
+			want: "1 < 2 and 3 > 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := htmlExtractor{}.Extract([]byte(tt.html))
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if got := parts[0].Text; got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+			if got := parts[0].Metadata["title"]; got != tt.wantTitle {
+				t.Errorf("title = %q, want %q", got, tt.wantTitle)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package ingest
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	// languageMaxGram is the longest character n-gram in a language profile
+	languageMaxGram = 3
+
+	// languageMinLetters is the fewest letters a text needs to be identified
+	languageMinLetters = 3
+
+	// languageMinConfidence is the lowest confidence a chunk's language is
+	// recorded and filtered on; below it the language is undetermined, as
+	// short chunks, tables and loanwords are often mistaken for another
+	// language
+	languageMinConfidence = 0.8
+
+	// languageEvidence caps the n-grams whose likelihoods are weighed, so the
+	// confidence of a long chunk reflects how distinct its language is rather
+	// than saturating with its length
+	languageEvidence = 20
+)
+
+// languageScripts are the writing systems a text is classified by first.
+// Scripts written in a single language identify it outright; languages that
+// share a script are told apart by their n-gram profiles.
+var languageScripts = []struct {
+	tables   []*unicode.RangeTable
+	language string // Language of the script, or "" when several share it
+}{
+	{[]*unicode.RangeTable{unicode.Latin}, ""},
+	{[]*unicode.RangeTable{unicode.Cyrillic}, ""},
+	{[]*unicode.RangeTable{unicode.Greek}, "el"},
+	{[]*unicode.RangeTable{unicode.Arabic}, "ar"},
+	{[]*unicode.RangeTable{unicode.Hebrew}, "he"},
+	{[]*unicode.RangeTable{unicode.Devanagari}, "hi"},
+	{[]*unicode.RangeTable{unicode.Thai}, "th"},
+	{[]*unicode.RangeTable{unicode.Hangul}, "ko"},
+	{[]*unicode.RangeTable{unicode.Hiragana, unicode.Katakana}, "ja"},
+	{[]*unicode.RangeTable{unicode.Han}, "zh"},
+}
+
+// Indexes of languageScripts that need special handling
+const (
+	scriptKana = 8
+	scriptHan  = 9
+)
+
+// languageProfile holds the n-gram log probabilities of a language
+type languageProfile struct {
+	language string
+	script   int
+	logProb  map[string]float64
+	unseen   float64 // Log probability of an n-gram missing from the sample
+}
+
+var (
+	profilesOnce     sync.Once
+	languageProfiles []languageProfile
+)
+
+// loadLanguageProfiles builds the n-gram profiles of languageSamples once
+func loadLanguageProfiles() []languageProfile {
+	profilesOnce.Do(func() {
+		vocabulary := map[string]bool{}
+		counts := map[string]map[string]int{}
+		for language, sample := range languageSamples {
+			counts[language] = map[string]int{}
+			languageGrams(sample, func(gram string) {
+				counts[language][gram]++
+				vocabulary[gram] = true
+			})
+		}
+
+		// Add-one smoothing over the n-grams of every sample
+		for language, grams := range counts {
+			total := 0
+			for _, count := range grams {
+				total += count
+			}
+			denominator := float64(total + len(vocabulary))
+			scripts, _ := scriptCounts(languageSamples[language])
+			profile := languageProfile{
+				language: language,
+				script:   dominantScript(scripts),
+				logProb:  make(map[string]float64, len(grams)),
+				unseen:   math.Log(1 / denominator),
+			}
+			for gram, count := range grams {
+				profile.logProb[gram] = math.Log(float64(count+1) / denominator)
+			}
+			languageProfiles = append(languageProfiles, profile)
+		}
+		sort.Slice(languageProfiles, func(i, j int) bool {
+			return languageProfiles[i].language < languageProfiles[j].language
+		})
+	})
+	return languageProfiles
+}
+
+// identifyLanguage returns the language detected in text with its
+// confidence, or "" when the detection is not confident enough to act on
+func identifyLanguage(text string) (string, float64) {
+	language, confidence := detectLanguage(text)
+	if confidence < languageMinConfidence {
+		return "", 0
+	}
+	return language, confidence
+}
+
+// detectLanguage returns the ISO 639-1 code of the language text is written
+// in and a confidence from 0 to 1, or "" when text has too few letters
+func detectLanguage(text string) (string, float64) {
+	counts, letters := scriptCounts(text)
+	if letters < languageMinLetters {
+		return "", 0
+	}
+
+	// Japanese mixes kanji with kana
+	if counts[scriptKana] > 0 {
+		counts[scriptKana] += counts[scriptHan]
+		counts[scriptHan] = 0
+	}
+	script := dominantScript(counts)
+	share := float64(counts[script]) / float64(letters)
+	if language := languageScripts[script].language; language != "" {
+		return language, roundConfidence(share)
+	}
+
+	// Weigh the likelihood of the text's n-grams under each profile
+	var candidates []languageProfile
+	for _, profile := range loadLanguageProfiles() {
+		if profile.script == script {
+			candidates = append(candidates, profile)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", 0
+	}
+	scores := make([]float64, len(candidates))
+	n := 0
+	languageGrams(text, func(gram string) {
+		n++
+		for i, profile := range candidates {
+			if logProb, ok := profile.logProb[gram]; ok {
+				scores[i] += logProb
+			} else {
+				scores[i] += profile.unseen
+			}
+		}
+	})
+
+	// Posterior probability of the most likely language
+	scale := float64(min(n, languageEvidence)) / float64(n)
+	best := 0
+	for i := range scores {
+		scores[i] *= scale
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp(score - scores[best])
+	}
+	return candidates[best].language, roundConfidence(share / sum)
+}
+
+// scriptCounts counts the letters of text in each of languageScripts
+func scriptCounts(text string) ([]int, int) {
+	counts := make([]int, len(languageScripts))
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for i, script := range languageScripts {
+			if unicode.In(r, script.tables...) {
+				counts[i]++
+				break
+			}
+		}
+	}
+	return counts, letters
+}
+
+// dominantScript returns the index of the script with the most letters
+func dominantScript(counts []int) int {
+	script := 0
+	for i, count := range counts {
+		if count > counts[script] {
+			script = i
+		}
+	}
+	return script
+}
+
+// languageGrams calls fn with every character n-gram, up to languageMaxGram
+// long, of the lowercased words of text padded with a space on each side
+func languageGrams(text string, fn func(gram string)) {
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.Is(unicode.Mn, r)
+	}) {
+		runes := []rune(" " + word + " ")
+		for n := 1; n <= languageMaxGram; n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				if gram := string(runes[i : i+n]); gram != " " {
+					fn(gram)
+				}
+			}
+		}
+	}
+}
+
+// roundConfidence rounds a confidence to three decimals
+func roundConfidence(confidence float64) float64 {
+	return math.Round(confidence*1000) / 1000
+}
@@ -0,0 +1,262 @@
+package ingest
+
+// languageSamples holds passages of everyday prose and of documentation for
+// each language told apart by character n-grams. The n-gram profiles are
+// built from them, so they favor common function words and spelling over any
+// particular topic.
+var languageSamples = map[string]string{
+	"en": `The weather was cold this morning, so we stayed at home and read the
+newspaper. My brother works in a large company in the city, where he writes
+software for the people who manage the accounts of the bank. He says that the
+work is interesting, but that there is never enough time to finish everything
+before the end of the week. In the evening we often walk through the park
+with the children and talk about what happened during the day. Although the
+government has promised to build new schools and to improve the roads, most
+people think that nothing will change until after the next election. What
+would you do if you could travel anywhere in the world? I would like to see
+the mountains of the north, which are beautiful in the summer when the snow
+has melted and the rivers are full of water. These are the things that they
+have always wanted to know, and they should have asked us much earlier.
+
+This document describes how to install and configure the application. First
+download the latest release, then run the installer and follow the steps on
+the screen. The settings are stored in a file in your home directory, which
+you can edit with any text editor. If an error occurs, check the log file and
+make sure that all of the required services are running. You will find more
+examples of how to use these features in the next section of the manual.`,
+
+	"de": `Das Wetter war heute Morgen kalt, deshalb sind wir zu Hause geblieben
+und haben die Zeitung gelesen. Mein Bruder arbeitet in einer großen Firma in
+der Stadt, wo er Software für die Leute schreibt, die die Konten der Bank
+verwalten. Er sagt, dass die Arbeit interessant ist, aber dass es nie genug
+Zeit gibt, um alles vor dem Ende der Woche fertig zu machen. Am Abend gehen
+wir oft mit den Kindern durch den Park spazieren und sprechen darüber, was
+während des Tages passiert ist. Obwohl die Regierung versprochen hat, neue
+Schulen zu bauen und die Straßen zu verbessern, glauben die meisten Menschen,
+dass sich nichts ändern wird, bis nach der nächsten Wahl. Was würdest du tun,
+wenn du überall auf der Welt hinreisen könntest? Ich möchte die Berge im
+Norden sehen, die im Sommer schön sind, wenn der Schnee geschmolzen ist und
+die Flüsse voller Wasser sind. Das sind die Dinge, die sie immer wissen
+wollten, und sie hätten uns viel früher fragen sollen.
+
+Dieses Dokument beschreibt, wie die Anwendung installiert und konfiguriert
+wird. Laden Sie zuerst die neueste Version herunter, starten Sie dann das
+Installationsprogramm und folgen Sie den Schritten auf dem Bildschirm. Die
+Einstellungen werden in einer Datei in Ihrem Benutzerverzeichnis gespeichert,
+die Sie mit jedem Texteditor bearbeiten können. Wenn ein Fehler auftritt,
+prüfen Sie die Protokolldatei und stellen Sie sicher, dass alle benötigten
+Dienste laufen. Weitere Beispiele für diese Funktionen finden Sie im nächsten
+Abschnitt des Handbuchs.`,
+
+	"fr": `Il faisait froid ce matin, alors nous sommes restés à la maison et
+nous avons lu le journal. Mon frère travaille dans une grande entreprise de
+la ville, où il écrit des logiciels pour les personnes qui gèrent les comptes
+de la banque. Il dit que le travail est intéressant, mais qu'il n'y a jamais
+assez de temps pour tout terminer avant la fin de la semaine. Le soir, nous
+nous promenons souvent dans le parc avec les enfants et nous parlons de ce
+qui s'est passé pendant la journée. Bien que le gouvernement ait promis de
+construire de nouvelles écoles et d'améliorer les routes, la plupart des gens
+pensent que rien ne changera avant les prochaines élections. Que feriez-vous
+si vous pouviez voyager partout dans le monde? J'aimerais voir les montagnes
+du nord, qui sont très belles en été quand la neige a fondu et que les
+rivières sont pleines d'eau. Ce sont les choses qu'ils ont toujours voulu
+savoir, et ils auraient dû nous demander beaucoup plus tôt.
+
+Ce document décrit comment installer et configurer l'application. Téléchargez
+d'abord la dernière version, puis lancez le programme d'installation et
+suivez les étapes affichées à l'écran. Les paramètres sont enregistrés dans
+un fichier de votre répertoire personnel, que vous pouvez modifier avec
+n'importe quel éditeur de texte. Si une erreur se produit, consultez le
+fichier journal et vérifiez que tous les services nécessaires sont en cours
+d'exécution. Vous trouverez d'autres exemples d'utilisation de ces fonctions
+dans la section suivante du manuel.`,
+
+	"es": `Hacía frío esta mañana, así que nos quedamos en casa y leímos el
+periódico. Mi hermano trabaja en una empresa grande de la ciudad, donde
+escribe programas para las personas que administran las cuentas del banco.
+Dice que el trabajo es interesante, pero que nunca hay tiempo suficiente para
+terminarlo todo antes del fin de la semana. Por la tarde solemos pasear por
+el parque con los niños y hablamos de lo que ha pasado durante el día. Aunque
+el gobierno ha prometido construir nuevas escuelas y mejorar las carreteras,
+la mayoría de la gente cree que nada cambiará hasta después de las próximas
+elecciones. ¿Qué harías si pudieras viajar a cualquier lugar del mundo? Me
+gustaría ver las montañas del norte, que son muy bonitas en verano cuando la
+nieve se ha derretido y los ríos están llenos de agua. Estas son las cosas
+que siempre han querido saber, y deberían habernos preguntado mucho antes.
+
+Este documento describe cómo instalar y configurar la aplicación. Primero
+descargue la última versión, luego ejecute el instalador y siga los pasos que
+aparecen en la pantalla. La configuración se guarda en un archivo de su
+directorio personal, que puede editar con cualquier editor de texto. Si se
+produce un error, revise el archivo de registro y asegúrese de que todos los
+servicios necesarios estén en funcionamiento. Encontrará más ejemplos de cómo
+usar estas funciones en la siguiente sección del manual.`,
+
+	"it": `Stamattina faceva freddo, quindi siamo rimasti a casa e abbiamo letto
+il giornale. Mio fratello lavora in una grande azienda della città, dove
+scrive programmi per le persone che gestiscono i conti della banca. Dice che
+il lavoro è interessante, ma che non c'è mai abbastanza tempo per finire
+tutto prima della fine della settimana. La sera passeggiamo spesso nel parco
+con i bambini e parliamo di quello che è successo durante la giornata.
+Sebbene il governo abbia promesso di costruire nuove scuole e di migliorare
+le strade, la maggior parte della gente pensa che niente cambierà fino alle
+prossime elezioni. Che cosa faresti se potessi viaggiare in qualsiasi parte
+del mondo? Mi piacerebbe vedere le montagne del nord, che sono molto belle
+d'estate quando la neve si è sciolta e i fiumi sono pieni d'acqua. Queste
+sono le cose che hanno sempre voluto sapere, e avrebbero dovuto chiedercelo
+molto prima.
+
+Questo documento descrive come installare e configurare l'applicazione. Per
+prima cosa scaricate l'ultima versione, poi avviate il programma di
+installazione e seguite i passaggi sullo schermo. Le impostazioni vengono
+salvate in un file nella vostra cartella personale, che potete modificare con
+qualsiasi editor di testo. Se si verifica un errore, controllate il file di
+registro e assicuratevi che tutti i servizi necessari siano in esecuzione.
+Troverete altri esempi su come usare queste funzioni nella prossima sezione
+del manuale.`,
+
+	"pt": `Estava frio hoje de manhã, então ficamos em casa e lemos o jornal. O
+meu irmão trabalha numa empresa grande da cidade, onde escreve programas para
+as pessoas que administram as contas do banco. Ele diz que o trabalho é
+interessante, mas que nunca há tempo suficiente para terminar tudo antes do
+fim da semana. À noite costumamos passear no parque com as crianças e
+conversamos sobre o que aconteceu durante o dia. Embora o governo tenha
+prometido construir novas escolas e melhorar as estradas, a maioria das
+pessoas acha que nada vai mudar até depois das próximas eleições. O que você
+faria se pudesse viajar para qualquer lugar do mundo? Eu gostaria de ver as
+montanhas do norte, que são muito bonitas no verão, quando a neve já derreteu
+e os rios estão cheios de água. São estas as coisas que eles sempre quiseram
+saber, e deveriam ter nos perguntado muito mais cedo. Não há nenhuma razão
+para esperar, porque a situação não vai melhorar sozinha.
+
+Este documento descreve como instalar e configurar a aplicação. Primeiro
+transfira a versão mais recente, depois execute o instalador e siga os passos
+apresentados no ecrã. As definições são guardadas num ficheiro da sua pasta
+pessoal, que pode editar com qualquer editor de texto. Se ocorrer um erro,
+verifique o ficheiro de registo e confirme que todos os serviços necessários
+estão em execução. Encontrará mais exemplos de como utilizar estas funções na
+próxima secção do manual.`,
+
+	"nl": `Het was koud vanochtend, dus we zijn thuisgebleven en hebben de krant
+gelezen. Mijn broer werkt bij een groot bedrijf in de stad, waar hij software
+schrijft voor de mensen die de rekeningen van de bank beheren. Hij zegt dat
+het werk interessant is, maar dat er nooit genoeg tijd is om alles voor het
+einde van de week af te maken. 's Avonds wandelen we vaak met de kinderen door
+het park en praten we over wat er die dag is gebeurd. Hoewel de regering heeft
+beloofd nieuwe scholen te bouwen en de wegen te verbeteren, denken de meeste
+mensen dat er niets zal veranderen tot na de volgende verkiezingen. Wat zou
+jij doen als je overal ter wereld naartoe kon reizen? Ik zou graag de bergen
+in het noorden zien, die in de zomer heel mooi zijn als de sneeuw gesmolten is
+en de rivieren vol water staan. Dat zijn de dingen die ze altijd al wilden
+weten, en ze hadden het ons veel eerder moeten vragen.
+
+Dit document beschrijft hoe je de toepassing installeert en configureert.
+Download eerst de nieuwste versie, start daarna het installatieprogramma en
+volg de stappen op het scherm. De instellingen worden opgeslagen in een
+bestand in je persoonlijke map, dat je met elke teksteditor kunt bewerken.
+Als er een fout optreedt, controleer dan het logbestand en zorg ervoor dat
+alle benodigde diensten actief zijn. Meer voorbeelden van het gebruik van
+deze functies vind je in het volgende hoofdstuk van de handleiding.`,
+
+	"sv": `Det var kallt i morse, så vi stannade hemma och läste tidningen. Min
+bror arbetar på ett stort företag i staden, där han skriver program för de
+människor som sköter bankens konton. Han säger att arbetet är intressant, men
+att det aldrig finns tillräckligt med tid för att bli klar med allt före
+veckans slut. På kvällen promenerar vi ofta i parken med barnen och pratar om
+vad som har hänt under dagen. Även om regeringen har lovat att bygga nya
+skolor och förbättra vägarna, tror de flesta människor att ingenting kommer
+att förändras förrän efter nästa val. Vad skulle du göra om du kunde resa
+vart som helst i världen? Jag skulle vilja se bergen i norr, som är mycket
+vackra på sommaren när snön har smält och älvarna är fulla av vatten. Det är
+sådana saker som de alltid har velat veta, och de borde ha frågat oss mycket
+tidigare.
+
+Det här dokumentet beskriver hur du installerar och konfigurerar programmet.
+Ladda först ner den senaste versionen, kör sedan installationsprogrammet och
+följ stegen på skärmen. Inställningarna sparas i en fil i din hemkatalog, som
+du kan redigera med valfri textredigerare. Om ett fel uppstår, kontrollera
+loggfilen och se till att alla nödvändiga tjänster körs. Du hittar fler
+exempel på hur du använder de här funktionerna i nästa avsnitt av handboken.`,
+
+	"pl": `Dziś rano było zimno, więc zostaliśmy w domu i czytaliśmy gazetę. Mój
+brat pracuje w dużej firmie w mieście, gdzie pisze programy dla ludzi, którzy
+zarządzają kontami banku. Mówi, że praca jest ciekawa, ale że nigdy nie ma
+wystarczająco dużo czasu, żeby wszystko skończyć przed końcem tygodnia.
+Wieczorem często spacerujemy z dziećmi po parku i rozmawiamy o tym, co się
+wydarzyło w ciągu dnia. Chociaż rząd obiecał budować nowe szkoły i poprawić
+drogi, większość ludzi uważa, że nic się nie zmieni aż do następnych wyborów.
+Co byś zrobił, gdybyś mógł pojechać w dowolne miejsce na świecie? Chciałbym
+zobaczyć góry na północy, które są bardzo piękne latem, kiedy śnieg już
+stopniał, a rzeki są pełne wody. To są rzeczy, które zawsze chcieli wiedzieć,
+i powinni byli zapytać nas dużo wcześniej.
+
+Ten dokument opisuje, jak zainstalować i skonfigurować aplikację. Najpierw
+pobierz najnowszą wersję, następnie uruchom instalator i postępuj zgodnie z
+krokami wyświetlanymi na ekranie. Ustawienia są zapisywane w pliku w twoim
+katalogu domowym, który możesz edytować w dowolnym edytorze tekstu. Jeśli
+wystąpi błąd, sprawdź plik dziennika i upewnij się, że wszystkie wymagane
+usługi są uruchomione. Więcej przykładów użycia tych funkcji znajdziesz w
+następnej części podręcznika.`,
+
+	"tr": `Bu sabah hava soğuktu, bu yüzden evde kalıp gazete okuduk. Erkek
+kardeşim şehirdeki büyük bir şirkette çalışıyor ve bankanın hesaplarını
+yöneten insanlar için yazılım yazıyor. İşin ilginç olduğunu, ama haftanın
+sonundan önce her şeyi bitirmek için hiçbir zaman yeterli zaman olmadığını
+söylüyor. Akşamları çocuklarla birlikte sık sık parkta yürüyüş yapıyoruz ve
+gün içinde neler olduğunu konuşuyoruz. Hükümet yeni okullar inşa etmeye ve
+yolları iyileştirmeye söz vermiş olsa da, insanların çoğu bir sonraki
+seçimlere kadar hiçbir şeyin değişmeyeceğini düşünüyor. Dünyanın herhangi bir
+yerine seyahat edebilseydin ne yapardın? Ben kuzeydeki dağları görmek
+isterdim, çünkü yazın karlar eridiğinde ve nehirler suyla dolduğunda çok
+güzel oluyorlar. Bunlar onların her zaman bilmek istedikleri şeyler ve bize
+çok daha önce sormaları gerekirdi.
+
+Bu belge, uygulamanın nasıl kurulacağını ve yapılandırılacağını açıklar.
+Önce en son sürümü indirin, ardından kurulum programını çalıştırın ve
+ekrandaki adımları izleyin. Ayarlar, ana dizininizdeki bir dosyada saklanır
+ve bu dosyayı herhangi bir metin düzenleyiciyle değiştirebilirsiniz. Bir hata
+oluşursa günlük dosyasını kontrol edin ve gerekli tüm hizmetlerin çalıştığından
+emin olun. Bu özelliklerin nasıl kullanılacağına dair daha fazla örneği
+kılavuzun bir sonraki bölümünde bulabilirsiniz.`,
+
+	"ru": `Сегодня утром было холодно, поэтому мы остались дома и читали газету.
+Мой брат работает в большой компании в городе, где он пишет программы для
+людей, которые управляют счетами банка. Он говорит, что работа интересная, но
+что никогда не хватает времени, чтобы закончить всё до конца недели. Вечером
+мы часто гуляем с детьми в парке и разговариваем о том, что случилось за
+день. Хотя правительство обещало построить новые школы и улучшить дороги,
+большинство людей думает, что ничего не изменится до следующих выборов. Что
+бы ты сделал, если бы мог поехать в любое место в мире? Я хотел бы увидеть
+горы на севере, которые очень красивы летом, когда снег уже растаял и реки
+полны воды. Это те вещи, которые они всегда хотели знать, и им следовало
+спросить нас гораздо раньше.
+
+Этот документ описывает, как установить и настроить приложение. Сначала
+скачайте последнюю версию, затем запустите программу установки и следуйте
+шагам на экране. Настройки хранятся в файле в вашем домашнем каталоге,
+который можно изменить в любом текстовом редакторе. Если возникнет ошибка,
+проверьте файл журнала и убедитесь, что все необходимые службы запущены.
+Больше примеров использования этих функций вы найдёте в следующем разделе
+руководства.`,
+
+	"uk": `Сьогодні зранку було холодно, тому ми залишилися вдома і читали
+газету. Мій брат працює у великій компанії в місті, де він пише програми для
+людей, які керують рахунками банку. Він каже, що робота цікава, але що ніколи
+не вистачає часу, щоб закінчити все до кінця тижня. Увечері ми часто гуляємо
+з дітьми в парку і розмовляємо про те, що сталося протягом дня. Хоча уряд
+обіцяв збудувати нові школи та покращити дороги, більшість людей вважає, що
+нічого не зміниться до наступних виборів. Що б ти зробив, якби міг поїхати
+будь-куди у світі? Я хотів би побачити гори на півночі, які дуже гарні
+влітку, коли сніг уже розтанув і річки повні води. Це ті речі, які вони
+завжди хотіли знати, і їм слід було запитати нас набагато раніше. Її
+відповідь була цілком зрозуміла, і ґанок біля їхнього будинку вже
+відремонтували.
+
+Цей документ описує, як встановити та налаштувати застосунок. Спочатку
+завантажте останню версію, потім запустіть програму встановлення і виконайте
+кроки на екрані. Налаштування зберігаються у файлі у вашому домашньому
+каталозі, який можна змінити в будь-якому текстовому редакторі. Якщо
+виникне помилка, перевірте файл журналу і переконайтеся, що всі потрібні
+служби запущені. Більше прикладів використання цих функцій ви знайдете в
+наступному розділі посібника.`,
+}
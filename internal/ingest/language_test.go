@@ -0,0 +1,103 @@
+package ingest
+
+import (
+	"testing"
+
+	"wafer/internal/config"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"The committee will publish its report on the new housing policy next month.", "en"},
+		{"Der Ausschuss wird seinen Bericht über die neue Wohnungspolitik nächsten Monat veröffentlichen.", "de"},
+		{"Le comité publiera son rapport sur la nouvelle politique du logement le mois prochain.", "fr"},
+		{"El comité publicará su informe sobre la nueva política de vivienda el próximo mes.", "es"},
+		{"Il comitato pubblicherà il suo rapporto sulla nuova politica abitativa il mese prossimo.", "it"},
+		{"O comitê vai publicar o seu relatório sobre a nova política de habitação no próximo mês.", "pt"},
+		{"De commissie zal volgende maand haar rapport over het nieuwe woonbeleid publiceren.", "nl"},
+		{"Kommittén kommer att publicera sin rapport om den nya bostadspolitiken nästa månad.", "sv"},
+		{"Komisja opublikuje w przyszłym miesiącu swój raport o nowej polityce mieszkaniowej.", "pl"},
+		{"Komite, yeni konut politikası hakkındaki raporunu gelecek ay yayınlayacak.", "tr"},
+		{"Комитет опубликует свой доклад о новой жилищной политике в следующем месяце.", "ru"},
+		{"Комітет опублікує свою доповідь про нову житлову політику наступного місяця.", "uk"},
+		{"Η επιτροπή θα δημοσιεύσει την έκθεσή της τον επόμενο μήνα.", "el"},
+		{"ستنشر اللجنة تقريرها الشهر المقبل.", "ar"},
+		{"הוועדה תפרסם את הדוח שלה בחודש הבא.", "he"},
+		{"समिति अगले महीने अपनी रिपोर्ट प्रकाशित करेगी।", "hi"},
+		{"คณะกรรมการจะเผยแพร่รายงานในเดือนหน้า", "th"},
+		{"위원회는 다음 달에 보고서를 발표할 예정이다.", "ko"},
+		{"委員会は来月、新しい住宅政策に関する報告書を発表する。", "ja"},
+		{"委员会将于下个月发布关于新住房政策的报告。", "zh"},
+		{"42 + 17 = 59", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want+" "+tt.text, func(t *testing.T) {
+			got, confidence := detectLanguage(tt.text)
+			if got != tt.want {
+				t.Errorf("detectLanguage() = %q (%.3f), want %q", got, confidence, tt.want)
+			}
+			if (got == "") != (confidence == 0) || confidence < 0 || confidence > 1 {
+				t.Errorf("confidence = %v for language %q", confidence, got)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage_Confidence(t *testing.T) {
+	_, clear := detectLanguage("The committee will publish its report on the new housing policy next month.")
+	_, ambiguous := detectLanguage("Hotel Taxi Restaurant")
+	if clear <= ambiguous {
+		t.Errorf("confidence of a clear sentence %.3f should exceed that of loanwords %.3f", clear, ambiguous)
+	}
+
+	// Letters of another script dilute the confidence
+	_, mixed := detectLanguage("The committee will publish its report: Комитет опубликует доклад.")
+	if mixed >= clear {
+		t.Errorf("confidence of mixed scripts %.3f should be below %.3f", mixed, clear)
+	}
+}
+
+func TestIdentifyLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"The committee will publish its report on the new housing policy next month.", "en"},
+		{"Invoice overdue. Please pay promptly.", ""},
+		{"sku: A1\nname: Standing desk\ndescription: Oak top, electric lift\nprice: 420", ""},
+		{"42 + 17 = 59", ""},
+	}
+
+	for _, tt := range tests {
+		got, confidence := identifyLanguage(tt.text)
+		if got != tt.want {
+			t.Errorf("identifyLanguage(%q) = %q (%.3f), want %q", tt.text, got, confidence, tt.want)
+		}
+		if got == "" && confidence != 0 {
+			t.Errorf("identifyLanguage(%q) confidence = %v for an undetermined language", tt.text, confidence)
+		}
+	}
+}
+
+func TestSupportedLanguages(t *testing.T) {
+	scripts := map[string]bool{}
+	for _, script := range languageScripts {
+		if script.language != "" {
+			scripts[script.language] = true
+		}
+	}
+	for _, language := range config.SupportedLanguages {
+		if _, ok := languageSamples[language]; !ok && !scripts[language] {
+			t.Errorf("language %q has neither a sample nor a script", language)
+		}
+	}
+	if len(languageSamples)+len(scripts) != len(config.SupportedLanguages) {
+		t.Errorf("%d samples and %d script languages do not match %d supported languages",
+			len(languageSamples), len(scripts), len(config.SupportedLanguages))
+	}
+}
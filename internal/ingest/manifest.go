@@ -0,0 +1,88 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RunManifest records the settings and results of an ingestion run, so its
+// embeddings can be reproduced exactly
+type RunManifest struct {
+	StartedAt         string         `json:"started_at"`
+	FinishedAt        string         `json:"finished_at"`
+	Directory         string         `json:"directory"`
+	Model             string         `json:"model"`
+	ChunkSize         int            `json:"chunk_size"`
+	ChunkStrategy     string         `json:"chunk_strategy"`
+	ChunkOverlap      int            `json:"chunk_overlap"`
+	ChunkUnit         string         `json:"chunk_unit"`
+	Tokenizer         string         `json:"tokenizer,omitempty"`
+	MinChunkSize      int            `json:"min_chunk_size,omitempty"`
+	MinChunkPolicy    string         `json:"min_chunk_policy,omitempty"`
+	MaxChars          int            `json:"max_chars,omitempty"`
+	SemanticThreshold float64        `json:"semantic_threshold,omitempty"`
+	SemanticWindow    int            `json:"semantic_window,omitempty"`
+	SemanticMinSize   int            `json:"semantic_min_size,omitempty"`
+	ParentSize        int            `json:"parent_size,omitempty"`
+	EmbedParents      bool           `json:"embed_parents,omitempty"`
+	CodeFiles         bool           `json:"code,omitempty"`
+	HeaderTemplate    string         `json:"header_template,omitempty"`
+	TextColumns       string         `json:"text_columns,omitempty"`
+	IDColumn          string         `json:"id_column,omitempty"`
+	MetadataColumns   []string       `json:"metadata_columns,omitempty"`
+	Normalize         []string       `json:"normalize"`
+	StripBoilerplate  bool           `json:"strip_boilerplate,omitempty"`
+	Encoding          string         `json:"encoding,omitempty"`
+	IncludeExt        []string       `json:"include_ext,omitempty"`
+	Languages         []string       `json:"languages,omitempty"`
+	RedactMode        string         `json:"redact_mode,omitempty"`
+	RedactRules       string         `json:"redact_rules,omitempty"`
+	SecretPolicy      string         `json:"secret_policy,omitempty"`
+	SecretReport      string         `json:"secret_report,omitempty"`
+	Dedup             string         `json:"dedup,omitempty"`
+	DedupMethod       string         `json:"dedup_method,omitempty"`
+	DedupThreshold    float64        `json:"dedup_threshold,omitempty"`
+	FilesProcessed    int            `json:"files_processed"`
+	FilesSkipped      int            `json:"files_skipped"`
+	SkipReasons       map[string]int `json:"skip_reasons,omitempty"`
+	ChunksCreated     int            `json:"chunks_created"`
+	Duplicates        int            `json:"duplicates,omitempty"`
+	LanguageSkipped   int            `json:"language_skipped,omitempty"`
+	Redactions        map[string]int `json:"redactions,omitempty"`
+	Secrets           map[string]int `json:"secrets,omitempty"`
+	SecretFiles       int            `json:"secret_files_skipped,omitempty"`
+	SecretChunks      int            `json:"secret_chunks_skipped,omitempty"`
+}
+
+// ManifestPath returns the manifest file kept next to an output file, such
+// as "storage/vectors.manifest.jsonl" for "storage/vectors.jsonl"
+func ManifestPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".manifest.jsonl"
+}
+
+// AppendManifest appends the manifest of a run to path as one JSON line,
+// since the output file accumulates the records of every run
+func AppendManifest(path string, manifest RunManifest) error {
+	if manifest.Normalize == nil {
+		manifest.Normalize = []string{}
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open manifest file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package ingest
+
+import (
+	"regexp"
+	"strings"
+)
+
+// sectionSeparator joins the heading titles of a Markdown section path
+const sectionSeparator = " > "
+
+var (
+	atxHeadingRE      = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextUnderlineRE = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	fenceRE           = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	tableDelimiterRE  = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+)
+
+// Markdown block kinds
+const (
+	blockParagraph = iota
+	blockHeading
+	blockCode
+	blockTable
+)
+
+// markdownBlock is a structural unit of a Markdown document
+type markdownBlock struct {
+	span
+	kind  int
+	level int    // Heading level, for heading blocks
+	title string // Heading text, for heading blocks
+}
+
+// markdownStrategy splits Markdown on its heading hierarchy and packs the
+// blocks of each section up to the budget without breaking code fences or tables
+type markdownStrategy struct {
+	budget *budget
+}
+
+// Split implements Strategy
+func (s *markdownStrategy) Split(text string) []Chunk {
+	var chunks []Chunk
+	var headings []markdownBlock
+	var section []markdownBlock
+
+	flushSection := func() {
+		chunks = append(chunks, s.packSection(text, section, headingPath(headings))...)
+		section = section[:0]
+	}
+
+	for _, block := range parseMarkdownBlocks(text) {
+		if block.kind == blockHeading {
+			flushSection()
+			for len(headings) > 0 && headings[len(headings)-1].level >= block.level {
+				headings = headings[:len(headings)-1]
+			}
+			headings = append(headings, block)
+		}
+		section = append(section, block)
+	}
+	flushSection()
+
+	return chunks
+}
+
+// packSection groups the blocks of one section into chunks within the budget
+func (s *markdownStrategy) packSection(text string, blocks []markdownBlock, path string) []Chunk {
+	var chunks []Chunk
+	var current []markdownBlock
+	currentWords := 0
+	currentCost := 0
+
+	flush := func() {
+		// A heading with no content of its own lives on in its children's paths
+		if len(current) == 0 || (len(current) == 1 && current[0].kind == blockHeading) {
+			current = current[:0]
+			currentWords, currentCost = 0, 0
+			return
+		}
+		chunks = append(chunks, Chunk{
+			Text:      text[current[0].start:current[len(current)-1].end],
+			WordCount: currentWords,
+			Section:   path,
+			StartByte: current[0].start,
+			EndByte:   current[len(current)-1].end,
+		})
+		current = current[:0]
+		currentWords, currentCost = 0, 0
+	}
+
+	for _, block := range blocks {
+		words := wordSpans(text, block.span)
+		costs := s.budget.spanCosts(text, words)
+		cost := sum(costs)
+
+		// Prose that cannot fit on its own is split; code and tables never are
+		if cost > s.budget.size && block.kind == blockParagraph {
+			flush()
+			for _, chunk := range packWords(text, block.span, words, costs, s.budget.size, 0) {
+				chunk.Section = path
+				chunks = append(chunks, chunk)
+			}
+			continue
+		}
+
+		if len(current) > 0 && currentCost+cost > s.budget.size {
+			// Keep a heading together with the first block that follows it
+			if len(current) == 1 && current[0].kind == blockHeading {
+				current = append(current, block)
+				currentWords += len(words)
+				flush()
+				continue
+			}
+			flush()
+		}
+
+		current = append(current, block)
+		currentWords += len(words)
+		currentCost += cost
+	}
+	flush()
+
+	return chunks
+}
+
+// headingPath renders the open headings as a breadcrumb such as "Install > Linux"
+func headingPath(headings []markdownBlock) string {
+	titles := make([]string, len(headings))
+	for i, heading := range headings {
+		titles[i] = heading.title
+	}
+	return strings.Join(titles, sectionSeparator)
+}
+
+// parseMarkdownBlocks splits a Markdown document into headings, fenced code
+// blocks, tables and paragraphs
+func parseMarkdownBlocks(text string) []markdownBlock {
+	lines := splitLines(text)
+	var blocks []markdownBlock
+
+	for i := 0; i < len(lines); {
+		line := text[lines[i].start:lines[i].end]
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case fenceRE.MatchString(line):
+			fence := strings.TrimSpace(fenceRE.FindStringSubmatch(line)[1])
+			end := i + 1
+			for end < len(lines) && !isClosingFence(text[lines[end].start:lines[end].end], fence) {
+				end++
+			}
+			if end == len(lines) {
+				end-- // Unclosed fences run to the end of the document
+			}
+			blocks = append(blocks, markdownBlock{span: span{lines[i].start, lines[end].end}, kind: blockCode})
+			i = end + 1
+
+		case atxHeadingRE.MatchString(line):
+			m := atxHeadingRE.FindStringSubmatch(line)
+			blocks = append(blocks, markdownBlock{
+				span:  lines[i],
+				kind:  blockHeading,
+				level: len(m[1]),
+				title: strings.TrimSpace(m[2]),
+			})
+			i++
+
+		case isTableStart(text, lines, i):
+			end := i + 1
+			for end+1 < len(lines) && strings.Contains(text[lines[end+1].start:lines[end+1].end], "|") &&
+				strings.TrimSpace(text[lines[end+1].start:lines[end+1].end]) != "" {
+				end++
+			}
+			blocks = append(blocks, markdownBlock{span: span{lines[i].start, lines[end].end}, kind: blockTable})
+			i = end + 1
+
+		default:
+			end := i
+			for end+1 < len(lines) && !startsNewBlock(text, lines, end+1) {
+				end++
+			}
+
+			// A paragraph underlined with === or --- is a setext heading
+			if end+1 < len(lines) {
+				if m := setextUnderlineRE.FindStringSubmatch(text[lines[end+1].start:lines[end+1].end]); m != nil {
+					level := 2
+					if m[1][0] == '=' {
+						level = 1
+					}
+					title := strings.Join(strings.Fields(text[lines[i].start:lines[end].end]), " ")
+					blocks = append(blocks, markdownBlock{
+						span:  span{lines[i].start, lines[end+1].end},
+						kind:  blockHeading,
+						level: level,
+						title: title,
+					})
+					i = end + 2
+					continue
+				}
+			}
+
+			blocks = append(blocks, markdownBlock{span: span{lines[i].start, lines[end].end}, kind: blockParagraph})
+			i = end + 1
+		}
+	}
+
+	return blocks
+}
+
+// startsNewBlock reports whether line i ends the paragraph before it
+func startsNewBlock(text string, lines []span, i int) bool {
+	line := text[lines[i].start:lines[i].end]
+	return strings.TrimSpace(line) == "" ||
+		fenceRE.MatchString(line) ||
+		atxHeadingRE.MatchString(line) ||
+		setextUnderlineRE.MatchString(line) ||
+		isTableStart(text, lines, i)
+}
+
+// isTableStart reports whether line i is a table header followed by a delimiter row
+func isTableStart(text string, lines []span, i int) bool {
+	if i+1 >= len(lines) {
+		return false
+	}
+	header := text[lines[i].start:lines[i].end]
+	delimiter := text[lines[i+1].start:lines[i+1].end]
+	return strings.Contains(header, "|") && strings.Contains(delimiter, "-") && tableDelimiterRE.MatchString(delimiter)
+}
+
+// isClosingFence reports whether line closes a fence opened with fence
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// splitLines returns the span of every line in text, excluding newlines
+func splitLines(text string) []span {
+	var lines []span
+	start := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lines = append(lines, span{start, i})
+			start = i + 1
+		}
+	}
+	if start < len(text) {
+		lines = append(lines, span{start, len(text)})
+	}
+	return lines
+}
+
+// frontMatterRE matches the YAML front matter opening a Markdown document
+var frontMatterRE = regexp.MustCompile(`\A---[ \t]*\r?\n(?s:(.*?))\r?\n---[ \t]*(?:\r?\n|\z)`)
+
+// markdownExtractor keeps Markdown as it is, with its front matter blanked
+// out, and takes its title from the front matter or its first top heading
+type markdownExtractor struct{}
+
+// Extract implements Extractor
+func (markdownExtractor) Extract(data []byte) ([]ExtractedText, error) {
+	text := string(data)
+	var title string
+
+	// Blank the front matter rather than cut it, so offsets stay those of the file
+	if m := frontMatterRE.FindStringSubmatchIndex(text); m != nil {
+		for _, line := range strings.Split(text[m[2]:m[3]], "\n") {
+			if key, value, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(key) == "title" {
+				title = strings.Trim(strings.TrimSpace(value), `"'`)
+			}
+		}
+		blank := []byte(text[:m[1]])
+		for i, b := range blank {
+			if b != '\n' {
+				blank[i] = ' '
+			}
+		}
+		text = string(blank) + text[m[1]:]
+	}
+
+	if title == "" {
+		for _, block := range parseMarkdownBlocks(text) {
+			if block.kind == blockHeading && block.level == 1 {
+				title = block.title
+				break
+			}
+		}
+	}
+
+	part := ExtractedText{Text: text, Markdown: true, Verbatim: true}
+	if title != "" {
+		part.Metadata = map[string]string{"title": title}
+	}
+	return []ExtractedText{part}, nil
+}
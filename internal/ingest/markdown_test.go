@@ -0,0 +1,117 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wafer/internal/config"
+)
+
+const sampleMarkdown = "# Install\n" +
+	"\n" +
+	"Wafer ships as a single binary.\n" +
+	"\n" +
+	"## Linux\n" +
+	"\n" +
+	"### Docker\n" +
+	"\n" +
+	"Run the image with your documents mounted.\n" +
+	"\n" +
+	"```bash\n" +
+	"docker run --rm \\\n" +
+	"\n" +
+	"  -v $(pwd)/documents:/data wafer ingest /data\n" +
+	"```\n" +
+	"\n" +
+	"Usage\n" +
+	"=====\n" +
+	"\n" +
+	"| Flag | Default |\n" +
+	"|------|---------|\n" +
+	"| --model | nomic-embed-text |\n"
+
+func TestParseMarkdownBlocks(t *testing.T) {
+	blocks := parseMarkdownBlocks(sampleMarkdown)
+
+	wantKinds := []int{
+		blockHeading, blockParagraph, blockHeading, blockHeading, blockParagraph,
+		blockCode, blockHeading, blockTable,
+	}
+	if len(blocks) != len(wantKinds) {
+		t.Fatalf("parseMarkdownBlocks() got %d blocks, want %d", len(blocks), len(wantKinds))
+	}
+	for i, block := range blocks {
+		if block.kind != wantKinds[i] {
+			t.Errorf("block %d (%q): got kind %d, want %d",
+				i, sampleMarkdown[block.start:block.end], block.kind, wantKinds[i])
+		}
+	}
+
+	// The blank line inside the fence must not split the code block
+	code := sampleMarkdown[blocks[5].start:blocks[5].end]
+	if !strings.HasPrefix(code, "```bash") || !strings.HasSuffix(code, "```") {
+		t.Errorf("code block = %q", code)
+	}
+
+	if blocks[6].title != "Usage" || blocks[6].level != 1 {
+		t.Errorf("setext heading got title %q level %d", blocks[6].title, blocks[6].level)
+	}
+}
+
+func TestMarkdownStrategy_SectionPaths(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 300})
+
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "guide.md")
+	if err := os.WriteFile(path, []byte(sampleMarkdown), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	chunks, err := chunker.ChunkFile(path)
+	if err != nil {
+		t.Fatalf("ChunkFile() error = %v", err)
+	}
+
+	wantSections := []string{"Install", "Install > Linux > Docker", "Usage"}
+	if len(chunks) != len(wantSections) {
+		t.Fatalf("ChunkFile() got %d chunks, want %d: %+v", len(chunks), len(wantSections), chunks)
+	}
+	for i, chunk := range chunks {
+		if chunk.Section != wantSections[i] {
+			t.Errorf("chunk %d: got section %q, want %q", i, chunk.Section, wantSections[i])
+		}
+	}
+
+	if !strings.Contains(chunks[1].Text, "```bash") || !strings.HasSuffix(chunks[1].Text, "```") {
+		t.Errorf("Docker chunk should contain the whole code fence, got %q", chunks[1].Text)
+	}
+}
+
+func TestMarkdownStrategy_NeverSplitsCodeFence(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 5})
+
+	text := "# Example\n\nShort intro.\n\n```go\n" + strings.Repeat("fmt.Println(\"hello world\")\n", 10) + "```\n"
+	chunks := chunker.chunkText(chunker.markdown, text)
+
+	fences := 0
+	for _, chunk := range chunks {
+		fences += strings.Count(chunk.Text, "```")
+		if strings.Count(chunk.Text, "```")%2 != 0 {
+			t.Errorf("chunk %d splits a code fence: %q", chunk.Index, chunk.Text)
+		}
+	}
+	if fences != 2 {
+		t.Errorf("expected the fence to appear once, counted %d markers", fences)
+	}
+}
+
+func TestChunker_PlainTextIgnoresMarkdown(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 300})
+
+	chunks := chunker.ChunkText("# Heading\n\nBody text.")
+	if len(chunks) != 1 || chunks[0].Section != "" {
+		t.Errorf("ChunkText() should not apply Markdown structure, got %+v", chunks)
+	}
+}
@@ -0,0 +1,238 @@
+package ingest
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+
+	"wafer/internal/config"
+)
+
+// normalizeStage rewrites text into w
+type normalizeStage func(text string, w *rewriter)
+
+// normalizeStages maps stage names to their implementations
+var normalizeStages = map[string]normalizeStage{
+	config.NormalizeNFC:         normalForm(norm.NFC),
+	config.NormalizeNFKC:        normalForm(norm.NFKC),
+	config.NormalizeControls:    stripControls,
+	config.NormalizeQuotes:      foldQuotes,
+	config.NormalizeDehyphenate: dehyphenate,
+	config.NormalizeWhitespace:  collapseWhitespace,
+	config.NormalizeLowercase:   lowercase,
+}
+
+// normalizer applies the configured normalization stages to document text
+// before it is chunked, keeping track of where each byte came from
+type normalizer struct {
+	names  []string // Stages applied, in order
+	stages []normalizeStage
+}
+
+// newNormalizer returns a normalizer for the named stages, applied in the
+// order of config.NormalizeStages, or nil when no stage is selected
+func newNormalizer(names []string) *normalizer {
+	selected := map[string]bool{}
+	for _, name := range names {
+		selected[name] = true
+	}
+
+	n := &normalizer{}
+	for _, name := range config.NormalizeStages {
+		if selected[name] {
+			n.names = append(n.names, name)
+			n.stages = append(n.stages, normalizeStages[name])
+		}
+	}
+	if len(n.stages) == 0 {
+		return nil
+	}
+	return n
+}
+
+// normalize runs text through every stage and trims the result, returning
+// the map from the normalized text back to text
+func (n *normalizer) normalize(text string) (string, *rewriteMap) {
+	m := &rewriteMap{from: make([]int, len(text)+1)}
+	for i := range m.from {
+		m.from[i] = i
+	}
+
+	for _, stage := range append(n.stages[:len(n.stages):len(n.stages)], trimSpace) {
+		w := &rewriter{}
+		stage(text, w)
+
+		// Compose the stage's map with the map of the stages before it
+		from := append(w.from, len(text))
+		text = w.b.String()
+		for i := range from {
+			from[i] = m.from[from[i]]
+		}
+		m.from = from
+	}
+	return text, m
+}
+
+// rewriter collects the output of a stage along with the input offset each
+// output byte was produced from
+type rewriter struct {
+	b    strings.Builder
+	from []int
+}
+
+// write appends s, produced from the input at offset at
+func (w *rewriter) write(s string, at int) {
+	w.b.WriteString(s)
+	for range len(s) {
+		w.from = append(w.from, at)
+	}
+}
+
+// copy appends text[start:end] unchanged
+func (w *rewriter) copy(text string, start, end int) {
+	w.b.WriteString(text[start:end])
+	for i := start; i < end; i++ {
+		w.from = append(w.from, i)
+	}
+}
+
+// writeRune appends r, produced from the input at offset at
+func (w *rewriter) writeRune(r rune, at int) {
+	w.write(string(r), at)
+}
+
+// rewriteMap translates offsets in normalized text back to the text it was
+// normalized from
+type rewriteMap struct {
+	from []int // Source offset of each normalized byte, plus the source length
+}
+
+// start maps a normalized start offset to the source
+func (m *rewriteMap) start(n int) int {
+	return m.from[n]
+}
+
+// end maps a normalized exclusive end offset to the source, extending it to
+// the end of a source character that was expanded into several
+func (m *rewriteMap) end(n int) int {
+	j := n
+	for j < len(m.from)-1 && m.from[j] == m.from[n-1] {
+		j++
+	}
+	return m.from[j]
+}
+
+// normalForm returns a stage applying a Unicode normalization form
+func normalForm(form norm.Form) normalizeStage {
+	return func(text string, w *rewriter) {
+		var it norm.Iter
+		it.InitString(form, text)
+		for !it.Done() {
+			at := it.Pos()
+			w.write(string(it.Next()), at)
+		}
+	}
+}
+
+// stripControls removes zero-width, formatting and control characters other
+// than newlines and tabs
+func stripControls(text string, w *rewriter) {
+	for i, r := range text {
+		if r == '\n' || r == '\t' || !(unicode.Is(unicode.Cc, r) || unicode.Is(unicode.Cf, r)) {
+			w.writeRune(r, i)
+		}
+	}
+}
+
+// quoteFolds maps typographic quotes and primes to their ASCII forms
+var quoteFolds = map[rune]rune{
+	'‘': '\'', '’': '\'', '‚': '\'', '‛': '\'', '′': '\'',
+	'“': '"', '”': '"', '„': '"', '‟': '"', '″': '"',
+}
+
+// foldQuotes replaces typographic quotes with ASCII quotes
+func foldQuotes(text string, w *rewriter) {
+	for i, r := range text {
+		if folded, ok := quoteFolds[r]; ok {
+			r = folded
+		}
+		w.writeRune(r, i)
+	}
+}
+
+// dehyphenate joins words hyphenated across a line break, such as
+// "exam-\nple", when the line continues in lowercase
+func dehyphenate(text string, w *rewriter) {
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if (r == '-' || r == '‐') && i > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(text[:i])
+			if next := wrappedWord(text, i+size); unicode.IsLetter(prev) && next > 0 {
+				i = next
+				continue
+			}
+		}
+		w.write(text[i:i+size], i)
+		i += size
+	}
+}
+
+// wrappedWord returns the offset of the lowercase word continuing on the
+// next line after offset i, or -1 if the line does not end at i
+func wrappedWord(text string, i int) int {
+	i += len(text[i:]) - len(strings.TrimLeft(text[i:], " \t"))
+	if i == len(text) || text[i] != '\n' {
+		return -1
+	}
+	i++
+	i += len(text[i:]) - len(strings.TrimLeft(text[i:], " \t"))
+	if r, _ := utf8.DecodeRuneInString(text[i:]); !unicode.IsLower(r) {
+		return -1
+	}
+	return i
+}
+
+// collapseWhitespace replaces each run of whitespace with a single space,
+// or with its line break when it spans lines, keeping at most one blank line
+func collapseWhitespace(text string, w *rewriter) {
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !unicode.IsSpace(r) {
+			w.write(text[i:i+size], i)
+			i += size
+			continue
+		}
+
+		start, breaks := i, 0
+		for i < len(text) {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			if r == '\n' && breaks < 2 {
+				w.write("\n", i)
+				breaks++
+			}
+			i += size
+		}
+		if breaks == 0 {
+			w.write(" ", start)
+		}
+	}
+}
+
+// lowercase maps every character to lower case
+func lowercase(text string, w *rewriter) {
+	for i, r := range text {
+		w.writeRune(unicode.ToLower(r), i)
+	}
+}
+
+// trimSpace removes the surrounding whitespace a stage may have exposed
+func trimSpace(text string, w *rewriter) {
+	trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+	start := len(text) - len(trimmed)
+	w.copy(text, start, start+len(strings.TrimRightFunc(trimmed, unicode.IsSpace)))
+}
@@ -0,0 +1,146 @@
+package ingest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"wafer/internal/config"
+)
+
+func TestNormalizer_Stages(t *testing.T) {
+	tests := []struct {
+		name   string
+		stages []string
+		input  string
+		want   string
+	}{
+		{
+			name:   "nfc composes accents",
+			stages: []string{config.NormalizeNFC},
+			input:  "café ﬁle",
+			want:   "café ﬁle",
+		},
+		{
+			name:   "nfkc folds compatibility forms",
+			stages: []string{config.NormalizeNFKC},
+			input:  "ﬁle ＡＢＣ ①",
+			want:   "file ABC 1",
+		},
+		{
+			name:   "controls stripped",
+			stages: []string{config.NormalizeControls},
+			input:  "zero\u200bwidth\u00ad soft\x07bell\ttab\nline",
+			want:   "zerowidth softbell\ttab\nline",
+		},
+		{
+			name:   "quotes folded",
+			stages: []string{config.NormalizeQuotes},
+			input:  "“Don’t” ‚low‛",
+			want:   "\"Don't\" 'low'",
+		},
+		{
+			name:   "dehyphenate joins wrapped words",
+			stages: []string{config.NormalizeDehyphenate},
+			input:  "an exam-\nple and a well-\n  known co-\nOperative, state-of-the-art",
+			want:   "an example and a wellknown co-\nOperative, state-of-the-art",
+		},
+		{
+			name:   "whitespace collapsed",
+			stages: []string{config.NormalizeWhitespace},
+			input:  "one \t two  \nthree\n\n\n\n  four",
+			want:   "one two\nthree\n\nfour",
+		},
+		{
+			name:   "lowercase",
+			stages: []string{config.NormalizeLowercase},
+			input:  "Hello ÉCOLE",
+			want:   "hello école",
+		},
+		{
+			name:   "stages run in pipeline order",
+			stages: []string{config.NormalizeLowercase, config.NormalizeWhitespace, config.NormalizeNFKC},
+			input:  "ＴＨＥ\u3000ＥＮＤ",
+			want:   "the end",
+		},
+		{
+			name:   "result trimmed",
+			stages: []string{config.NormalizeControls},
+			input:  "\u200b  text  \ufeff",
+			want:   "text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, m := newNormalizer(tt.stages).normalize(tt.input)
+			if got != tt.want {
+				t.Errorf("normalize() = %q, want %q", got, tt.want)
+			}
+			if len(m.from) != len(got)+1 || m.from[len(got)] != len(tt.input) {
+				t.Errorf("map has %d entries ending at %d, want %d ending at %d",
+					len(m.from), m.from[len(m.from)-1], len(got)+1, len(tt.input))
+			}
+		})
+	}
+}
+
+func TestNewNormalizer(t *testing.T) {
+	if n := newNormalizer(nil); n != nil {
+		t.Errorf("newNormalizer(nil) = %+v, want nil", n)
+	}
+
+	n := newNormalizer([]string{config.NormalizeLowercase, config.NormalizeDehyphenate, config.NormalizeLowercase})
+	if want := []string{config.NormalizeDehyphenate, config.NormalizeLowercase}; !reflect.DeepEqual(n.names, want) {
+		t.Errorf("stages = %v, want %v", n.names, want)
+	}
+}
+
+func TestChunker_NormalizeOffsets(t *testing.T) {
+	text := "  \ufeffThe “ﬁrst” para-\r\ngraph   has ＷＩＤＥ text.\r\n\r\n\r\n" +
+		"Second\u200b para\u00adgraph, well-\n known and exam-\nple heavy.  "
+	stages := []string{config.NormalizeNFKC, config.NormalizeControls, config.NormalizeQuotes,
+		config.NormalizeDehyphenate, config.NormalizeWhitespace, config.NormalizeLowercase}
+
+	for _, size := range []int{1, 2, 3, 50} {
+		chunker := NewChunker(&config.Config{ChunkSize: size, Normalize: stages})
+		chunks := chunker.ChunkText(text)
+		if len(chunks) == 0 {
+			t.Fatalf("size %d: no chunks", size)
+		}
+
+		for _, chunk := range chunks {
+			// Each chunk keeps the source it points at and embeds its
+			// normalized words
+			source := strings.ReplaceAll(text[chunk.StartByte:chunk.EndByte], "\r\n", "\n")
+			if source != chunk.Text {
+				t.Errorf("size %d chunk %d: text %q, want %q", size, chunk.Index, chunk.Text, source)
+			}
+			if got, _ := chunker.normalize.normalize(source); strings.Join(strings.Fields(got), " ") != chunk.embeddingInput() {
+				t.Errorf("size %d chunk %d: %d-%d normalizes to %q, want %q",
+					size, chunk.Index, chunk.StartByte, chunk.EndByte, got, chunk.embeddingInput())
+			}
+			if want := 1 + countLineBreaks(text[:chunk.StartByte]); chunk.StartLine != want {
+				t.Errorf("size %d chunk %d: start line %d, want %d", size, chunk.Index, chunk.StartLine, want)
+			}
+		}
+	}
+
+	chunks := NewChunker(&config.Config{ChunkSize: 50, Normalize: stages}).ChunkText(text)
+	if want := "the \"first\" paragraph has wide text. second paragraph, wellknown and example heavy."; chunks[0].EmbeddingText != want {
+		t.Errorf("embedding text = %q, want %q", chunks[0].EmbeddingText, want)
+	}
+	if want := strings.ReplaceAll(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), "\ufeff")), "\r\n", "\n"); chunks[0].Text != want {
+		t.Errorf("text = %q, want %q", chunks[0].Text, want)
+	}
+}
+
+func TestChunker_NormalizeSkipsCode(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 100, Normalize: []string{config.NormalizeWhitespace, config.NormalizeLowercase}})
+
+	code := "func Main() {\n\tprintln(\"Hi\")\n}"
+	chunks := chunker.chunkText(&codeStrategy{budget: chunker.budget, language: "go"}, code)
+	if len(chunks) != 1 || chunks[0].Text != code {
+		t.Errorf("code chunks = %+v, want the source unchanged", chunks)
+	}
+}
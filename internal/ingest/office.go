@@ -0,0 +1,787 @@
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// officeMaxPartSize bounds the decompressed size of a part of an Office
+// document, so a small archive cannot expand without limit
+const officeMaxPartSize = 256 << 20
+
+// officeArchive is the parts of a zip-based Office document by name
+type officeArchive struct {
+	parts map[string]*zip.File
+}
+
+// openOfficeArchive opens an Office document. A password-protected document
+// is not a zip archive but a compound file holding an encrypted package, and
+// is skipped.
+func openOfficeArchive(data []byte) (*officeArchive, error) {
+	if bytes.HasPrefix(data, []byte{0xD0, 0xCF, 0x11, 0xE0}) && bytes.Contains(data, utf16LE("EncryptedPackage")) {
+		return nil, &skipError{reason: SkipEncrypted}
+	}
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	a := &officeArchive{parts: map[string]*zip.File{}}
+	for _, file := range r.File {
+		a.parts[file.Name] = file
+	}
+	return a, nil
+}
+
+// utf16LE encodes ASCII text as little-endian UTF-16
+func utf16LE(s string) []byte {
+	b := make([]byte, 0, 2*len(s))
+	for i := 0; i < len(s); i++ {
+		b = append(b, s[i], 0)
+	}
+	return b
+}
+
+// read returns the content of a part
+func (a *officeArchive) read(name string) ([]byte, error) {
+	file, ok := a.parts[name]
+	if !ok {
+		return nil, fmt.Errorf("missing part: %s", name)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, officeMaxPartSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(data) > officeMaxPartSize {
+		return nil, fmt.Errorf("part too large: %s", name)
+	}
+	return data, nil
+}
+
+// walk calls fn with each token of an XML part
+func (a *officeArchive) walk(name string, fn func(xml.Token)) error {
+	data, err := a.read(name)
+	if err != nil {
+		return err
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		fn(token)
+	}
+}
+
+// relationships returns the parts a part refers to by relationship ID
+func (a *officeArchive) relationships(part string) map[string]string {
+	dir, file := path.Split(part)
+	targets := map[string]string{}
+	a.walk(dir+"_rels/"+file+".rels", func(token xml.Token) {
+		el, ok := token.(xml.StartElement)
+		if !ok || el.Name.Local != "Relationship" || xmlAttr(el, "TargetMode") == "External" {
+			return
+		}
+		target := xmlAttr(el, "Target")
+		if strings.HasPrefix(target, "/") {
+			target = target[1:]
+		} else {
+			target = path.Join(dir, target)
+		}
+		targets[xmlAttr(el, "Id")] = target
+	})
+	return targets
+}
+
+// title returns the title held by a metadata part, such as
+// docProps/core.xml, or ""
+func (a *officeArchive) title(name string) string {
+	var b strings.Builder
+	inTitle := false
+	a.walk(name, func(token xml.Token) {
+		switch t := token.(type) {
+		case xml.StartElement:
+			inTitle = t.Name.Local == "title"
+		case xml.EndElement:
+			inTitle = false
+		case xml.CharData:
+			if inTitle {
+				b.Write(t)
+			}
+		}
+	})
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// xmlAttr returns the value of the attribute with a local name, or ""
+func xmlAttr(el xml.StartElement, local string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// officeRelID returns the relationship ID an element refers to, its r:id
+func officeRelID(el xml.StartElement) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == "id" && strings.HasSuffix(attr.Name.Space, "/relationships") {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// officeMetadata returns metadata holding the document title, if any, and
+// the given entries
+func officeMetadata(title string, entries ...string) map[string]string {
+	metadata := map[string]string{}
+	if title != "" {
+		metadata["title"] = title
+	}
+	for i := 0; i+1 < len(entries); i += 2 {
+		metadata[entries[i]] = entries[i+1]
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
+
+// officeWriter collects the paragraphs of a document as Markdown blocks,
+// writing each table row as one line with its cells separated by " | "
+type officeWriter struct {
+	blocks []string        // Paragraphs and tables written
+	para   strings.Builder // Text of the current paragraph
+	prefix string          // Heading or bullet marker of the current paragraph
+	cell   []string        // Paragraphs of the current table cell
+	row    []string        // Cells of the current table row
+	table  []string        // Rows of the current table
+	tables int             // Depth of open tables
+}
+
+// startParagraph starts a paragraph
+func (w *officeWriter) startParagraph() {
+	w.para.Reset()
+	w.prefix = ""
+}
+
+// heading marks the current paragraph as a heading of a level from 1
+func (w *officeWriter) heading(level int) {
+	w.prefix = strings.Repeat("#", min(max(level, 1), 6)) + " "
+}
+
+// endParagraph ends a paragraph, adding it to the current table cell when
+// a table is open
+func (w *officeWriter) endParagraph() {
+	text := strings.TrimSpace(w.para.String())
+	w.para.Reset()
+	if text == "" {
+		return
+	}
+	if w.tables > 0 {
+		w.cell = append(w.cell, strings.Join(strings.Fields(text), " "))
+		return
+	}
+	w.blocks = append(w.blocks, w.prefix+text)
+}
+
+// startTable starts a table
+func (w *officeWriter) startTable() {
+	w.tables++
+}
+
+// endCell ends a table cell
+func (w *officeWriter) endCell() {
+	w.row = append(w.row, strings.Join(w.cell, " "))
+	w.cell = nil
+}
+
+// endRow ends a table row, leaving out rows with no text
+func (w *officeWriter) endRow() {
+	if strings.TrimSpace(strings.Join(w.row, "")) != "" {
+		w.table = append(w.table, strings.Join(w.row, " | "))
+	}
+	w.row = nil
+}
+
+// endTable ends a table, writing it as a block once the outer table ends
+func (w *officeWriter) endTable() {
+	if w.tables == 0 {
+		return
+	}
+	w.tables--
+	if w.tables == 0 && len(w.table) > 0 {
+		w.blocks = append(w.blocks, strings.Join(w.table, "\n"))
+		w.table = nil
+	}
+}
+
+// String returns the blocks written, separated by blank lines
+func (w *officeWriter) String() string {
+	return cleanLines(strings.Join(w.blocks, "\n\n"))
+}
+
+// docxExtractor extracts the paragraphs of a Word document, its body text
+// without headers, footers or comments. Headings become Markdown headings,
+// so chunks get their section, list paragraphs become bullets and table rows
+// become lines. The document title is kept as metadata.
+type docxExtractor struct{}
+
+// Extract implements Extractor
+func (docxExtractor) Extract(data []byte) ([]ExtractedText, error) {
+	a, err := openOfficeArchive(data)
+	if err != nil {
+		return nil, err
+	}
+	headings := docxHeadingStyles(a)
+
+	w := &officeWriter{}
+	inRun, inText := false, false
+	err = a.walk("word/document.xml", func(token xml.Token) {
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				w.startParagraph()
+			case "pStyle":
+				if level := headings[xmlAttr(t, "val")]; level > 0 {
+					w.heading(level)
+				}
+			case "outlineLvl":
+				if level, err := strconv.Atoi(xmlAttr(t, "val")); err == nil && level < 9 && w.prefix == "" {
+					w.heading(level + 1)
+				}
+			case "numPr":
+				if w.prefix == "" {
+					w.prefix = "- "
+				}
+			case "r":
+				inRun = true
+			case "t":
+				inText = inRun
+			case "tab":
+				if inRun {
+					w.para.WriteByte('\t')
+				}
+			case "br", "cr":
+				if inRun {
+					w.para.WriteByte('\n')
+				}
+			case "tbl":
+				w.startTable()
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				w.endParagraph()
+			case "r":
+				inRun = false
+			case "t":
+				inText = false
+			case "tc":
+				w.endCell()
+			case "tr":
+				w.endRow()
+			case "tbl":
+				w.endTable()
+			}
+		case xml.CharData:
+			if inText {
+				w.para.Write(t)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	part := ExtractedText{Text: w.String(), Markdown: true}
+	part.Metadata = officeMetadata(a.title("docProps/core.xml"))
+	return []ExtractedText{part}, nil
+}
+
+// docxHeadingRE matches the names of heading styles, such as "heading 2"
+var docxHeadingRE = regexp.MustCompile(`^heading ([1-9])$`)
+
+// docxHeadingStyles returns the heading level of each heading style of a
+// Word document by style ID, found by style name or outline level
+func docxHeadingStyles(a *officeArchive) map[string]int {
+	levels := map[string]int{"Title": 1}
+	for level := 1; level <= 9; level++ {
+		levels["Heading"+strconv.Itoa(level)] = level
+	}
+
+	id := ""
+	a.walk("word/styles.xml", func(token xml.Token) {
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "style":
+				id = xmlAttr(t, "styleId")
+			case "name":
+				name := strings.ToLower(xmlAttr(t, "val"))
+				if m := docxHeadingRE.FindStringSubmatch(name); m != nil && id != "" {
+					levels[id] = int(m[1][0] - '0')
+				} else if name == "title" && id != "" {
+					levels[id] = 1
+				}
+			case "outlineLvl":
+				if level, err := strconv.Atoi(xmlAttr(t, "val")); err == nil && level < 9 && id != "" && levels[id] == 0 {
+					levels[id] = level + 1
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == "style" {
+				id = ""
+			}
+		}
+	})
+	return levels
+}
+
+// odtSkipped are OpenDocument elements whose text is not part of the body:
+// notes, comments and tracked deletions
+var odtSkipped = map[string]bool{
+	"note":            true,
+	"annotation":      true,
+	"tracked-changes": true,
+}
+
+// odtSpaceRE matches runs of whitespace, which OpenDocument collapses
+var odtSpaceRE = regexp.MustCompile(`\s+`)
+
+// odtExtractor extracts the paragraphs of an OpenDocument text document
+// like docxExtractor does those of a Word document
+type odtExtractor struct{}
+
+// Extract implements Extractor
+func (odtExtractor) Extract(data []byte) ([]ExtractedText, error) {
+	a, err := openOfficeArchive(data)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &officeWriter{}
+	paras, skip := 0, 0 // Depths of open paragraphs and skipped elements
+	listItem := false   // The next paragraph starts a list item
+	err = a.walk("content.xml", func(token xml.Token) {
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skip > 0 || odtSkipped[t.Name.Local] {
+				skip++
+				return
+			}
+			switch t.Name.Local {
+			case "h", "p":
+				paras++
+				if paras > 1 {
+					// A paragraph of a frame within a paragraph
+					w.para.WriteByte(' ')
+					return
+				}
+				w.startParagraph()
+				if t.Name.Local == "h" {
+					level, err := strconv.Atoi(xmlAttr(t, "outline-level"))
+					if err != nil {
+						level = 1
+					}
+					w.heading(level)
+				} else if listItem {
+					w.prefix = "- "
+				}
+				listItem = false
+			case "list-item":
+				listItem = true
+			case "s":
+				count, err := strconv.Atoi(xmlAttr(t, "c"))
+				if err != nil {
+					count = 1
+				}
+				w.para.WriteString(strings.Repeat(" ", min(max(count, 1), 100)))
+			case "tab":
+				w.para.WriteByte('\t')
+			case "line-break":
+				w.para.WriteByte('\n')
+			case "table":
+				w.startTable()
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				return
+			}
+			switch t.Name.Local {
+			case "h", "p":
+				paras--
+				if paras == 0 {
+					w.endParagraph()
+				}
+			case "table-cell":
+				w.endCell()
+			case "table-row":
+				w.endRow()
+			case "table":
+				w.endTable()
+			}
+		case xml.CharData:
+			if paras > 0 && skip == 0 {
+				w.para.WriteString(odtSpaceRE.ReplaceAllString(string(t), " "))
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	part := ExtractedText{Text: w.String(), Markdown: true}
+	part.Metadata = officeMetadata(a.title("meta.xml"))
+	return []ExtractedText{part}, nil
+}
+
+// pptxSlideRE matches the slide parts of a presentation
+var pptxSlideRE = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+// pptxExtractor extracts the text of each slide of a PowerPoint
+// presentation, in slide order, as a part of its own. Slide titles become
+// Markdown headings, so chunks get their section, and the slide number is
+// kept as metadata along with the document title.
+type pptxExtractor struct{}
+
+// Extract implements Extractor
+func (pptxExtractor) Extract(data []byte) ([]ExtractedText, error) {
+	a, err := openOfficeArchive(data)
+	if err != nil {
+		return nil, err
+	}
+	title := a.title("docProps/core.xml")
+
+	var parts []ExtractedText
+	for i, slide := range pptxSlides(a) {
+		w := &officeWriter{}
+		inText, isTitle := false, false
+		err := a.walk(slide, func(token xml.Token) {
+			switch t := token.(type) {
+			case xml.StartElement:
+				switch t.Name.Local {
+				case "sp":
+					isTitle = false
+				case "ph":
+					kind := xmlAttr(t, "type")
+					isTitle = kind == "title" || kind == "ctrTitle"
+				case "p":
+					w.startParagraph()
+					if isTitle && w.tables == 0 {
+						w.heading(1)
+					}
+				case "t":
+					inText = true
+				case "br":
+					w.para.WriteByte('\n')
+				case "tbl":
+					w.startTable()
+				}
+			case xml.EndElement:
+				switch t.Name.Local {
+				case "p":
+					w.endParagraph()
+				case "t":
+					inText = false
+				case "tc":
+					w.endCell()
+				case "tr":
+					w.endRow()
+				case "tbl":
+					w.endTable()
+				}
+			case xml.CharData:
+				if inText {
+					w.para.Write(t)
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if text := w.String(); text != "" {
+			metadata := officeMetadata(title, "slide", strconv.Itoa(i+1))
+			parts = append(parts, ExtractedText{Text: text, Metadata: metadata, Markdown: true})
+		}
+	}
+	return parts, nil
+}
+
+// pptxSlides returns the slide parts of a presentation in slide order, as
+// listed by the presentation or, failing that, by their numbers
+func pptxSlides(a *officeArchive) []string {
+	targets := a.relationships("ppt/presentation.xml")
+	var slides []string
+	a.walk("ppt/presentation.xml", func(token xml.Token) {
+		if el, ok := token.(xml.StartElement); ok && el.Name.Local == "sldId" {
+			if target, ok := targets[officeRelID(el)]; ok {
+				slides = append(slides, target)
+			}
+		}
+	})
+	if len(slides) > 0 {
+		return slides
+	}
+
+	numbers := map[string]int{}
+	for name := range a.parts {
+		if m := pptxSlideRE.FindStringSubmatch(name); m != nil {
+			numbers[name], _ = strconv.Atoi(m[1])
+			slides = append(slides, name)
+		}
+	}
+	sort.Slice(slides, func(i, j int) bool { return numbers[slides[i]] < numbers[slides[j]] })
+	return slides
+}
+
+// xlsxCell is a cell of a spreadsheet row
+type xlsxCell struct {
+	col   int // 0-based column
+	value string
+	text  bool // The value is a string rather than a number, boolean or error
+}
+
+// xlsxRow is a row of a spreadsheet with the cells that hold a value
+type xlsxRow struct {
+	num   int // 1-based row number
+	cells []xlsxCell
+}
+
+// xlsxExtractor extracts each sheet of an Excel workbook as a part of its
+// own, one line per row. When the first row of a sheet is a row of labels,
+// each later row is written as "Label: value; Label: value" so a chunk keeps
+// the context of its columns. The sheet name and the rows each chunk spans
+// are kept as metadata along with the document title.
+type xlsxExtractor struct{}
+
+// Extract implements Extractor
+func (xlsxExtractor) Extract(data []byte) ([]ExtractedText, error) {
+	a, err := openOfficeArchive(data)
+	if err != nil {
+		return nil, err
+	}
+	title := a.title("docProps/core.xml")
+	shared := xlsxSharedStrings(a)
+
+	targets := a.relationships("xl/workbook.xml")
+	type sheet struct{ name, part string }
+	var sheets []sheet
+	err = a.walk("xl/workbook.xml", func(token xml.Token) {
+		if el, ok := token.(xml.StartElement); ok && el.Name.Local == "sheet" {
+			if target, ok := targets[officeRelID(el)]; ok {
+				sheets = append(sheets, sheet{name: xmlAttr(el, "name"), part: target})
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []ExtractedText
+	for _, sheet := range sheets {
+		rows, err := xlsxRows(a, sheet.part, shared)
+		if err != nil {
+			return nil, err
+		}
+
+		// A first row of labels names the columns of the rows below it
+		var labels map[int]string
+		if len(rows) > 1 && len(rows[0].cells) > 0 {
+			labels = map[int]string{}
+			for _, cell := range rows[0].cells {
+				if !cell.text {
+					labels = nil
+					break
+				}
+				labels[cell.col] = strings.Join(strings.Fields(cell.value), " ")
+			}
+			if labels != nil {
+				rows = rows[1:]
+			}
+		}
+
+		var lines []string
+		var nums []int
+		for _, row := range rows {
+			values := make([]string, 0, len(row.cells))
+			for _, cell := range row.cells {
+				value := strings.Join(strings.Fields(cell.value), " ")
+				if labels != nil {
+					label, ok := labels[cell.col]
+					if !ok || label == "" {
+						label = xlsxColumnName(cell.col)
+					}
+					value = label + ": " + value
+				}
+				values = append(values, value)
+			}
+			separator := " | "
+			if labels != nil {
+				separator = "; "
+			}
+			lines = append(lines, strings.Join(values, separator))
+			nums = append(nums, row.num)
+		}
+		if len(lines) > 0 {
+			parts = append(parts, ExtractedText{
+				Text:     strings.Join(lines, "\n"),
+				Metadata: officeMetadata(title, "sheet", sheet.name),
+				Rows:     nums,
+			})
+		}
+	}
+	return parts, nil
+}
+
+// xlsxSharedStrings returns the shared strings table of a workbook, without
+// the phonetic runs of East Asian text
+func xlsxSharedStrings(a *officeArchive) []string {
+	var strs []string
+	var b strings.Builder
+	inText, phonetic := false, 0
+	a.walk("xl/sharedStrings.xml", func(token xml.Token) {
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				b.Reset()
+			case "t":
+				inText = phonetic == 0
+			case "rPh":
+				phonetic++
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				strs = append(strs, b.String())
+			case "t":
+				inText = false
+			case "rPh":
+				phonetic--
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	})
+	return strs
+}
+
+// xlsxRows returns the rows of a worksheet that hold values
+func xlsxRows(a *officeArchive, part string, shared []string) ([]xlsxRow, error) {
+	var rows []xlsxRow
+	var row xlsxRow
+	var cell xlsxCell
+	var kind string
+	var value strings.Builder
+	inValue := false
+
+	err := a.walk(part, func(token xml.Token) {
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				num, err := strconv.Atoi(xmlAttr(t, "r"))
+				if err != nil {
+					num = len(rows) + 1
+					if len(rows) > 0 {
+						num = rows[len(rows)-1].num + 1
+					}
+				}
+				row = xlsxRow{num: num}
+			case "c":
+				col, ok := xlsxColumn(xmlAttr(t, "r"))
+				if !ok {
+					col = len(row.cells)
+					if len(row.cells) > 0 {
+						col = row.cells[len(row.cells)-1].col + 1
+					}
+				}
+				cell, kind = xlsxCell{col: col}, xmlAttr(t, "t")
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				cell.value, cell.text = xlsxValue(kind, value.String(), shared)
+				if strings.TrimSpace(cell.value) != "" {
+					row.cells = append(row.cells, cell)
+				}
+			case "row":
+				if len(row.cells) > 0 {
+					rows = append(rows, row)
+				}
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	})
+	return rows, err
+}
+
+// xlsxValue returns the text of a cell value of a type, and whether it is a
+// string. Numbers, dates among them, are written as stored.
+func xlsxValue(kind, value string, shared []string) (string, bool) {
+	switch kind {
+	case "s":
+		if i, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && i >= 0 && i < len(shared) {
+			return shared[i], true
+		}
+		return "", true
+	case "inlineStr", "str":
+		return value, true
+	case "b":
+		if strings.TrimSpace(value) == "1" {
+			return "TRUE", false
+		}
+		return "FALSE", false
+	}
+	return strings.TrimSpace(value), false
+}
+
+// xlsxColumn returns the 0-based column of a cell reference such as "B3"
+func xlsxColumn(ref string) (int, bool) {
+	col, n := 0, 0
+	for ; n < len(ref) && ref[n] >= 'A' && ref[n] <= 'Z'; n++ {
+		col = col*26 + int(ref[n]-'A'+1)
+	}
+	return col - 1, n > 0 && n <= 3
+}
+
+// xlsxColumnName returns the letters of a 0-based column, such as "AB"
+func xlsxColumnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
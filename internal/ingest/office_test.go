@@ -0,0 +1,225 @@
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// buildOffice assembles an Office document from its parts by name
+func buildOffice(parts map[string]string) []byte {
+	names := make([]string, 0, len(parts))
+	for name := range parts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, name := range names {
+		f, _ := w.Create(name)
+		f.Write([]byte(parts[name]))
+	}
+	w.Close()
+	return b.Bytes()
+}
+
+const (
+	officeCore = `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Field Guide</dc:title></cp:coreProperties>`
+	officeRels = `http://schemas.openxmlformats.org/officeDocument/2006/relationships`
+	wordNS     = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+	drawingNS  = `xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"`
+	sheetNS    = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="` + officeRels + `"`
+)
+
+// pptxSlide returns a slide with a title and a body text box
+func pptxSlide(title string, body ...string) string {
+	var paras strings.Builder
+	for _, text := range body {
+		fmt.Fprintf(&paras, "<a:p><a:r><a:t>%s</a:t></a:r></a:p>", text)
+	}
+	return `<p:sld ` + drawingNS + `><p:cSld><p:spTree>` +
+		`<p:sp><p:nvSpPr><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>` + title + `</a:t></a:r></a:p></p:txBody></p:sp>` +
+		`<p:sp><p:nvSpPr><p:nvPr><p:ph idx="1"/></p:nvPr></p:nvSpPr><p:txBody>` + paras.String() + `</p:txBody></p:sp>` +
+		`</p:spTree></p:cSld></p:sld>`
+}
+
+// xlsxWorkbook returns the parts of a workbook with the given sheets, whose
+// cells refer to the shared strings given
+func xlsxWorkbook(shared []string, sheets ...string) map[string]string {
+	var list, rels strings.Builder
+	parts := map[string]string{"docProps/core.xml": officeCore}
+	for i, sheet := range sheets {
+		name, data, _ := strings.Cut(sheet, "=")
+		fmt.Fprintf(&list, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, name, i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="%s/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, officeRels, i+1)
+		parts[fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)] = `<worksheet ` + sheetNS + `><sheetData>` + data + `</sheetData></worksheet>`
+	}
+	var strs strings.Builder
+	for _, s := range shared {
+		fmt.Fprintf(&strs, "<si><t>%s</t></si>", s)
+	}
+	parts["xl/workbook.xml"] = `<workbook ` + sheetNS + `><sheets>` + list.String() + `</sheets></workbook>`
+	parts["xl/_rels/workbook.xml.rels"] = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + rels.String() + `</Relationships>`
+	parts["xl/sharedStrings.xml"] = `<sst ` + sheetNS + `>` + strs.String() + `</sst>`
+	return parts
+}
+
+func TestOfficeExtractors(t *testing.T) {
+	tests := []struct {
+		name      string
+		extractor Extractor
+		parts     map[string]string
+		want      []string            // Text of each part
+		wantMeta  []map[string]string // Metadata of each part
+	}{
+		{
+			name:      "docx headings, lists and tables",
+			extractor: docxExtractor{},
+			parts: map[string]string{
+				"docProps/core.xml": officeCore,
+				"word/styles.xml": `<w:styles ` + wordNS + `>` +
+					`<w:style w:styleId="Kop1"><w:name w:val="heading 1"/></w:style>` +
+					`<w:style w:styleId="Custom"><w:name w:val="Section"/><w:pPr><w:outlineLvl w:val="1"/></w:pPr></w:style></w:styles>`,
+				"word/document.xml": `<w:document ` + wordNS + `><w:body>` +
+					`<w:p><w:pPr><w:pStyle w:val="Kop1"/><w:tabs><w:tab w:val="left" w:pos="720"/></w:tabs></w:pPr><w:r><w:t>Getting started</w:t></w:r></w:p>` +
+					`<w:p><w:r><w:t xml:space="preserve">Install the </w:t></w:r><w:r><w:t>tool</w:t></w:r><w:r><w:br/><w:t>then run it.</w:t></w:r></w:p>` +
+					`<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/></w:numPr></w:pPr><w:r><w:t>First step</w:t></w:r></w:p>` +
+					`<w:p><w:pPr><w:pStyle w:val="Custom"/></w:pPr><w:r><w:t>Options</w:t></w:r></w:p>` +
+					`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Flag</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Meaning</w:t></w:r></w:p></w:tc></w:tr>` +
+					`<w:tr><w:tc><w:p><w:r><w:t>-v</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Verbose</w:t></w:r></w:p><w:p><w:r><w:t>output</w:t></w:r></w:p></w:tc></w:tr></w:tbl>` +
+					`<w:p><w:r><w:delText>removed</w:delText><w:tab/><w:t>Done.</w:t></w:r></w:p>` +
+					`</w:body></w:document>`,
+			},
+			want:     []string{"# Getting started\n\nInstall the tool\nthen run it.\n\n- First step\n\n## Options\n\nFlag | Meaning\n-v | Verbose output\n\nDone."},
+			wantMeta: []map[string]string{{"title": "Field Guide"}},
+		},
+		{
+			name:      "odt headings, lists, spaces and notes",
+			extractor: odtExtractor{},
+			parts: map[string]string{
+				"meta.xml": `<office:document-meta xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><office:meta><dc:title>Notes</dc:title></office:meta></office:document-meta>`,
+				"content.xml": `<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"><office:body><office:text>` +
+					`<text:h text:outline-level="2">Plan</text:h>` +
+					"<text:p>Ship   the\n release<text:s text:c=\"3\"/>now<text:note><text:note-body><text:p>A footnote</text:p></text:note-body></text:note>.</text:p>" +
+					`<text:list><text:list-item><text:p>Write docs</text:p></text:list-item><text:list-item><text:p>Tag</text:p></text:list-item></text:list>` +
+					`<table:table><table:table-row><table:table-cell><text:p>Owner</text:p></table:table-cell><table:table-cell><text:p>Ana</text:p></table:table-cell></table:table-row></table:table>` +
+					`</office:text></office:body></office:document-content>`,
+			},
+			want:     []string{"## Plan\n\nShip the release   now.\n\n- Write docs\n\n- Tag\n\nOwner | Ana"},
+			wantMeta: []map[string]string{{"title": "Notes"}},
+		},
+		{
+			name:      "pptx slides in presentation order",
+			extractor: pptxExtractor{},
+			parts: map[string]string{
+				"docProps/core.xml": officeCore,
+				"ppt/presentation.xml": `<p:presentation ` + drawingNS + ` xmlns:r="` + officeRels + `"><p:sldIdLst>` +
+					`<p:sldId id="256" r:id="rId3"/><p:sldId id="257" r:id="rId2"/><p:sldId id="258" r:id="rId4"/></p:sldIdLst></p:presentation>`,
+				"ppt/_rels/presentation.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+					`<Relationship Id="rId2" Type="` + officeRels + `/slide" Target="slides/slide1.xml"/>` +
+					`<Relationship Id="rId3" Type="` + officeRels + `/slide" Target="slides/slide2.xml"/>` +
+					`<Relationship Id="rId4" Type="` + officeRels + `/slide" Target="/ppt/slides/slide3.xml"/></Relationships>`,
+				"ppt/slides/slide1.xml": pptxSlide("Roadmap", "Ship in May", "Grow in June"),
+				"ppt/slides/slide2.xml": pptxSlide("Welcome"),
+				"ppt/slides/slide3.xml": `<p:sld ` + drawingNS + `><p:cSld><p:spTree/></p:cSld></p:sld>`,
+			},
+			want:     []string{"# Welcome", "# Roadmap\n\nShip in May\n\nGrow in June"},
+			wantMeta: []map[string]string{{"title": "Field Guide", "slide": "1"}, {"title": "Field Guide", "slide": "2"}},
+		},
+		{
+			name:      "xlsx sheets with and without a header row",
+			extractor: xlsxExtractor{},
+			parts: xlsxWorkbook([]string{"Name", "Role", "Ana", "Lead", "Bo"},
+				`People=<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>Active</t></is></c></row>`+
+					`<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2" t="s"><v>3</v></c><c r="C2" t="b"><v>1</v></c></row>`+
+					`<row r="5"><c r="A5" t="s"><v>4</v></c><c r="C5" t="b"><v>0</v></c><c r="D5"><v>42</v></c></row>`,
+				`Totals=<row r="3"><c r="B3"><v>1.5</v></c><c r="C3" t="str"><f>SUM(A1)</f><v>ok</v></c></row>`,
+				`Empty=`,
+			),
+			want:     []string{"Name: Ana; Role: Lead; Active: TRUE\nName: Bo; Active: FALSE; D: 42", "1.5 | ok"},
+			wantMeta: []map[string]string{{"title": "Field Guide", "sheet": "People"}, {"title": "Field Guide", "sheet": "Totals"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := tt.extractor.Extract(buildOffice(tt.parts))
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			var got []string
+			var meta []map[string]string
+			for _, part := range parts {
+				got = append(got, part.Text)
+				meta = append(meta, part.Metadata)
+				if !part.Markdown && tt.extractor != (xlsxExtractor{}) {
+					t.Error("Extract() text should be Markdown")
+				}
+			}
+			if strings.Join(got, "\f") != strings.Join(tt.want, "\f") {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+			if fmt.Sprint(meta) != fmt.Sprint(tt.wantMeta) {
+				t.Errorf("metadata = %v, want %v", meta, tt.wantMeta)
+			}
+		})
+	}
+}
+
+func TestOfficeExtractors_Errors(t *testing.T) {
+	// A password-protected document is a compound file holding the package
+	encrypted := append([]byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}, utf16LE("EncryptedPackage")...)
+	_, err := docxExtractor{}.Extract(encrypted)
+	var skip *skipError
+	if !errors.As(err, &skip) || skip.reason != SkipEncrypted {
+		t.Errorf("Extract() error = %v, want skipped as %s", err, SkipEncrypted)
+	}
+
+	if _, err := (docxExtractor{}).Extract([]byte("plain text")); err == nil {
+		t.Error("Extract() of a file that is not an archive should fail")
+	}
+	if _, err := (odtExtractor{}).Extract(buildOffice(map[string]string{"meta.xml": "<meta/>"})); err == nil {
+		t.Error("Extract() of an archive without content should fail")
+	}
+}
+
+func TestExtractedText_MetadataFor(t *testing.T) {
+	part := ExtractedText{Metadata: map[string]string{"sheet": "People"}, Rows: []int{2, 3, 7}}
+	tests := []struct {
+		first, last int
+		want        string
+	}{
+		{1, 1, "2"},
+		{1, 3, "2-7"},
+		{2, 9, "3-7"},
+	}
+	for _, tt := range tests {
+		got := part.metadataFor(tt.first, tt.last)
+		if got["row"] != tt.want || got["sheet"] != "People" {
+			t.Errorf("metadataFor(%d, %d) = %v, want row %s", tt.first, tt.last, got, tt.want)
+		}
+	}
+	if _, ok := part.Metadata["row"]; ok {
+		t.Error("metadataFor() should not change the metadata of the part")
+	}
+	if got := (ExtractedText{Metadata: part.Metadata}).metadataFor(1, 2); got["row"] != "" {
+		t.Errorf("metadataFor() without rows = %v", got)
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AB3": 27} {
+		got, ok := xlsxColumn(ref)
+		if !ok || got != want {
+			t.Errorf("xlsxColumn(%q) = %d, want %d", ref, got, want)
+		}
+		if name := xlsxColumnName(want); name != strings.TrimRight(ref, "0123456789") {
+			t.Errorf("xlsxColumnName(%d) = %q", want, name)
+		}
+	}
+}
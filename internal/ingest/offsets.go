@@ -0,0 +1,75 @@
+package ingest
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// sourceMap translates offsets in trimmed, line-ending-normalized text back
+// to byte offsets and line numbers in the original document
+type sourceMap struct {
+	base     int   // Bytes trimmed from the start of the original
+	baseLine int   // Line breaks trimmed from the start of the original
+	crlf     []int // Normalized offsets of newlines that were "\r\n" originally
+	newlines []int // Normalized offsets of every newline
+}
+
+// normalizeDocument trims surrounding whitespace and converts Windows and old
+// Mac line endings to "\n", recording how to map offsets back to the original
+func normalizeDocument(original string) (string, *sourceMap) {
+	m := &sourceMap{}
+
+	trimmed := strings.TrimLeftFunc(original, unicode.IsSpace)
+	m.base = len(original) - len(trimmed)
+	m.baseLine = countLineBreaks(original[:m.base])
+	trimmed = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+
+	var b strings.Builder
+	b.Grow(len(trimmed))
+	for i := 0; i < len(trimmed); i++ {
+		switch {
+		case trimmed[i] == '\r' && i+1 < len(trimmed) && trimmed[i+1] == '\n':
+			// Windows -> Unix: the "\r" is dropped and the "\n" kept
+			m.crlf = append(m.crlf, b.Len())
+		case trimmed[i] == '\r':
+			// Old Mac -> Unix
+			m.newlines = append(m.newlines, b.Len())
+			b.WriteByte('\n')
+		default:
+			if trimmed[i] == '\n' {
+				m.newlines = append(m.newlines, b.Len())
+			}
+			b.WriteByte(trimmed[i])
+		}
+	}
+
+	return b.String(), m
+}
+
+// startByte maps a normalized start offset to the original document
+func (m *sourceMap) startByte(n int) int {
+	// A chunk starting on a converted newline starts at its "\n"
+	return m.base + n + sort.SearchInts(m.crlf, n+1)
+}
+
+// endByte maps a normalized exclusive end offset to the original document
+func (m *sourceMap) endByte(n int) int {
+	return m.base + n + sort.SearchInts(m.crlf, n)
+}
+
+// line returns the 1-based line number of the byte at normalized offset n
+func (m *sourceMap) line(n int) int {
+	return 1 + m.baseLine + sort.SearchInts(m.newlines, n)
+}
+
+// countLineBreaks counts line breaks, treating "\r\n" as a single break
+func countLineBreaks(text string) int {
+	count := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' || (text[i] == '\r' && (i+1 == len(text) || text[i+1] != '\n')) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,661 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/lzw"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+// PDF objects, as parsed from a file or a content stream. Numbers are
+// float64, booleans bool and null nil.
+type (
+	pdfName    string
+	pdfString  string // Raw bytes of a literal or hexadecimal string
+	pdfKeyword string // Bare word, such as "obj" or a content stream operator
+	pdfArray   []any
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		data []byte // Raw, still encoded, data
+	}
+)
+
+// pdfObjectRE finds the objects and trailers of a PDF file
+var pdfObjectRE = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b|trailer\s*<<`)
+
+// pdfMaxDepth bounds the nesting of objects, references and form XObjects,
+// so malformed files cannot recurse without end
+const pdfMaxDepth = 32
+
+// pdfExtractor extracts the text of each page of a PDF file, in the order
+// the page draws it. It reads the objects of the file one after another
+// rather than through its cross-reference table, so files with a damaged
+// table still open. Encrypted files, files whose pages hold images but no
+// text and files too damaged to read are skipped. The document title is kept
+// as metadata.
+type pdfExtractor struct{}
+
+// Extract implements Extractor
+func (pdfExtractor) Extract(data []byte) (parts []ExtractedText, err error) {
+	// The reader trusts offsets and lengths in the file, so a damaged file
+	// that gets past its bounds checks is skipped rather than crashing the run
+	defer func() {
+		if recover() != nil {
+			parts, err = nil, &skipError{reason: SkipMalformed}
+		}
+	}()
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return nil, errors.New("not a PDF file")
+	}
+	f := parsePDF(data)
+	if f.trailer["Encrypt"] != nil {
+		return nil, &skipError{reason: SkipEncrypted}
+	}
+	pages := f.pages()
+	if len(pages) == 0 {
+		return nil, errors.New("no pages found")
+	}
+
+	var metadata map[string]string
+	if title := pdfText(f.resolve(f.dict(f.trailer["Info"])["Title"])); title != "" {
+		metadata = map[string]string{"title": title}
+	}
+
+	images := false
+	for i, page := range pages {
+		text, sawImages := f.pageText(page)
+		images = images || sawImages
+		if text != "" {
+			parts = append(parts, ExtractedText{Text: text, Metadata: metadata, Page: i + 1})
+		}
+	}
+	if len(parts) == 0 && images {
+		return nil, &skipError{reason: SkipImageOnly}
+	}
+	return parts, nil
+}
+
+// pdfFile is the objects of a PDF file by number, with its trailer
+type pdfFile struct {
+	objects map[int]any
+	trailer pdfDict             // Trailer entries, those of later updates taking precedence
+	fonts   map[pdfRef]*pdfFont // Fonts loaded so far
+}
+
+// parsePDF reads the objects and trailers of a PDF file in order, so the
+// objects of incremental updates replace those they update, then unpacks
+// the objects held in object streams
+func parsePDF(data []byte) *pdfFile {
+	f := &pdfFile{objects: map[int]any{}, trailer: pdfDict{}}
+	var trailers []pdfDict
+
+	for pos := 0; pos < len(data); {
+		m := pdfObjectRE.FindSubmatchIndex(data[pos:])
+		if m == nil {
+			break
+		}
+		l := &pdfLexer{src: data, pos: pos + m[1]}
+		if m[2] < 0 {
+			// A trailer dictionary, whose "<<" ends the match
+			l.pos -= 2
+			if dict, ok := l.object(0).(pdfDict); ok {
+				trailers = append(trailers, dict)
+			}
+			pos = max(l.pos, pos+m[1])
+			continue
+		}
+
+		num, _ := strconv.Atoi(string(data[pos+m[2] : pos+m[3]]))
+		obj := l.object(0)
+		if dict, ok := obj.(pdfDict); ok {
+			if stream := l.stream(dict); stream != nil {
+				obj = stream
+				// Cross-reference streams hold the trailer entries
+				if dict["Type"] == pdfName("XRef") {
+					trailers = append(trailers, dict)
+				}
+			}
+		}
+		f.objects[num] = obj
+		pos = max(l.pos, pos+m[1])
+	}
+
+	for _, trailer := range trailers {
+		for key, value := range trailer {
+			f.trailer[key] = value
+		}
+	}
+	f.unpackObjectStreams()
+
+	// Without a trailer, the catalog is found by its type
+	if f.trailer["Root"] == nil {
+		root := -1
+		for num, obj := range f.objects {
+			if dict, ok := obj.(pdfDict); ok && dict["Type"] == pdfName("Catalog") && num > root {
+				root = num
+			}
+		}
+		if root >= 0 {
+			f.trailer["Root"] = pdfRef{num: root}
+		}
+	}
+	return f
+}
+
+// unpackObjectStreams adds the objects compressed into object streams
+func (f *pdfFile) unpackObjectStreams() {
+	nums := make([]int, 0, len(f.objects))
+	for num := range f.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	for _, num := range nums {
+		stream, ok := f.objects[num].(*pdfStream)
+		if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		data, err := f.decode(stream)
+		if err != nil {
+			continue
+		}
+		n, _ := pdfInt(f.resolve(stream.dict["N"]))
+		first, _ := pdfInt(f.resolve(stream.dict["First"]))
+
+		header := &pdfLexer{src: data}
+		for i := 0; i < n; i++ {
+			objNum, ok1 := pdfInt(header.object(0))
+			offset, ok2 := pdfInt(header.object(0))
+			if !ok1 || !ok2 || first+offset >= len(data) {
+				break
+			}
+			if _, ok := f.objects[objNum]; !ok {
+				f.objects[objNum] = (&pdfLexer{src: data, pos: first + offset}).object(0)
+			}
+		}
+	}
+}
+
+// resolve follows references to the object they refer to
+func (f *pdfFile) resolve(obj any) any {
+	for depth := 0; depth < pdfMaxDepth; depth++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = f.objects[ref.num]
+	}
+	return nil
+}
+
+// dict resolves obj to a dictionary, the dictionary of a stream, or nil
+func (f *pdfFile) dict(obj any) pdfDict {
+	switch obj := f.resolve(obj).(type) {
+	case pdfDict:
+		return obj
+	case *pdfStream:
+		return obj.dict
+	}
+	return nil
+}
+
+// array resolves obj to an array, or nil
+func (f *pdfFile) array(obj any) pdfArray {
+	array, _ := f.resolve(obj).(pdfArray)
+	return array
+}
+
+// pdfPage is a page of a PDF file with the resources it inherits
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages walks the page tree of the document in page order
+func (f *pdfFile) pages() []pdfPage {
+	var pages []pdfPage
+	seen := map[any]bool{}
+
+	var walk func(node any, resources pdfDict, depth int)
+	walk = func(node any, resources pdfDict, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if seen[ref] {
+				return
+			}
+			seen[ref] = true
+		}
+		dict := f.dict(node)
+		if dict == nil || depth > pdfMaxDepth {
+			return
+		}
+		if own := f.dict(dict["Resources"]); own != nil {
+			resources = own
+		}
+		kids := f.array(dict["Kids"])
+		if dict["Type"] == pdfName("Page") || kids == nil && dict["Contents"] != nil {
+			pages = append(pages, pdfPage{dict: dict, resources: resources})
+			return
+		}
+		for _, kid := range kids {
+			walk(kid, resources, depth+1)
+		}
+	}
+
+	walk(f.dict(f.trailer["Root"])["Pages"], nil, 0)
+	return pages
+}
+
+// contents returns the decoded content streams of a page, joined
+func (f *pdfFile) contents(page pdfDict) []byte {
+	var streams []any
+	switch contents := f.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		streams = []any{contents}
+	case pdfArray:
+		streams = contents
+	}
+
+	var out []byte
+	for _, obj := range streams {
+		stream, ok := f.resolve(obj).(*pdfStream)
+		if !ok {
+			continue
+		}
+		data, err := f.decode(stream)
+		if err != nil && len(data) == 0 {
+			continue
+		}
+		out = append(append(out, data...), '\n')
+	}
+	return out
+}
+
+// decode applies the filters of a stream to its data. A stream cut short
+// returns the data decoded before the error.
+func (f *pdfFile) decode(stream *pdfStream) ([]byte, error) {
+	var filters []any
+	switch filter := f.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{filter}
+	case pdfArray:
+		filters = filter
+	}
+
+	data := stream.data
+	for _, filter := range filters {
+		var err error
+		switch f.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			data, err = inflate(data)
+		case pdfName("LZWDecode"), pdfName("LZW"):
+			data, err = io.ReadAll(lzw.NewReader(bytes.NewReader(data), lzw.MSB, 8))
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			data, err = decodeASCIIHex(data)
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			data, err = decodeASCII85(data)
+		default:
+			return nil, fmt.Errorf("unsupported filter: %v", filter)
+		}
+		if err != nil {
+			return data, fmt.Errorf("failed to decode stream: %w", err)
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib data, falling back to raw deflate data
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// decodeASCIIHex decodes hexadecimal digits up to the ">" that ends them
+func decodeASCIIHex(data []byte) ([]byte, error) {
+	digits := make([]byte, 0, len(data))
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	_, err := hex.Decode(out, digits)
+	return out, err
+}
+
+// decodeASCII85 decodes base-85 data up to the "~>" that ends it
+func decodeASCII85(data []byte) ([]byte, error) {
+	if end := bytes.Index(data, []byte("~>")); end >= 0 {
+		data = data[:end]
+	}
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	out := make([]byte, 4*len(data)/5+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
+
+// pdfInt returns obj as an integer
+func pdfInt(obj any) (int, bool) {
+	n, ok := obj.(float64)
+	return int(n), ok
+}
+
+// pdfText decodes a text string, which is UTF-16 when it starts with a byte
+// order mark and otherwise close enough to Latin-1 for metadata
+func pdfText(obj any) string {
+	s, ok := obj.(pdfString)
+	if !ok {
+		return ""
+	}
+	if len(s) >= 2 && s[0] == 0xFE && s[1] == 0xFF {
+		return decodeUTF16BE([]byte(s[2:]))
+	}
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
+
+// decodeUTF16BE decodes big-endian UTF-16, ignoring an odd last byte
+func decodeUTF16BE(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+// pdfLexer reads PDF objects and content stream tokens
+type pdfLexer struct {
+	src []byte
+	pos int
+}
+
+// isPDFSpace reports whether c is PDF whitespace
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+// isPDFDelimiter reports whether c ends a name, number or keyword
+func isPDFDelimiter(c byte) bool {
+	return isPDFSpace(c) || bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+// skipSpace skips whitespace and comments
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '%' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		} else if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// object reads the next object or keyword. The end of an array or a
+// dictionary is returned as the keyword "]" or ">>", and the end of the
+// input as nil with the lexer at its end.
+func (l *pdfLexer) object(depth int) any {
+	l.skipSpace()
+	if l.pos >= len(l.src) || depth > pdfMaxDepth {
+		l.pos = len(l.src)
+		return nil
+	}
+
+	switch c := l.src[l.pos]; {
+	case c == '/':
+		return l.name()
+	case c == '(':
+		return l.literal()
+	case c == '<' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '<':
+		l.pos += 2
+		dict := pdfDict{}
+		for l.pos < len(l.src) {
+			key := l.object(depth + 1)
+			if key == pdfKeyword(">>") || key == nil {
+				break
+			}
+			if name, ok := key.(pdfName); ok {
+				dict[name] = l.object(depth + 1)
+			}
+		}
+		return dict
+	case c == '<':
+		return l.hexString()
+	case c == '>' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '>':
+		l.pos += 2
+		return pdfKeyword(">>")
+	case c == '[':
+		l.pos++
+		array := pdfArray{}
+		for l.pos < len(l.src) {
+			obj := l.object(depth + 1)
+			if obj == pdfKeyword("]") || obj == nil && l.pos >= len(l.src) {
+				break
+			}
+			array = append(array, obj)
+		}
+		return array
+	case c == ']':
+		l.pos++
+		return pdfKeyword("]")
+	case c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.':
+		return l.number()
+	case isPDFDelimiter(c):
+		// A stray ")", ">", "{" or "}"
+		l.pos++
+		return pdfKeyword([]byte{c})
+	}
+
+	word := l.word()
+	switch word {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	return pdfKeyword(word)
+}
+
+// word reads the bytes up to the next delimiter
+func (l *pdfLexer) word() string {
+	start := l.pos
+	for l.pos < len(l.src) && !isPDFDelimiter(l.src[l.pos]) {
+		l.pos++
+	}
+	return string(l.src[start:l.pos])
+}
+
+// number reads a number, or a reference when it is followed by a
+// generation number and "R"
+func (l *pdfLexer) number() any {
+	word := l.word()
+	n, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		return pdfKeyword(word)
+	}
+	if n < 0 || n != float64(int(n)) || bytes.ContainsAny([]byte(word), ".+-") {
+		return n
+	}
+
+	// Look ahead for "gen R"
+	start := l.pos
+	l.skipSpace()
+	genStart := l.pos
+	for l.pos < len(l.src) && l.src[l.pos] >= '0' && l.src[l.pos] <= '9' {
+		l.pos++
+	}
+	if l.pos > genStart && l.pos < len(l.src) && isPDFDelimiter(l.src[l.pos]) {
+		gen, _ := strconv.Atoi(string(l.src[genStart:l.pos]))
+		l.skipSpace()
+		if l.pos < len(l.src) && l.src[l.pos] == 'R' && (l.pos+1 == len(l.src) || isPDFDelimiter(l.src[l.pos+1])) {
+			l.pos++
+			return pdfRef{num: int(n), gen: gen}
+		}
+	}
+	l.pos = start
+	return n
+}
+
+// name reads a name, decoding its "#xx" escapes
+func (l *pdfLexer) name() pdfName {
+	l.pos++
+	word := l.word()
+	if !bytes.ContainsRune([]byte(word), '#') {
+		return pdfName(word)
+	}
+	var b []byte
+	for i := 0; i < len(word); i++ {
+		if word[i] == '#' && i+2 < len(word) {
+			if v, err := strconv.ParseUint(word[i+1:i+3], 16, 8); err == nil {
+				b = append(b, byte(v))
+				i += 2
+				continue
+			}
+		}
+		b = append(b, word[i])
+	}
+	return pdfName(b)
+}
+
+// literal reads a string in parentheses, which may nest
+func (l *pdfLexer) literal() pdfString {
+	l.pos++
+	var b []byte
+	for depth := 1; l.pos < len(l.src); l.pos++ {
+		c := l.src[l.pos]
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				l.pos++
+				return pdfString(b)
+			}
+		case '\\':
+			l.pos++
+			if l.pos >= len(l.src) {
+				return pdfString(b)
+			}
+			c = l.src[l.pos]
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// A line continuation
+				if l.pos+1 < len(l.src) && l.src[l.pos+1] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := 0
+					for i := 0; i < 3 && l.pos < len(l.src) && l.src[l.pos] >= '0' && l.src[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.src[l.pos]-'0')
+						l.pos++
+					}
+					l.pos--
+					c = byte(v)
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return pdfString(b)
+}
+
+// hexString reads a string of hexadecimal digits in angle brackets
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++
+	end := bytes.IndexByte(l.src[l.pos:], '>')
+	if end < 0 {
+		end = len(l.src) - l.pos
+	}
+	data, _ := decodeASCIIHex(l.src[l.pos : l.pos+end])
+	l.pos = min(l.pos+end+1, len(l.src))
+	return pdfString(data)
+}
+
+// stream reads the data of a stream following its dictionary, or returns
+// nil when no stream follows. A /Length that does not end at "endstream",
+// such as one given by a reference, is replaced by a search for it.
+func (l *pdfLexer) stream(dict pdfDict) *pdfStream {
+	l.skipSpace()
+	if l.pos >= len(l.src) || !bytes.HasPrefix(l.src[l.pos:], []byte("stream")) {
+		return nil
+	}
+	l.pos += len("stream")
+	if l.pos < len(l.src) && l.src[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+		l.pos++
+	}
+
+	start := l.pos
+	end := -1
+	if length, ok := pdfInt(dict["Length"]); ok && length >= 0 && start+length <= len(l.src) {
+		rest := bytes.TrimLeft(l.src[start+length:], "\r\n \t")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			end = start + length
+		}
+	}
+	if end < 0 {
+		k := bytes.Index(l.src[start:], []byte("endstream"))
+		if k < 0 {
+			k = len(l.src) - start
+		}
+		end = start + k
+		// The end-of-line before "endstream" is not part of the data
+		if end > start && l.src[end-1] == '\n' {
+			end--
+		}
+		if end > start && l.src[end-1] == '\r' {
+			end--
+		}
+	}
+
+	l.pos = end
+	if k := bytes.Index(l.src[end:], []byte("endstream")); k >= 0 {
+		l.pos = end + k + len("endstream")
+	}
+	return &pdfStream{dict: dict, data: l.src[start:end]}
+}
@@ -0,0 +1,254 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF assembles a PDF file from objects numbered from 1, leaving out
+// empty ones, with a cross-reference table and a trailer whose extra entries
+// are given
+func buildPDF(objects []string, trailer string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		if obj != "" {
+			offsets[i] = b.Len()
+			fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+		}
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		if offset == 0 {
+			b.WriteString("0000000000 65535 f \n")
+		} else {
+			fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+		}
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R %s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailer, xref)
+	return b.Bytes()
+}
+
+// pdfStreamObject returns a stream object, compressed when flate is set
+func pdfStreamObject(dict, data string, flate bool) string {
+	if flate {
+		var b bytes.Buffer
+		w := zlib.NewWriter(&b)
+		w.Write([]byte(data))
+		w.Close()
+		data = b.String()
+		dict += " /Filter /FlateDecode"
+	}
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+// pdfPages returns the objects of a document whose pages draw the given
+// content streams with the font F1 and the image Im1: the catalog, the page
+// tree, the font, the image, then each page and its content
+func pdfPages(font string, contents ...string) []string {
+	objects := []string{"<< /Type /Catalog /Pages 2 0 R >>", "", font, pdfStreamObject("/Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8", "\x00", false)}
+	var kids []string
+	for i, content := range contents {
+		page := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents %d 0 R >>", page+1),
+			pdfStreamObject("", content, i%2 == 1))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /Resources << /Font << /F1 3 0 R >> /XObject << /Im1 4 0 R >> >> >>",
+		strings.Join(kids, " "), len(contents))
+	return objects
+}
+
+const pdfHelvetica = "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"
+
+func TestPDFExtractor(t *testing.T) {
+	tests := []struct {
+		name      string
+		pdf       []byte
+		want      []string // Text of each page with text
+		wantPages []int
+		wantTitle string
+	}{
+		{
+			name: "lines, paragraphs and pages",
+			pdf: buildPDF(pdfPages(pdfHelvetica,
+				"BT /F1 12 Tf 14 TL 72 720 Td (Quarterly report) Tj T* (for the \\(first\\) quarter) Tj 0 -40 Td (Revenue grew.) Tj ET",
+				"BT /F1 12 Tf 72 720 Td [(W) 80 (afer) -300 (ingests)] TJ ( PDFs) Tj ET",
+			), "/Info << /Title (Q1 Report) >>"),
+			want:      []string{"Quarterly report\nfor the (first) quarter\n\nRevenue grew.", "Wafer ingests PDFs"},
+			wantPages: []int{1, 2},
+			wantTitle: "Q1 Report",
+		},
+		{
+			name: "words placed one by one",
+			pdf: buildPDF(pdfPages(pdfHelvetica,
+				"BT /F1 10 Tf 1 0 0 1 72 700 Tm (Hello) Tj 1 0 0 1 100 700 Tm (world) Tj 1 0 0 1 125 700 Tm (s) Tj ET",
+			), ""),
+			want:      []string{"Hello worlds"},
+			wantPages: []int{1},
+		},
+		{
+			name: "empty pages are left out",
+			pdf: buildPDF(pdfPages(pdfHelvetica,
+				"",
+				"q 1 0 0 1 0 0 cm BT /F1 12 Tf 72 720 Td (Only page two) Tj ET Q",
+			), "/Info << /Title <FEFF00C9007400E9> >>"),
+			want:      []string{"Only page two"},
+			wantPages: []int{2},
+			wantTitle: "Été",
+		},
+		{
+			name: "differences and glyph names",
+			pdf: buildPDF(pdfPages("<< /Type /Font /Subtype /Type1 /BaseFont /Custom /Encoding << /Type /Encoding /Differences [1 /C /a /f /eacute /space /fi /n /uni00E7 /e /o.sc] >> >>",
+				"BT /F1 12 Tf 72 720 Td <01020304050302080A07> Tj (\\005\\006\\007\\011) Tj ET",
+			), ""),
+			want:      []string{"Café façon fine"},
+			wantPages: []int{1},
+		},
+		{
+			name: "composite font with a ToUnicode map",
+			pdf: func() []byte {
+				objects := pdfPages("<< /Type /Font /Subtype /Type0 /BaseFont /Noto /Encoding /Identity-H /DescendantFonts [7 0 R] /ToUnicode 8 0 R >>",
+					"BT /F1 11 Tf 72 720 Td <00010002000300030005000600050007> Tj ET")
+				cmap := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+					"1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+					"2 beginbfchar <0001> <0048> <0002> <0065> endbfchar\n" +
+					"3 beginbfrange <0003> <0004> <006C> <0005> <0006> [<006F> <00200077>] <0007> <0007> <00720064> endbfrange\n" +
+					"endcmap CMapName currentdict /CMap defineresource pop end end"
+				return buildPDF(append(objects,
+					"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /Noto /DW 600 /W [1 [700 500] 3 4 250] >>",
+					pdfStreamObject("", cmap, true),
+				), "")
+			}(),
+			want:      []string{"Hello word"},
+			wantPages: []int{1},
+		},
+		{
+			name: "objects in an object stream",
+			pdf: func() []byte {
+				catalog := "<< /Type /Catalog /Pages 2 0 R >>"
+				header := fmt.Sprintf("1 0 2 %d ", len(catalog)+1)
+				body := header + catalog + " << /Type /Pages /Kids [4 0 R] /Count 1 >>"
+				return buildPDF([]string{
+					"", "", // The catalog and page tree, held by object 6
+					"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+					"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 3 0 R >> >> /Contents 5 0 R >>",
+					pdfStreamObject("", "BT /F1 12 Tf 72 720 Td (Compressed objects) Tj ET", false),
+					pdfStreamObject(fmt.Sprintf("/Type /ObjStm /N 2 /First %d", len(header)), body, true),
+				}, "")
+			}(),
+			want:      []string{"Compressed objects"},
+			wantPages: []int{1},
+		},
+		{
+			name: "form and inline image",
+			pdf: func() []byte {
+				objects := pdfPages(pdfHelvetica, "BI /W 2 /H 1 /BPC 8 /CS /G ID \x00EI\xff EI BT /F1 12 Tf 72 720 Td (Before) Tj ET /Fm1 Do")
+				objects[1] = strings.Replace(objects[1], "/Im1 4 0 R", "/Im1 4 0 R /Fm1 7 0 R", 1)
+				return buildPDF(append(objects, pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 612 792] /Matrix [1 0 0 1 0 -14]",
+					"BT /F1 12 Tf 72 720 Td (After) Tj ET", true)), "")
+			}(),
+			want:      []string{"Before\nAfter"},
+			wantPages: []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := pdfExtractor{}.Extract(tt.pdf)
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			var got []string
+			var pages []int
+			for _, part := range parts {
+				got = append(got, part.Text)
+				pages = append(pages, part.Page)
+				if part.Metadata["title"] != tt.wantTitle {
+					t.Errorf("title = %q, want %q", part.Metadata["title"], tt.wantTitle)
+				}
+			}
+			if strings.Join(got, "\f") != strings.Join(tt.want, "\f") {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+			if fmt.Sprint(pages) != fmt.Sprint(tt.wantPages) {
+				t.Errorf("pages = %v, want %v", pages, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestPDFExtractor_Skipped(t *testing.T) {
+	tests := []struct {
+		name       string
+		pdf        []byte
+		wantReason string
+	}{
+		{
+			name:       "encrypted",
+			pdf:        buildPDF(pdfPages(pdfHelvetica, "BT /F1 12 Tf (Hidden) Tj ET"), "/Encrypt << /Filter /Standard /V 2 /R 3 >>"),
+			wantReason: SkipEncrypted,
+		},
+		{
+			name:       "scanned pages",
+			pdf:        buildPDF(pdfPages(pdfHelvetica, "q 612 0 0 792 0 0 cm /Im1 Do Q", "q 612 0 0 792 0 0 cm /Im1 Do Q"), ""),
+			wantReason: SkipImageOnly,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pdfExtractor{}.Extract(tt.pdf)
+			var skip *skipError
+			if !errors.As(err, &skip) || skip.reason != tt.wantReason {
+				t.Errorf("Extract() error = %v, want skipped as %s", err, tt.wantReason)
+			}
+		})
+	}
+
+	if _, err := (pdfExtractor{}).Extract([]byte("plain text")); err == nil {
+		t.Error("Extract() of a file that is not a PDF should fail")
+	}
+}
+
+func FuzzPDFExtractor(f *testing.F) {
+	f.Add(buildPDF(pdfPages(pdfHelvetica,
+		"BT /F1 12 Tf 14 TL 72 720 Td (Quarterly report) Tj T* (for the \\(first\\) quarter) Tj ET",
+		"BT /F1 12 Tf 72 720 Td [(W) 80 (afer) -300 (ingests)] TJ ( PDFs) Tj ET",
+	), "/Info << /Title (Q1 Report) >>"))
+	f.Add(buildPDF(pdfPages(pdfHelvetica, "BT /F1 12 Tf (Hidden) Tj ET"), "/Encrypt << /Filter /Standard /V 2 /R 3 >>"))
+	f.Add(buildPDF(pdfPages(pdfHelvetica, "q 612 0 0 792 0 0 cm /Im1 Do Q"), ""))
+	f.Add([]byte("%PDF-1.7\n1 0 obj\n<< /Title (\\"))
+
+	// A damaged file fails or is skipped, but never panics
+	f.Fuzz(func(t *testing.T, data []byte) {
+		pdfExtractor{}.Extract(data)
+	})
+}
+
+func TestGlyphText(t *testing.T) {
+	tests := map[string]string{
+		"a":           "a",
+		"quoteright":  "’",
+		"Aacute":      "Á",
+		"scaron":      "š",
+		"uni20AC":     "€",
+		"uni00660069": "fi",
+		"u1F600":      "😀",
+		"f_f_i":       "ffi",
+		"a.sc":        "a",
+		"g42":         "",
+	}
+	for name, want := range tests {
+		if got := glyphText(name); got != want {
+			t.Errorf("glyphText(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
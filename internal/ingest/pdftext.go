@@ -0,0 +1,714 @@
+package ingest
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+)
+
+// pdfMatrix is a PDF transformation matrix [a b c d e f]
+type pdfMatrix [6]float64
+
+// pdfIdentity is the identity matrix
+var pdfIdentity = pdfMatrix{1, 0, 0, 1, 0, 0}
+
+// mul returns the product m × n, which applies m and then n
+func (m pdfMatrix) mul(n pdfMatrix) pdfMatrix {
+	return pdfMatrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// pdfGState is the part of the graphics state that places text
+type pdfGState struct {
+	ctm         pdfMatrix
+	font        *pdfFont
+	size        float64 // Font size
+	charSpacing float64
+	wordSpacing float64
+	scale       float64 // Horizontal scaling, 1 for none
+	leading     float64
+	rise        float64
+}
+
+// pdfInlineImageEndRE finds the end of the data of an inline image
+var pdfInlineImageEndRE = regexp.MustCompile(`\sEI(?:\s|$)`)
+
+// pdfContent runs the content streams of a page, writing the text they show.
+// Lines break where text moves down the page, paragraphs where it moves down
+// by more than a line, and words are spaced where text skips ahead.
+type pdfContent struct {
+	f      *pdfFile
+	out    strings.Builder
+	images bool // The page draws an image
+
+	gs      pdfGState
+	stack   []pdfGState
+	tm, tlm pdfMatrix // Text matrix and text line matrix
+
+	shown      bool    // Text has been written
+	endX, endY float64 // User space position just past the text last shown
+	lastSize   float64 // User space font size of the text last shown
+}
+
+// pageText returns the text of a page and whether it draws images
+func (f *pdfFile) pageText(page pdfPage) (string, bool) {
+	c := &pdfContent{f: f, gs: pdfGState{ctm: pdfIdentity, scale: 1}}
+	c.run(f.contents(page.dict), page.resources, 0)
+	return cleanLines(c.out.String()), c.images
+}
+
+// run interprets a content stream
+func (c *pdfContent) run(data []byte, resources pdfDict, depth int) {
+	l := &pdfLexer{src: data}
+	var operands []any
+	for l.pos < len(data) {
+		obj := l.object(0)
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+		c.operator(string(op), operands, resources, depth, l)
+		operands = operands[:0]
+	}
+}
+
+// operator applies a content stream operator to its operands
+func (c *pdfContent) operator(op string, operands []any, resources pdfDict, depth int, l *pdfLexer) {
+	n := make([]float64, 0, len(operands))
+	for _, operand := range operands {
+		if v, ok := operand.(float64); ok {
+			n = append(n, v)
+		}
+	}
+
+	switch op {
+	case "q":
+		c.stack = append(c.stack, c.gs)
+	case "Q":
+		if len(c.stack) > 0 {
+			c.gs = c.stack[len(c.stack)-1]
+			c.stack = c.stack[:len(c.stack)-1]
+		}
+	case "cm":
+		if len(n) == 6 {
+			c.gs.ctm = pdfMatrix(n).mul(c.gs.ctm)
+		}
+	case "BT":
+		c.tm, c.tlm = pdfIdentity, pdfIdentity
+	case "Tf":
+		if len(operands) == 2 && len(n) == 1 {
+			name, _ := operands[0].(pdfName)
+			c.gs.font = c.f.font(c.f.dict(resources["Font"])[name])
+			c.gs.size = n[0]
+		}
+	case "Tc", "Tw", "Tz", "TL", "Ts":
+		if len(n) == 1 {
+			switch op {
+			case "Tc":
+				c.gs.charSpacing = n[0]
+			case "Tw":
+				c.gs.wordSpacing = n[0]
+			case "Tz":
+				c.gs.scale = n[0] / 100
+			case "TL":
+				c.gs.leading = n[0]
+			case "Ts":
+				c.gs.rise = n[0]
+			}
+		}
+	case "Td", "TD":
+		if len(n) == 2 {
+			if op == "TD" {
+				c.gs.leading = -n[1]
+			}
+			c.nextLine(n[0], n[1])
+		}
+	case "Tm":
+		if len(n) == 6 {
+			c.tm, c.tlm = pdfMatrix(n), pdfMatrix(n)
+		}
+	case "T*":
+		c.nextLine(0, -c.gs.leading)
+	case "Tj", "'", "\"":
+		if op != "Tj" {
+			if op == "\"" && len(n) >= 2 {
+				c.gs.wordSpacing, c.gs.charSpacing = n[0], n[1]
+			}
+			c.nextLine(0, -c.gs.leading)
+		}
+		if len(operands) > 0 {
+			if s, ok := operands[len(operands)-1].(pdfString); ok {
+				c.show(s)
+			}
+		}
+	case "TJ":
+		if len(operands) == 1 {
+			array, _ := operands[0].(pdfArray)
+			for _, item := range array {
+				switch item := item.(type) {
+				case pdfString:
+					c.show(item)
+				case float64:
+					c.advance(-item / 1000 * c.gs.size * c.gs.scale)
+				}
+			}
+		}
+	case "Do":
+		if len(operands) == 1 {
+			name, _ := operands[0].(pdfName)
+			c.xObject(c.f.resolve(c.f.dict(resources["XObject"])[name]), resources, depth)
+		}
+	case "BI":
+		c.images = true
+		// The image data ends at "EI" after the "ID" that starts it
+		for l.pos < len(l.src) {
+			if l.object(0) == pdfKeyword("ID") {
+				break
+			}
+		}
+		if m := pdfInlineImageEndRE.FindIndex(l.src[l.pos:]); m != nil {
+			l.pos += m[1]
+		} else {
+			l.pos = len(l.src)
+		}
+	}
+}
+
+// xObject draws an external object: images are noted and forms are run
+func (c *pdfContent) xObject(obj any, resources pdfDict, depth int) {
+	stream, ok := obj.(*pdfStream)
+	if !ok {
+		return
+	}
+	switch stream.dict["Subtype"] {
+	case pdfName("Image"):
+		c.images = true
+	case pdfName("Form"):
+		if depth >= pdfMaxDepth {
+			return
+		}
+		data, err := c.f.decode(stream)
+		if err != nil && len(data) == 0 {
+			return
+		}
+		if own := c.f.dict(stream.dict["Resources"]); own != nil {
+			resources = own
+		}
+		saved := c.gs
+		if m := c.f.array(stream.dict["Matrix"]); len(m) == 6 {
+			var matrix pdfMatrix
+			for i, v := range m {
+				matrix[i], _ = c.f.resolve(v).(float64)
+			}
+			c.gs.ctm = matrix.mul(c.gs.ctm)
+		}
+		c.run(data, resources, depth+1)
+		c.gs = saved
+	}
+}
+
+// nextLine moves to the start of the next line, offset from the start of
+// the current one
+func (c *pdfContent) nextLine(tx, ty float64) {
+	c.tlm = pdfMatrix{1, 0, 0, 1, tx, ty}.mul(c.tlm)
+	c.tm = c.tlm
+}
+
+// advance moves the text position tx along the line, in text space
+func (c *pdfContent) advance(tx float64) {
+	c.tm = pdfMatrix{1, 0, 0, 1, tx, 0}.mul(c.tm)
+}
+
+// position returns the user space position of the text position and the
+// user space font size
+func (c *pdfContent) position() (x, y, size float64) {
+	trm := pdfMatrix{c.gs.size * c.gs.scale, 0, 0, c.gs.size, 0, c.gs.rise}.mul(c.tm).mul(c.gs.ctm)
+	return trm[4], trm[5], math.Hypot(trm[2], trm[3])
+}
+
+// show writes the text of a string, separated from the text before it by
+// the line break or space its position calls for, and moves past it
+func (c *pdfContent) show(s pdfString) {
+	font := c.gs.font
+	if font == nil {
+		font = c.f.font(nil)
+	}
+	x, y, size := c.position()
+
+	var text strings.Builder
+	font.each(s, func(code, n int, glyph string, width float64) {
+		text.WriteString(glyph)
+		tx := width/1000*c.gs.size + c.gs.charSpacing
+		if n == 1 && code == ' ' {
+			tx += c.gs.wordSpacing
+		}
+		c.advance(tx * c.gs.scale)
+	})
+	if text.Len() == 0 {
+		return
+	}
+
+	if c.shown {
+		height := max(size, c.lastSize)
+		switch dy := math.Abs(y - c.endY); {
+		case dy > 1.7*height:
+			c.out.WriteString("\n\n")
+		case dy > 0.6*height:
+			c.out.WriteString("\n")
+		case x-c.endX > 0.2*size && !strings.HasSuffix(c.out.String(), " ") && !strings.HasPrefix(text.String(), " "):
+			c.out.WriteString(" ")
+		}
+	}
+	c.out.WriteString(text.String())
+	c.shown = true
+	c.endX, c.endY, _ = c.position()
+	c.lastSize = size
+}
+
+// pdfFont decodes the strings shown in a font into text and glyph widths
+type pdfFont struct {
+	cmap     *pdfCMap        // ToUnicode map, when the font has one
+	codeLen  int             // Bytes per code when no ToUnicode map tells
+	encoding *[256]string    // Text of each code of a simple font
+	widths   map[int]float64 // Glyph widths by code, in thousandths of an em
+	missing  float64         // Width of glyphs not listed
+}
+
+// font loads a font dictionary, caching fonts loaded by reference. A font
+// that is not found decodes strings with the standard encoding.
+func (f *pdfFile) font(obj any) *pdfFont {
+	ref, isRef := obj.(pdfRef)
+	if font, ok := f.fonts[ref]; isRef && ok {
+		return font
+	}
+
+	dict := f.dict(obj)
+	font := &pdfFont{codeLen: 1, widths: map[int]float64{}, missing: 500}
+	if stream, ok := f.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, _ := f.decode(stream); len(data) > 0 {
+			font.cmap = parseCMap(data)
+		}
+	}
+
+	if dict["Subtype"] == pdfName("Type0") {
+		// Composite fonts use 2-byte codes unless their CMap says otherwise
+		font.codeLen, font.missing = 2, 1000
+		if descendants := f.array(dict["DescendantFonts"]); len(descendants) > 0 {
+			cid := f.dict(descendants[0])
+			if dw, ok := f.resolve(cid["DW"]).(float64); ok {
+				font.missing = dw
+			}
+			f.cidWidths(font, f.array(cid["W"]))
+		}
+	} else {
+		font.encoding = f.simpleEncoding(dict)
+		scale := 1.0
+		if m := f.array(dict["FontMatrix"]); len(m) == 6 {
+			if a, ok := f.resolve(m[0]).(float64); ok {
+				scale = a * 1000 // Type 3 glyph space
+			}
+		}
+		first, _ := pdfInt(f.resolve(dict["FirstChar"]))
+		for i, w := range f.array(dict["Widths"]) {
+			if w, ok := f.resolve(w).(float64); ok {
+				font.widths[first+i] = w * scale
+			}
+		}
+		if mw, ok := f.resolve(f.dict(dict["FontDescriptor"])["MissingWidth"]).(float64); ok && mw > 0 {
+			font.missing = mw * scale
+		}
+	}
+
+	if isRef {
+		if f.fonts == nil {
+			f.fonts = map[pdfRef]*pdfFont{}
+		}
+		f.fonts[ref] = font
+	}
+	return font
+}
+
+// cidWidths reads the /W array of a CID font, which lists widths as
+// "c [w1 w2 ...]" for consecutive codes or "cfirst clast w" for a range
+func (f *pdfFile) cidWidths(font *pdfFont, w pdfArray) {
+	for i := 0; i+1 < len(w); {
+		first, ok := pdfInt(f.resolve(w[i]))
+		if !ok {
+			return
+		}
+		if widths, ok := f.resolve(w[i+1]).(pdfArray); ok {
+			for j, width := range widths {
+				if width, ok := f.resolve(width).(float64); ok {
+					font.widths[first+j] = width
+				}
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(w) {
+			return
+		}
+		last, _ := pdfInt(f.resolve(w[i+1]))
+		width, _ := f.resolve(w[i+2]).(float64)
+		for code := first; code <= last && code-first < 0x10000; code++ {
+			font.widths[code] = width
+		}
+		i += 3
+	}
+}
+
+// simpleEncoding returns the text of each code of a simple font, from its
+// base encoding with its /Differences applied
+func (f *pdfFile) simpleEncoding(dict pdfDict) *[256]string {
+	var base any
+	var differences pdfArray
+	switch enc := f.resolve(dict["Encoding"]).(type) {
+	case pdfName:
+		base = enc
+	case pdfDict:
+		base = f.resolve(enc["BaseEncoding"])
+		differences = f.array(enc["Differences"])
+	}
+
+	var table [256]string
+	switch base {
+	case pdfName("WinAnsiEncoding"):
+		table = pdfWinAnsiEncoding
+	case pdfName("MacRomanEncoding"):
+		table = pdfMacRomanEncoding
+	default:
+		table = pdfStandardEncoding
+	}
+	if differences == nil {
+		return &table
+	}
+
+	code := 0
+	for _, item := range differences {
+		switch item := f.resolve(item).(type) {
+		case float64:
+			code = int(item)
+		case pdfName:
+			if code >= 0 && code < 256 {
+				table[code] = glyphText(string(item))
+			}
+			code++
+		}
+	}
+	return &table
+}
+
+// each calls fn with every code of s, its length in bytes, its text and its
+// width
+func (font *pdfFont) each(s pdfString, fn func(code, n int, text string, width float64)) {
+	for i := 0; i < len(s); {
+		n := 0
+		if font.cmap != nil {
+			n = font.cmap.codeLen(s[i:])
+		}
+		if n == 0 {
+			n = font.codeLen
+		}
+		n = min(n, len(s)-i)
+
+		code := 0
+		for _, b := range []byte(s[i : i+n]) {
+			code = code<<8 | int(b)
+		}
+		i += n
+
+		text, ok := "", false
+		if font.cmap != nil {
+			text, ok = font.cmap.lookup(code, n)
+		}
+		if !ok && font.encoding != nil && code < 256 {
+			text = font.encoding[code]
+		}
+		width, ok := font.widths[code]
+		if !ok {
+			width = font.missing
+		}
+		fn(code, n, cleanGlyphText(text), width)
+	}
+}
+
+// cleanGlyphText drops the control characters and replacement characters
+// fonts map some glyphs to
+func cleanGlyphText(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7F || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// pdfCMap is a ToUnicode CMap, which maps the codes of a font to text
+type pdfCMap struct {
+	spaces [][2][]byte // Codespace ranges, low and high codes of equal length
+	chars  map[[2]int]string
+	ranges []pdfCMapRange
+}
+
+// pdfCMapRange maps a range of codes to consecutive text, or to the text of
+// each code listed
+type pdfCMapRange struct {
+	lo, hi, n int
+	text      string
+	texts     []string
+}
+
+// parseCMap reads the codespace ranges and character mappings of a CMap
+func parseCMap(data []byte) *pdfCMap {
+	cmap := &pdfCMap{chars: map[[2]int]string{}}
+	l := &pdfLexer{src: data}
+	var operands []any
+	for l.pos < len(data) {
+		obj := l.object(0)
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 && len(lo) == len(hi) && len(lo) > 0 {
+					cmap.spaces = append(cmap.spaces, [2][]byte{[]byte(lo), []byte(hi)})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok := operands[i].(pdfString)
+				if !ok {
+					continue
+				}
+				cmap.chars[[2]int{cmapCode(src), len(src)}] = cmapText(operands[i+1])
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				r := pdfCMapRange{lo: cmapCode(lo), hi: cmapCode(hi), n: len(lo)}
+				if dsts, ok := operands[i+2].(pdfArray); ok {
+					for _, dst := range dsts {
+						r.texts = append(r.texts, cmapText(dst))
+					}
+				} else {
+					r.text = cmapText(operands[i+2])
+				}
+				cmap.ranges = append(cmap.ranges, r)
+			}
+		}
+		operands = operands[:0]
+	}
+	return cmap
+}
+
+// cmapCode returns the value of a code given as bytes
+func cmapCode(s pdfString) int {
+	code := 0
+	for i := 0; i < len(s) && i < 4; i++ {
+		code = code<<8 | int(s[i])
+	}
+	return code
+}
+
+// cmapText decodes the destination of a mapping: UTF-16 text, or a glyph name
+func cmapText(dst any) string {
+	switch dst := dst.(type) {
+	case pdfString:
+		return decodeUTF16BE([]byte(dst))
+	case pdfName:
+		return glyphText(string(dst))
+	}
+	return ""
+}
+
+// codeLen returns the length of the code s starts with, by the codespace
+// ranges, or 0 when none matches
+func (cmap *pdfCMap) codeLen(s pdfString) int {
+	for _, space := range cmap.spaces {
+		lo, hi := space[0], space[1]
+		if len(lo) > len(s) {
+			continue
+		}
+		match := true
+		for k := range lo {
+			if s[k] < lo[k] || s[k] > hi[k] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return len(lo)
+		}
+	}
+	return 0
+}
+
+// lookup returns the text of a code of n bytes
+func (cmap *pdfCMap) lookup(code, n int) (string, bool) {
+	if text, ok := cmap.chars[[2]int{code, n}]; ok {
+		return text, true
+	}
+	for _, r := range cmap.ranges {
+		if r.n != n || code < r.lo || code > r.hi {
+			continue
+		}
+		if r.texts != nil {
+			if code-r.lo < len(r.texts) {
+				return r.texts[code-r.lo], true
+			}
+			return "", false
+		}
+		// Consecutive codes map to text whose last character increments
+		last, size := utf8.DecodeLastRuneInString(r.text)
+		if size == 0 {
+			return "", false
+		}
+		return r.text[:len(r.text)-size] + string(last+rune(code-r.lo)), true
+	}
+	return "", false
+}
+
+// Base encodings of simple fonts
+var (
+	pdfWinAnsiEncoding  = charmapEncoding(charmap.Windows1252)
+	pdfMacRomanEncoding = charmapEncoding(charmap.Macintosh)
+	pdfStandardEncoding = standardEncoding()
+)
+
+// charmapEncoding returns the printable characters of a single-byte charset
+func charmapEncoding(cm *charmap.Charmap) [256]string {
+	var table [256]string
+	for code := 32; code < 256; code++ {
+		if r := cm.DecodeByte(byte(code)); r != utf8.RuneError && r != 0x7F {
+			table[code] = string(r)
+		}
+	}
+	return table
+}
+
+// standardEncoding returns Adobe's StandardEncoding: ASCII with curly
+// quotes, and ligatures and typographic marks in its upper half
+func standardEncoding() [256]string {
+	var table [256]string
+	for code := 32; code < 127; code++ {
+		table[code] = string(rune(code))
+	}
+	table['\''], table['`'] = "’", "‘"
+	upper := map[int]string{
+		0xA1: "¡", 0xA2: "¢", 0xA3: "£", 0xA4: "⁄", 0xA5: "¥", 0xA6: "ƒ", 0xA7: "§",
+		0xA8: "¤", 0xA9: "'", 0xAA: "“", 0xAB: "«", 0xAC: "‹", 0xAD: "›", 0xAE: "fi",
+		0xAF: "fl", 0xB1: "–", 0xB2: "†", 0xB3: "‡", 0xB4: "·", 0xB6: "¶", 0xB7: "•",
+		0xB8: "‚", 0xB9: "„", 0xBA: "”", 0xBB: "»", 0xBC: "…", 0xBD: "‰", 0xBF: "¿",
+		0xD0: "—", 0xE1: "Æ", 0xE3: "ª", 0xE8: "Ł", 0xE9: "Ø", 0xEA: "Œ", 0xEB: "º",
+		0xF1: "æ", 0xF5: "ı", 0xF8: "ł", 0xF9: "ø", 0xFA: "œ", 0xFB: "ß",
+	}
+	for code, text := range upper {
+		table[code] = text
+	}
+	return table
+}
+
+// pdfGlyphNames maps the glyph names of the Adobe Glyph List that are not a
+// single character or an accented letter to their text
+var pdfGlyphNames = map[string]string{
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#", "dollar": "$",
+	"percent": "%", "ampersand": "&", "quotesingle": "'", "parenleft": "(",
+	"parenright": ")", "asterisk": "*", "plus": "+", "comma": ",", "hyphen": "-",
+	"period": ".", "slash": "/", "zero": "0", "one": "1", "two": "2", "three": "3",
+	"four": "4", "five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+	"colon": ":", "semicolon": ";", "less": "<", "equal": "=", "greater": ">",
+	"question": "?", "at": "@", "bracketleft": "[", "backslash": "\\",
+	"bracketright": "]", "asciicircum": "^", "underscore": "_", "grave": "`",
+	"braceleft": "{", "bar": "|", "braceright": "}", "asciitilde": "~",
+	"quoteleft": "‘", "quoteright": "’", "quotedblleft": "“", "quotedblright": "”",
+	"quotesinglbase": "‚", "quotedblbase": "„", "guillemotleft": "«",
+	"guillemotright": "»", "guilsinglleft": "‹", "guilsinglright": "›",
+	"endash": "–", "emdash": "—", "bullet": "•", "ellipsis": "…", "periodcentered": "·",
+	"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl",
+	"dagger": "†", "daggerdbl": "‡", "perthousand": "‰", "degree": "°", "minus": "−",
+	"multiply": "×", "divide": "÷", "plusminus": "±", "copyright": "©",
+	"registered": "®", "trademark": "™", "section": "§", "paragraph": "¶",
+	"exclamdown": "¡", "questiondown": "¿", "cent": "¢", "sterling": "£",
+	"yen": "¥", "Euro": "€", "currency": "¤", "florin": "ƒ", "fraction": "⁄",
+	"onehalf": "½", "onequarter": "¼", "threequarters": "¾", "mu": "µ",
+	"ordfeminine": "ª", "ordmasculine": "º", "brokenbar": "¦", "logicalnot": "¬",
+	"nbspace": " ", "germandbls": "ß", "ae": "æ", "AE": "Æ", "oe": "œ", "OE": "Œ",
+	"oslash": "ø", "Oslash": "Ø", "dotlessi": "ı", "lslash": "ł", "Lslash": "Ł",
+	"eth": "ð", "Eth": "Ð", "thorn": "þ", "Thorn": "Þ",
+}
+
+// pdfAccents maps the accent suffixes of glyph names such as "eacute" to
+// combining marks
+var pdfAccents = []struct{ name, mark string }{
+	{"acute", "\u0301"}, {"grave", "\u0300"}, {"circumflex", "\u0302"},
+	{"dieresis", "\u0308"}, {"tilde", "\u0303"}, {"ring", "\u030A"},
+	{"cedilla", "\u0327"}, {"caron", "\u030C"}, {"macron", "\u0304"},
+	{"breve", "\u0306"}, {"ogonek", "\u0328"}, {"dotaccent", "\u0307"},
+	{"hungarumlaut", "\u030B"},
+}
+
+// glyphText returns the text of a glyph name, following the Adobe Glyph
+// List conventions, or "" for names that carry no text, such as "g12"
+func glyphText(name string) string {
+	if base, _, ok := strings.Cut(name, "."); ok {
+		name = base // Variants such as "a.sc"
+	}
+	if len(name) == 1 {
+		return name
+	}
+	if text, ok := pdfGlyphNames[name]; ok {
+		return text
+	}
+	if strings.Contains(name, "_") {
+		// Ligatures such as "f_f_i"
+		var text strings.Builder
+		for _, part := range strings.Split(name, "_") {
+			text.WriteString(glyphText(part))
+		}
+		return text.String()
+	}
+	if hexDigits, ok := strings.CutPrefix(name, "uni"); ok && len(hexDigits) >= 4 && len(hexDigits)%4 == 0 {
+		var units []byte
+		for i := 0; i < len(hexDigits); i += 4 {
+			v, err := strconv.ParseUint(hexDigits[i:i+4], 16, 16)
+			if err != nil {
+				return ""
+			}
+			units = append(units, byte(v>>8), byte(v))
+		}
+		return decodeUTF16BE(units)
+	}
+	if hexDigits, ok := strings.CutPrefix(name, "u"); ok && len(hexDigits) >= 4 && len(hexDigits) <= 6 {
+		if v, err := strconv.ParseUint(hexDigits, 16, 32); err == nil && utf8.ValidRune(rune(v)) {
+			return string(rune(v))
+		}
+	}
+	for _, accent := range pdfAccents {
+		if letter, ok := strings.CutSuffix(name, accent.name); ok && len(letter) == 1 {
+			return norm.NFC.String(letter + accent.mark)
+		}
+	}
+	return ""
+}
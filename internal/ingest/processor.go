@@ -2,43 +2,81 @@ package ingest
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log/slog"
+	"maps"
+	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
 
 	"wafer/internal/config"
 )
 
 // ProcessorStats holds statistics about the processing run
 type ProcessorStats struct {
-	FilesProcessed int
-	FilesSkipped   int
-	ChunksCreated  int
-	TotalErrors    int
-	StartTime      time.Time
-	EndTime        time.Time
+	FilesProcessed  int
+	FilesSkipped    int
+	SkipReasons     map[string]int // Files skipped without error per reason, such as "encrypted"
+	ChunksCreated   int
+	ParentsCreated  int
+	LinesStripped   int            // Boilerplate lines removed before chunking
+	Transcoded      int            // Files decoded to UTF-8 from another encoding
+	InvalidUTF8     int            // Files with invalid UTF-8 bytes replaced
+	Duplicates      int            // Chunks that nearly duplicate an earlier chunk
+	Languages       map[string]int // Text chunks per detected language, "und" when undetermined
+	LanguageSkipped int            // Text chunks skipped for their language
+	Redactions      map[string]int // Personal data found per placeholder type
+	RedactedDropped int            // Chunks dropped for holding personal data
+	Secrets         map[string]int // Secrets found per type
+	SecretFiles     int            // Files skipped for holding a secret
+	SecretChunks    int            // Chunks skipped for holding a secret
+	TotalErrors     int
+	StartTime       time.Time
+	EndTime         time.Time
 }
 
 // Processor orchestrates the entire ingestion process
 type Processor struct {
-	config   *config.Config
-	chunker  *Chunker
-	embedder *Embedder
-	writer   *Writer
-	stats    ProcessorStats
+	config    *config.Config
+	chunker   *Chunker
+	hierarchy *Hierarchy         // Set when parent chunks are enabled
+	header    *HeaderTemplate    // Set when chunks are embedded with a contextual header
+	dedup     *Deduplicator      // Set when near-duplicate chunks are detected
+	redactor  *Redactor          // Set when personal data is redacted
+	secrets   *Redactor          // Set when credentials are scanned for
+	report    *SecretReport      // Receives the secrets found
+	formats   *extractorRegistry // Formats of the documents ingested
+	embedder  *Embedder
+	writer    *Writer
+	stats     ProcessorStats
 }
 
 // NewProcessor creates a new processor with the given configuration
 func NewProcessor(cfg *config.Config) *Processor {
-	return &Processor{
+	p := &Processor{
 		config:   cfg,
-		chunker:  NewChunker(cfg.ChunkSize),
+		chunker:  NewChunker(cfg),
 		embedder: NewEmbedder(cfg.Model),
-		stats:    ProcessorStats{StartTime: time.Now()},
+		stats:    ProcessorStats{StartTime: time.Now(), Languages: map[string]int{}, Redactions: map[string]int{}, Secrets: map[string]int{}, SkipReasons: map[string]int{}},
+	}
+	p.chunker.SetEmbedder(p.embedder)
+	if cfg.ParentSize > 0 {
+		p.hierarchy = NewHierarchy(cfg, p.chunker)
+		p.hierarchy.SetEmbedder(p.embedder)
 	}
+	if cfg.Dedup != "" {
+		p.dedup = NewDeduplicator(cfg.DedupMethod, cfg.DedupThreshold)
+	}
+	return p
 }
 
 // Process runs the complete ingestion workflow
@@ -49,7 +87,12 @@ func (p *Processor) Process() error {
 		"directory", p.config.Directory,
 		"model", p.config.Model,
 		"output", p.config.Output,
-		"chunk_size", p.config.ChunkSize)
+		"chunk_size", p.config.ChunkSize,
+		"chunk_strategy", p.config.ChunkStrategy,
+		"chunk_overlap", p.config.ChunkOverlap,
+		"chunk_unit", p.config.ChunkUnit,
+		"normalize", strings.Join(p.chunker.Normalization(), ","),
+		"parent_size", p.config.ParentSize)
 
 	// Health check Ollama API
 	slog.Info("Checking Ollama API connectivity...")
@@ -58,6 +101,60 @@ func (p *Processor) Process() error {
 	}
 	slog.Info("Ollama API is accessible")
 
+	// Load the tokenizer used to count and size chunks in model tokens
+	if p.config.TokenizerPath != "" {
+		tokenizer, err := LoadTokenizer(p.config.TokenizerPath)
+		if err != nil {
+			return fmt.Errorf("failed to load tokenizer: %w", err)
+		}
+		p.chunker.SetTokenizer(tokenizer)
+		if p.hierarchy != nil {
+			p.hierarchy.SetTokenizer(tokenizer)
+		}
+		slog.Info("Loaded tokenizer", "path", p.config.TokenizerPath)
+	}
+
+	// Parse the contextual header rendered in front of each chunk
+	if p.config.HeaderTemplate != "" {
+		header, err := NewHeaderTemplate(p.config.HeaderTemplate)
+		if err != nil {
+			return err
+		}
+		p.header = header
+	}
+
+	// Load the redaction rules applied before text is embedded or written.
+	// Sentences embedded while chunking leave the machine too.
+	var sentences TextEmbedder = p.embedder
+	if p.config.RedactMode != "" {
+		redactor, err := NewRedactor(p.config.RedactRules)
+		if err != nil {
+			return err
+		}
+		p.redactor = redactor
+		if p.config.RedactMode != config.RedactReportOnly {
+			sentences = &redactingEmbedder{embedder: sentences, redactor: redactor}
+		}
+	}
+
+	// Open the report of secrets, which are kept out of the logs
+	if p.config.SecretPolicy != "" {
+		report, err := NewSecretReport(p.secretReportPath())
+		if err != nil {
+			return err
+		}
+		defer report.Close()
+		p.report = report
+		p.secrets = NewSecretScanner()
+
+		// Sentences are masked whatever the policy
+		sentences = &redactingEmbedder{embedder: sentences, redactor: p.secrets}
+	}
+	p.chunker.SetEmbedder(sentences)
+	if p.hierarchy != nil {
+		p.hierarchy.SetEmbedder(sentences)
+	}
+
 	// Initialize writer
 	writer, err := NewWriter(p.config.Output)
 	if err != nil {
@@ -66,27 +163,43 @@ func (p *Processor) Process() error {
 	defer writer.Close()
 	p.writer = writer
 
-	// Discover .txt files
-	txtFiles, err := p.discoverTextFiles()
+	// Discover the documents of the included formats and, optionally, source files
+	rows, err := newRowOptions(p.config)
+	if err != nil {
+		return err
+	}
+	formats, err := newExtractorRegistry(p.config.IncludeExt, rows)
+	if err != nil {
+		return err
+	}
+	p.formats = formats
+	files, err := p.discoverFiles()
 	if err != nil {
-		return fmt.Errorf("failed to discover text files: %w", err)
+		return fmt.Errorf("failed to discover files: %w", err)
 	}
 
-	if len(txtFiles) == 0 {
-		slog.Warn("No .txt files found in directory", "directory", p.config.Directory)
+	if len(files) == 0 {
+		slog.Warn("No supported files found in directory", "directory", p.config.Directory)
 		return nil
 	}
 
-	slog.Info("Found text files to process", "count", len(txtFiles))
+	slog.Info("Found files to process", "count", len(files))
 
 	// Process each file
-	for i, filePath := range txtFiles {
+	for i, file := range files {
 		slog.Info("Processing file",
-			"file", filePath,
-			"progress", fmt.Sprintf("%d/%d", i+1, len(txtFiles)))
-
-		if err := p.processFile(ctx, filePath); err != nil {
-			slog.Error("Failed to process file", "file", filePath, "error", err)
+			"file", file.path,
+			"progress", fmt.Sprintf("%d/%d", i+1, len(files)))
+
+		if err := p.processFile(ctx, file); err != nil {
+			var skip *skipError
+			if errors.As(err, &skip) {
+				slog.Warn("Skipped file", "file", file.path, "reason", skip.reason)
+				p.stats.FilesSkipped++
+				p.stats.SkipReasons[skip.reason]++
+				continue
+			}
+			slog.Error("Failed to process file", "file", file.path, "error", err)
 			p.stats.FilesSkipped++
 			p.stats.TotalErrors++
 			continue
@@ -99,12 +212,77 @@ func (p *Processor) Process() error {
 	p.stats.EndTime = time.Now()
 	p.printSummary()
 
+	// Record the run so its embeddings can be reproduced
+	if err := AppendManifest(ManifestPath(p.config.Output), p.manifest()); err != nil {
+		return fmt.Errorf("failed to write run manifest: %w", err)
+	}
+
 	return nil
 }
 
-// discoverTextFiles recursively finds all .txt files in the directory
-func (p *Processor) discoverTextFiles() ([]string, error) {
-	var txtFiles []string
+// manifest describes the settings and results of the run
+func (p *Processor) manifest() RunManifest {
+	return RunManifest{
+		StartedAt:         p.stats.StartTime.UTC().Format(time.RFC3339),
+		FinishedAt:        p.stats.EndTime.UTC().Format(time.RFC3339),
+		Directory:         p.config.Directory,
+		Model:             p.config.Model,
+		ChunkSize:         p.config.ChunkSize,
+		ChunkStrategy:     p.config.ChunkStrategy,
+		ChunkOverlap:      p.config.ChunkOverlap,
+		ChunkUnit:         p.config.ChunkUnit,
+		Tokenizer:         p.config.TokenizerPath,
+		MinChunkSize:      p.config.MinChunkSize,
+		MinChunkPolicy:    p.config.MinChunkPolicy,
+		MaxChars:          p.config.MaxChars,
+		SemanticThreshold: p.config.SemanticThreshold,
+		SemanticWindow:    p.config.SemanticWindow,
+		SemanticMinSize:   p.config.SemanticMinSize,
+		ParentSize:        p.config.ParentSize,
+		EmbedParents:      p.config.EmbedParents,
+		CodeFiles:         p.config.CodeFiles,
+		HeaderTemplate:    p.config.HeaderTemplate,
+		TextColumns:       p.config.TextColumns,
+		IDColumn:          p.config.IDColumn,
+		MetadataColumns:   p.config.MetadataColumns,
+		Normalize:         p.chunker.Normalization(),
+		StripBoilerplate:  p.config.StripBoilerplate,
+		Encoding:          p.config.Encoding,
+		IncludeExt:        p.formats.extensions(),
+		Languages:         p.config.Languages,
+		SecretPolicy:      p.config.SecretPolicy,
+		SecretReport:      p.secretReportPath(),
+		Secrets:           p.stats.Secrets,
+		SecretFiles:       p.stats.SecretFiles,
+		SecretChunks:      p.stats.SecretChunks,
+		Dedup:             p.config.Dedup,
+		DedupMethod:       p.config.DedupMethod,
+		DedupThreshold:    p.config.DedupThreshold,
+		Duplicates:        p.stats.Duplicates,
+		LanguageSkipped:   p.stats.LanguageSkipped,
+		RedactMode:        p.config.RedactMode,
+		RedactRules:       p.config.RedactRules,
+		Redactions:        p.stats.Redactions,
+		FilesProcessed:    p.stats.FilesProcessed,
+		FilesSkipped:      p.stats.FilesSkipped,
+		SkipReasons:       p.stats.SkipReasons,
+		ChunksCreated:     p.stats.ChunksCreated,
+	}
+}
+
+// sourceFile is a discovered file and its format, nil for source code
+type sourceFile struct {
+	path   string
+	format *fileFormat
+}
+
+// discoverFiles recursively finds the files of the included formats, by
+// their extension or sniffed content type, plus source files when code
+// ingestion is enabled. The files wafer writes are left out, so a run over a
+// directory holding its output does not ingest it.
+func (p *Processor) discoverFiles() ([]sourceFile, error) {
+	var files []sourceFile
+	outputs := p.outputPaths()
 
 	err := filepath.WalkDir(p.config.Directory, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -116,54 +294,388 @@ func (p *Processor) discoverTextFiles() ([]string, error) {
 		if d.IsDir() {
 			return nil
 		}
+		if abs, err := filepath.Abs(path); err == nil && outputs[abs] {
+			return nil
+		}
 
-		// Check if it's a .txt file
-		if strings.ToLower(filepath.Ext(d.Name())) == ".txt" {
-			txtFiles = append(txtFiles, path)
+		if format := p.formats.forExtension(d.Name()); format != nil {
+			files = append(files, sourceFile{path: path, format: format})
+		} else if p.config.CodeFiles && codeLanguage(d.Name()) != "" {
+			files = append(files, sourceFile{path: path})
+		} else if format, err := p.formats.sniff(path); err != nil {
+			slog.Warn("Error sniffing file type", "path", path, "error", err)
+		} else if format != nil {
+			files = append(files, sourceFile{path: path, format: format})
 		}
 
 		return nil
 	})
 
-	return txtFiles, err
+	return files, err
+}
+
+// outputPaths returns the absolute paths of the files a run writes: the
+// records, the run manifest and the secret report
+func (p *Processor) outputPaths() map[string]bool {
+	paths := map[string]bool{}
+	for _, path := range []string{p.config.Output, ManifestPath(p.config.Output), p.secretReportPath()} {
+		if path == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			paths[abs] = true
+		}
+	}
+	return paths
 }
 
-// processFile processes a single text file
-func (p *Processor) processFile(ctx context.Context, filePath string) error {
+// processFile processes a single file
+func (p *Processor) processFile(ctx context.Context, file sourceFile) error {
+	filePath := file.path
+
 	// Get relative path for output
 	relPath, err := filepath.Rel(p.config.Directory, filePath)
 	if err != nil {
 		relPath = filePath // Fallback to absolute path
 	}
 
-	// Chunk the file
-	chunks, err := p.chunker.ChunkFile(filePath)
+	extract := file.format != nil && file.format.extractor != nil
+	binary := extract && file.format.binary
+	var doc *document
+	if binary {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		doc = &document{whole: true, content: string(data)}
+	} else if doc, err = p.loadDocument(filePath, relPath, extract); err != nil {
+		return err
+	}
+
+	// A file holding a secret is skipped before any of it is embedded. A
+	// binary file is scanned once its text is extracted.
+	if p.config.SecretPolicy == config.SecretSkipFile && !binary {
+		skip, err := p.scanFileSecrets(filePath, relPath, doc)
+		if err != nil || skip {
+			return err
+		}
+	}
+
+	created := p.stats.ChunksCreated
+	switch {
+	case extract:
+		err = p.processExtracted(ctx, relPath, file.format, doc)
+	case p.hierarchy != nil:
+		err = p.processHierarchy(ctx, filePath, relPath, doc)
+	default:
+		err = p.processChunks(ctx, filePath, relPath, doc)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to chunk file: %w", err)
+		return err
 	}
 
-	if len(chunks) == 0 {
+	chunks := p.stats.ChunksCreated - created
+	if chunks == 0 {
 		slog.Warn("File produced no chunks", "file", filePath)
 		return nil
 	}
 
-	slog.Debug("File chunked", "file", filePath, "chunks", len(chunks))
+	slog.Debug("File chunked", "file", filePath, "chunks", chunks)
+	return nil
+}
+
+// processChunks chunks a plain text file, or source file, and processes
+// each chunk
+func (p *Processor) processChunks(ctx context.Context, filePath, relPath string, doc *document) error {
+	restore := func(chunk Chunk) error {
+		doc.restore(&chunk)
+		return p.emitChunk(ctx, relPath, chunk)
+	}
+
+	var err error
+	if doc.whole {
+		err = p.chunker.chunkReader(p.chunker.strategyFor(filePath), strings.NewReader(doc.content), restore)
+	} else {
+		// Stream the file so embedding starts before it has been read in full
+		err = p.chunker.StreamFile(filePath, restore)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to chunk file: %w", err)
+	}
+	return nil
+}
+
+// processExtracted extracts the text of a document and chunks each part of
+// it, numbering the chunks of all parts in sequence
+func (p *Processor) processExtracted(ctx context.Context, relPath string, format *fileFormat, doc *document) error {
+	parts, err := format.extractor.Extract([]byte(doc.content))
+	if err != nil {
+		return fmt.Errorf("failed to extract %s text: %w", format.name, err)
+	}
+	if format.binary && p.config.SecretPolicy == config.SecretSkipFile {
+		skip, err := p.scanExtractedSecrets(relPath, parts)
+		if err != nil || skip {
+			return err
+		}
+	}
+
+	parents, chunks := 0, 0
+	for _, part := range parts {
+		// Metadata is copied to every chunk of its part, so its personal
+		// data is counted once here
+		if p.redactor != nil {
+			_, found := p.redactor.RedactMetadata(part.Metadata)
+			for kind, n := range found {
+				p.stats.Redactions[kind] += n
+			}
+		}
+
+		n := 0 // Chunks of the part so far
+		// Offsets of text rewritten by the extractor locate chunks in that text
+		place := func(chunk *Chunk) {
+			if part.Verbatim {
+				doc.restore(chunk)
+			} else {
+				chunk.Encoding = doc.encoding.name
+			}
+			chunk.Metadata = part.metadataFor(chunk.StartLine, chunk.EndLine)
+			chunk.PageNumber = part.Page
+		}
+
+		if p.hierarchy != nil {
+			err = p.hierarchy.chunkExtracted(part, func(parent Chunk, children []Chunk) error {
+				place(&parent)
+				parent.Index = parents
+				parents++
+				for i := range children {
+					place(&children[i])
+					children[i].ID = part.chunkID(n)
+					children[i].Index = chunks
+					chunks++
+					n++
+				}
+				return p.processParent(ctx, relPath, parent, children)
+			})
+		} else {
+			strategy := p.chunker.textStrategy(part.Markdown)
+			err = p.chunker.chunkReader(strategy, strings.NewReader(part.Text), func(chunk Chunk) error {
+				place(&chunk)
+				chunk.ID = part.chunkID(n)
+				chunk.Index = chunks
+				chunks++
+				n++
+				return p.emitChunk(ctx, relPath, chunk)
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to chunk file: %w", err)
+		}
+	}
+	return nil
+}
+
+// emitChunk processes a chunk, naming it in the error of a failure
+func (p *Processor) emitChunk(ctx context.Context, relPath string, chunk Chunk) error {
+	if err := p.processChunk(ctx, relPath, chunk); err != nil {
+		return fmt.Errorf("failed to process chunk %d: %w", chunk.Index, err)
+	}
+	return nil
+}
+
+// document is a file prepared for chunking. UTF-8 files are streamed from
+// disk; others are read in full to be decoded, as are files whose
+// boilerplate is cut, with maps from their content back to the file's bytes.
+type document struct {
+	encoding fileEncoding
+	whole    bool        // Content holds the whole file rather than it being streamed
+	content  string      // Decoded text of the file with its boilerplate cut
+	decoded  *rewriteMap // Set when the file was decoded to UTF-8
+	cuts     *lineCuts   // Set when boilerplate lines were cut
+}
+
+// restore maps the offsets and line numbers of a chunk of the content back
+// to the file and records the file's encoding on it
+func (d *document) restore(chunk *Chunk) {
+	chunk.Encoding = d.encoding.name
+	if d.cuts != nil {
+		d.cuts.restore(chunk)
+	}
+	if d.decoded != nil {
+		chunk.StartByte = d.decoded.start(chunk.StartByte)
+		chunk.EndByte = d.decoded.end(chunk.EndByte)
+	}
+}
+
+// loadDocument detects the encoding of a file and reads it in full when it
+// must be decoded, extracted or, for plain text, have its page headers,
+// footers and numbers cut before it is chunked
+func (p *Processor) loadDocument(filePath, relPath string, whole bool) (*document, error) {
+	enc, err := detectEncoding(filePath, p.config.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	if enc.invalid > 0 {
+		slog.Warn("Replaced invalid UTF-8", "file", relPath, "bytes", enc.invalid)
+		p.stats.InvalidUTF8++
+	}
+
+	doc := &document{encoding: enc}
+	strip := p.config.StripBoilerplate && strings.ToLower(filepath.Ext(filePath)) == ".txt"
+	if enc.plain() && !strip && !whole {
+		return doc, nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	doc.whole = true
+	doc.content = string(data)
+	if !enc.plain() {
+		doc.content, doc.decoded = decodeText(data, enc)
+		if enc.name != config.EncodingUTF8 {
+			slog.Debug("Decoded file", "file", relPath, "encoding", enc.name)
+			p.stats.Transcoded++
+		}
+	}
+
+	if strip {
+		doc.content, doc.cuts = cutLines(doc.content, findBoilerplate(doc.content))
+		p.stats.LinesStripped += doc.cuts.removed()
+		slog.Info("Removed boilerplate lines", "file", relPath, "lines", doc.cuts.removed())
+	}
+	return doc, nil
+}
+
+// processHierarchy writes the parent chunks of a file, embedded or as text
+// only, each followed by the child chunks that reference it
+func (p *Processor) processHierarchy(ctx context.Context, filePath, relPath string, doc *document) error {
+	restore := func(parent Chunk, children []Chunk) error {
+		doc.restore(&parent)
+		for i := range children {
+			doc.restore(&children[i])
+		}
+		return p.processParent(ctx, relPath, parent, children)
+	}
+
+	var err error
+	if doc.whole {
+		err = p.hierarchy.chunkContent(filePath, doc.content, restore)
+	} else {
+		err = p.hierarchy.ChunkFile(filePath, restore)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to chunk file: %w", err)
+	}
+	return nil
+}
+
+// processParent writes a parent chunk, embedded or as text only, followed
+// by the child chunks cut from it
+func (p *Processor) processParent(ctx context.Context, relPath string, parent Chunk, children []Chunk) error {
+	parent.ID = uuid.New().String()
+	if parent.CodeLanguage == "" {
+		parent.Language, parent.LanguageConfidence = identifyLanguage(parent.Text)
+	}
 
-	// Process each chunk
-	for _, chunk := range chunks {
-		if err := p.processChunk(ctx, relPath, chunk); err != nil {
-			return fmt.Errorf("failed to process chunk %d: %w", chunk.Index, err)
+	// Children are cut from the parent, so a skipped parent takes them along
+	skip, err := p.scanSecrets(relPath, &parent)
+	if err != nil {
+		return err
+	}
+	if skip {
+		p.stats.SecretChunks += 1 + len(children)
+		return nil
+	}
+	if p.redact(&parent) {
+		p.stats.RedactedDropped += 1 + len(children)
+		return nil
+	}
+
+	var embedding []float64
+	if p.config.EmbedParents {
+		if err := p.contextualize(relPath, &parent); err != nil {
+			return err
 		}
-		p.stats.ChunksCreated++
+		var err error
+		embedding, err = p.embedder.GetEmbedding(ctx, parent.embeddingInput())
+		if err != nil {
+			return fmt.Errorf("failed to generate embedding for parent %d: %w", parent.Index, err)
+		}
+	}
+	if err := p.writer.WriteRecord(relPath, parent, embedding); err != nil {
+		return fmt.Errorf("failed to write parent %d: %w", parent.Index, err)
 	}
+	p.stats.ParentsCreated++
 
+	for _, child := range children {
+		child.ParentID = parent.ID
+		if err := p.emitChunk(ctx, relPath, child); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // processChunk processes a single text chunk
 func (p *Processor) processChunk(ctx context.Context, sourceFile string, chunk Chunk) error {
+	// A text chunk in a language outside the configured ones is skipped
+	if chunk.CodeLanguage == "" {
+		chunk.Language, chunk.LanguageConfidence = identifyLanguage(chunk.Text)
+		if len(p.config.Languages) > 0 && chunk.Language != "" && !slices.Contains(p.config.Languages, chunk.Language) {
+			p.stats.LanguageSkipped++
+			return nil
+		}
+		language := chunk.Language
+		if language == "" {
+			language = "und"
+		}
+		p.stats.Languages[language]++
+	}
+
+	// Secrets and personal data are handled before the text is embedded or written
+	skip, err := p.scanSecrets(sourceFile, &chunk)
+	if err != nil {
+		return err
+	}
+	if skip {
+		p.stats.SecretChunks++
+		return nil
+	}
+	if p.redact(&chunk) {
+		p.stats.RedactedDropped++
+		return nil
+	}
+
+	// A near-duplicate of an earlier chunk is skipped, linked or counted
+	var signature *dedupSignature
+	if p.dedup != nil {
+		if chunk.ID == "" {
+			chunk.ID = uuid.New().String()
+		}
+		var canonical string
+		if canonical, signature = p.dedup.Check(chunk.embeddingInput()); canonical != "" {
+			p.stats.Duplicates++
+			switch p.config.Dedup {
+			case config.DedupSkip:
+				return nil
+			case config.DedupLink:
+				chunk.DuplicateOf = canonical
+				if err := p.writer.WriteRecord(sourceFile, chunk, nil); err != nil {
+					return fmt.Errorf("failed to write record: %w", err)
+				}
+				p.stats.ChunksCreated++
+				return nil
+			}
+		}
+	}
+
+	if err := p.contextualize(sourceFile, &chunk); err != nil {
+		return err
+	}
+
 	// Generate embedding
-	embedding, err := p.embedder.GetEmbedding(ctx, chunk.Text)
+	embedding, err := p.embedder.GetEmbedding(ctx, chunk.embeddingInput())
 	if err != nil {
 		return fmt.Errorf("failed to generate embedding: %w", err)
 	}
@@ -172,10 +684,227 @@ func (p *Processor) processChunk(ctx context.Context, sourceFile string, chunk C
 	if err := p.writer.WriteRecord(sourceFile, chunk, embedding); err != nil {
 		return fmt.Errorf("failed to write record: %w", err)
 	}
+	p.stats.ChunksCreated++
+
+	// Only a written chunk becomes canonical, so duplicates never link to a
+	// chunk that failed to embed or write
+	if signature != nil {
+		p.dedup.Add(chunk.ID, signature)
+	}
+
+	return nil
+}
+
+// scanSecrets reports the secrets in a chunk and its metadata and, depending
+// on the secret policy, masks them. It reports whether the chunk must be
+// skipped. The secrets of a child chunk were reported with its parent.
+func (p *Processor) scanSecrets(sourceFile string, chunk *Chunk) (bool, error) {
+	if p.secrets == nil {
+		return false, nil
+	}
+	var findings []SecretFinding
+	for _, m := range p.secrets.find(chunk.Text) {
+		line := chunk.StartLine + strings.Count(chunk.Text[:m.start], "\n")
+		findings = append(findings, newSecretFinding(m.kind, chunk.Text[m.start:m.end], line))
+	}
+	findings = append(findings, metadataSecrets(p.secrets, chunk.Metadata, chunk.StartLine)...)
+	if len(findings) == 0 {
+		return false, nil
+	}
+
+	if chunk.ParentID == "" {
+		for _, finding := range findings {
+			finding.SourceFile = sourceFile
+			finding.ChunkIndex = &chunk.Index
+			finding.PageNumber = chunk.PageNumber
+			finding.Action = p.config.SecretPolicy
+			if err := p.recordSecret(finding); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if p.config.SecretPolicy != config.SecretMask {
+		return true, nil
+	}
+	chunk.Text, _ = p.secrets.Redact(chunk.Text)
+	chunk.CharCount = utf8.RuneCountInString(chunk.Text)
+	if chunk.EmbeddingText != "" {
+		chunk.EmbeddingText, _ = p.secrets.Redact(chunk.EmbeddingText)
+	}
+	chunk.Metadata, _ = p.secrets.RedactMetadata(chunk.Metadata)
+	return false, nil
+}
+
+// metadataSecrets returns the secrets in the values of metadata, in key
+// order, as found on the given line
+func metadataSecrets(scanner *Redactor, metadata map[string]string, line int) []SecretFinding {
+	var findings []SecretFinding
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		value := metadata[key]
+		for _, m := range scanner.find(value) {
+			finding := newSecretFinding(m.kind, value[m.start:m.end], line)
+			finding.Field = key
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// scanFileSecrets reports the secrets in a file and reports whether it holds
+// any, reading its lines from disk unless the document holds them already
+func (p *Processor) scanFileSecrets(filePath, relPath string, doc *document) (bool, error) {
+	var r io.Reader = strings.NewReader(doc.content)
+	if !doc.whole {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return false, fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+	findings, err := scanSecretLines(p.secrets, r)
+	if err != nil {
+		return false, err
+	}
+
+	// Lines of the document map back to the file like those of a chunk
+	for i := range findings {
+		at := Chunk{StartLine: findings[i].Line, EndLine: findings[i].Line}
+		doc.restore(&at)
+		findings[i].Line = at.StartLine
+	}
+	return p.skipSecretFile(relPath, findings)
+}
+
+// scanExtractedSecrets reports the secrets in the text and metadata
+// extracted from a file and reports whether it holds any. Lines are those of
+// each part, such as a page.
+func (p *Processor) scanExtractedSecrets(relPath string, parts []ExtractedText) (bool, error) {
+	var findings []SecretFinding
+	for _, part := range parts {
+		found, err := scanSecretLines(p.secrets, strings.NewReader(part.Text))
+		if err != nil {
+			return false, err
+		}
+		found = append(found, metadataSecrets(p.secrets, part.Metadata, 0)...)
+		for i := range found {
+			found[i].PageNumber = part.Page
+		}
+		findings = append(findings, found...)
+	}
+	return p.skipSecretFile(relPath, findings)
+}
+
+// skipSecretFile records the secrets found in a file, which is skipped when
+// there are any
+func (p *Processor) skipSecretFile(relPath string, findings []SecretFinding) (bool, error) {
+	if len(findings) == 0 {
+		return false, nil
+	}
+	for _, finding := range findings {
+		finding.SourceFile = relPath
+		finding.Action = config.SecretSkipFile
+		if err := p.recordSecret(finding); err != nil {
+			return false, err
+		}
+	}
+	p.stats.SecretFiles++
+	return true, nil
+}
 
+// recordSecret counts a secret and appends it to the secret report
+func (p *Processor) recordSecret(finding SecretFinding) error {
+	p.stats.Secrets[finding.Type]++
+	return p.report.Write(finding)
+}
+
+// secretReportPath returns the path of the secret report, or "" when secrets
+// are not scanned for
+func (p *Processor) secretReportPath() string {
+	if p.config.SecretPolicy == "" || p.config.SecretReport != "" {
+		return p.config.SecretReport
+	}
+	return SecretReportPath(p.config.Output)
+}
+
+// redact finds the personal data in a chunk and its metadata, records it
+// and, depending on the redact mode, masks it. It reports whether the chunk
+// must be dropped. Matches are counted once: those of a child chunk with its
+// parent, those in the overlap with the chunk before, and those of metadata
+// with the part they were extracted with.
+func (p *Processor) redact(chunk *Chunk) bool {
+	if p.redactor == nil {
+		return false
+	}
+	text, counts := p.redactor.Redact(chunk.Text)
+	metadata, found := p.redactor.RedactMetadata(chunk.Metadata)
+	for kind, n := range found {
+		if counts == nil {
+			counts = map[string]int{}
+		}
+		counts[kind] += n
+	}
+	if len(counts) == 0 {
+		return false
+	}
+	if chunk.ParentID == "" {
+		repeated := prefixBytes(chunk.Text, chunk.OverlapChars)
+		for _, m := range p.redactor.find(chunk.Text) {
+			if m.end > repeated {
+				p.stats.Redactions[m.kind]++
+			}
+		}
+	}
+	chunk.Redactions = counts
+
+	switch p.config.RedactMode {
+	case config.RedactDropChunk:
+		return true
+	case config.RedactMask:
+		chunk.Text = text
+		chunk.CharCount = utf8.RuneCountInString(text)
+		if chunk.EmbeddingText != "" {
+			chunk.EmbeddingText, _ = p.redactor.Redact(chunk.EmbeddingText)
+		}
+		chunk.Metadata = metadata
+	}
+	return false
+}
+
+// contextualize renders the header template for a chunk about to be embedded
+func (p *Processor) contextualize(sourceFile string, chunk *Chunk) error {
+	if p.header == nil {
+		return nil
+	}
+	text, err := p.header.Render(sourceFile, *chunk)
+	if err != nil {
+		return err
+	}
+	chunk.ContextText = text
 	return nil
 }
 
+// formatCounts lists counts most frequent first, such as "en=12 de=3"
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	items := make([]string, len(keys))
+	for i, key := range keys {
+		items[i] = fmt.Sprintf("%s=%d", key, counts[key])
+	}
+	return strings.Join(items, " ")
+}
+
 // printSummary prints a summary of the processing results
 func (p *Processor) printSummary() {
 	duration := p.stats.EndTime.Sub(p.stats.StartTime)
@@ -183,7 +912,20 @@ func (p *Processor) printSummary() {
 	slog.Info("Processing completed",
 		"files_processed", p.stats.FilesProcessed,
 		"files_skipped", p.stats.FilesSkipped,
+		"skip_reasons", formatCounts(p.stats.SkipReasons),
 		"chunks_created", p.stats.ChunksCreated,
+		"parents_created", p.stats.ParentsCreated,
+		"lines_stripped", p.stats.LinesStripped,
+		"files_transcoded", p.stats.Transcoded,
+		"invalid_utf8_files", p.stats.InvalidUTF8,
+		"duplicates", p.stats.Duplicates,
+		"languages", formatCounts(p.stats.Languages),
+		"language_skipped", p.stats.LanguageSkipped,
+		"redactions", formatCounts(p.stats.Redactions),
+		"redacted_dropped", p.stats.RedactedDropped,
+		"secrets_found", formatCounts(p.stats.Secrets),
+		"secret_files_skipped", p.stats.SecretFiles,
+		"secret_chunks_skipped", p.stats.SecretChunks,
 		"total_errors", p.stats.TotalErrors,
 		"duration", duration.String(),
 		"output_file", p.config.Output)
@@ -0,0 +1,960 @@
+package ingest
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"wafer/internal/config"
+)
+
+// newTestProcessor returns a processor for cfg backed by a mock Ollama server.
+// Files maps names to contents written into a fresh input directory.
+func newTestProcessor(t *testing.T, cfg config.Config, files map[string]string) *Processor {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/embeddings":
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(EmbeddingResponse{Embedding: []float64{0.1, 0.2, 0.3}}); err != nil {
+				http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			}
+		case "/api/tags":
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("OLLAMA_HOST", server.URL)
+
+	tmpDir := t.TempDir()
+	cfg.Directory = filepath.Join(tmpDir, "input")
+	cfg.Output = filepath.Join(tmpDir, "output.jsonl")
+	if cfg.Model == "" {
+		cfg.Model = "test-model"
+	}
+	if err := os.MkdirAll(cfg.Directory, 0755); err != nil {
+		t.Fatalf("Failed to create input directory: %v", err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(cfg.Directory, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	return NewProcessor(&cfg)
+}
+
+// readRecords parses the JSONL output of a processor
+func readRecords(t *testing.T, p *Processor) []VectorRecord {
+	t.Helper()
+
+	content, err := os.ReadFile(p.config.Output)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+
+	var records []VectorRecord
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var record VectorRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Failed to parse JSON: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestProcessor_ParentChunks(t *testing.T) {
+	text := strings.Repeat("Parents give the model context while children are matched. ", 30)
+
+	for _, embedParents := range []bool{false, true} {
+		p := newTestProcessor(t, config.Config{ChunkSize: 20, ParentSize: 100, EmbedParents: embedParents},
+			map[string]string{"doc.txt": text})
+		if err := p.Process(); err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+
+		parents := map[string]VectorRecord{}
+		var children []VectorRecord
+		for _, record := range readRecords(t, p) {
+			switch record.Level {
+			case ChunkParent:
+				parents[record.ID] = record
+				if embedParents != (record.Embedding != nil) {
+					t.Errorf("embed parents %v: parent embedding = %v", embedParents, record.Embedding)
+				}
+			case ChunkChild:
+				children = append(children, record)
+			default:
+				t.Errorf("record without a level: %+v", record)
+			}
+		}
+
+		if len(parents) != 3 || p.stats.ParentsCreated != 3 {
+			t.Errorf("got %d parents, %d counted, want 3", len(parents), p.stats.ParentsCreated)
+		}
+		if len(children) != 14 || p.stats.ChunksCreated != 14 {
+			t.Errorf("got %d children, %d counted, want 14", len(children), p.stats.ChunksCreated)
+		}
+		for _, child := range children {
+			parent, ok := parents[child.ParentID]
+			if !ok {
+				t.Fatalf("child %d references unknown parent %q", child.ChunkIndex, child.ParentID)
+			}
+			if child.StartByte < parent.StartByte || child.EndByte > parent.EndByte {
+				t.Errorf("child %d (%d-%d) lies outside its parent (%d-%d)",
+					child.ChunkIndex, child.StartByte, child.EndByte, parent.StartByte, parent.EndByte)
+			}
+			if child.Embedding == nil {
+				t.Errorf("child %d was not embedded", child.ChunkIndex)
+			}
+		}
+	}
+}
+
+func TestProcessor_HeaderTemplate(t *testing.T) {
+	text := "# Revenue\n\nIt increased by 20% over the quarter.\n"
+
+	p := newTestProcessor(t, config.Config{ChunkSize: 50, HeaderTemplate: `{{.SourceFile}} — {{.Section}}\n\n{{.Text}}`},
+		map[string]string{"report.md": text})
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	records := readRecords(t, p)
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	if records[0].Text != "# Revenue\n\nIt increased by 20% over the quarter." {
+		t.Errorf("text = %q, want the raw chunk", records[0].Text)
+	}
+	if want := "report.md — Revenue\n\n# Revenue\n\nIt increased by 20% over the quarter."; records[0].EmbeddedText != want {
+		t.Errorf("embedded_text = %q, want %q", records[0].EmbeddedText, want)
+	}
+}
+
+func TestProcessor_Manifest(t *testing.T) {
+	cfg := config.Config{
+		ChunkSize:       50,
+		Normalize:       []string{config.NormalizeLowercase, config.NormalizeNFKC},
+		MinChunkSize:    5,
+		MinChunkPolicy:  config.MinChunkRebalance,
+		CodeFiles:       true,
+		IDColumn:        "sku",
+		MetadataColumns: []string{"category"},
+		Dedup:           config.DedupCount,
+		DedupMethod:     config.DedupMinHash,
+		DedupThreshold:  0.8,
+	}
+	p := newTestProcessor(t, cfg, map[string]string{"doc.txt": "ＡＮ Example document."})
+
+	// Each run appends its own manifest line
+	for run := 0; run < 2; run++ {
+		if err := p.Process(); err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(ManifestPath(p.config.Output))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d manifest lines, want 2", len(lines))
+	}
+
+	var manifest RunManifest
+	if err := json.Unmarshal([]byte(lines[0]), &manifest); err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	if want := []string{config.NormalizeNFKC, config.NormalizeLowercase}; strings.Join(manifest.Normalize, ",") != strings.Join(want, ",") {
+		t.Errorf("normalize = %v, want %v", manifest.Normalize, want)
+	}
+	if manifest.Model != "test-model" || manifest.ChunkSize != 50 || manifest.ChunksCreated != 1 {
+		t.Errorf("manifest = %+v", manifest)
+	}
+	// Every setting that shapes the records is recorded
+	if manifest.MinChunkSize != 5 || manifest.MinChunkPolicy != config.MinChunkRebalance || !manifest.CodeFiles ||
+		manifest.IDColumn != "sku" || strings.Join(manifest.MetadataColumns, ",") != "category" ||
+		manifest.DedupMethod != config.DedupMinHash || manifest.DedupThreshold != 0.8 {
+		t.Errorf("manifest settings = %+v", manifest)
+	}
+
+	if records := readRecords(t, p); records[0].Text != "ＡＮ Example document." {
+		t.Errorf("text = %q, want the original chunk", records[0].Text)
+	}
+}
+
+func TestManifestPath(t *testing.T) {
+	tests := map[string]string{
+		"storage/vectors.jsonl": "storage/vectors.manifest.jsonl",
+		"out":                   "out.manifest.jsonl",
+	}
+	for output, want := range tests {
+		if got := ManifestPath(output); got != want {
+			t.Errorf("ManifestPath(%q) = %q, want %q", output, got, want)
+		}
+	}
+}
+
+func TestProcessor_StripBoilerplate(t *testing.T) {
+	text := pdfExport(5, func(page int) string { return fmt.Sprintf("Page %d\n", page) })
+
+	for _, parentSize := range []int{0, 60} {
+		p := newTestProcessor(t, config.Config{ChunkSize: 20, ParentSize: parentSize, StripBoilerplate: true},
+			map[string]string{"report.txt": text, "notes.md": text})
+		if err := p.Process(); err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+
+		if p.stats.LinesStripped != 15 {
+			t.Errorf("parent size %d: stripped %d lines, want 15", parentSize, p.stats.LinesStripped)
+		}
+		// Only plain text is stripped
+		kept := map[string]bool{}
+		for _, record := range readRecords(t, p) {
+			if strings.Contains(record.Text, "Confidential") || strings.Contains(record.Text, "ACME") {
+				kept[record.SourceFile] = true
+			}
+			source := text[record.StartByte:record.EndByte]
+			if !strings.HasPrefix(source, strings.Fields(record.Text)[0]) {
+				t.Errorf("parent size %d: chunk %d at %d-%d does not start with %q",
+					parentSize, record.ChunkIndex, record.StartByte, record.EndByte, record.Text)
+			}
+		}
+		if kept["report.txt"] || !kept["notes.md"] {
+			t.Errorf("parent size %d: boilerplate kept in %v, want only notes.md", parentSize, kept)
+		}
+	}
+}
+
+func TestProcessor_Dedup(t *testing.T) {
+	files := map[string]string{
+		"a.txt": boilerplateParagraph,
+		"b.txt": strings.Replace(boilerplateParagraph, "company", "Company", 2),
+		"c.txt": "Quarterly revenue grew by twenty percent, driven by strong demand in the northern region.",
+	}
+
+	tests := []struct {
+		mode        string
+		wantRecords int
+	}{
+		{config.DedupSkip, 2},
+		{config.DedupLink, 3},
+		{config.DedupCount, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			p := newTestProcessor(t, config.Config{ChunkSize: 200, Dedup: tt.mode}, files)
+			if err := p.Process(); err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if p.stats.Duplicates != 1 {
+				t.Errorf("counted %d duplicates, want 1", p.stats.Duplicates)
+			}
+
+			records := readRecords(t, p)
+			if len(records) != tt.wantRecords {
+				t.Fatalf("got %d records, want %d", len(records), tt.wantRecords)
+			}
+			for _, record := range records {
+				linked := record.SourceFile == "b.txt" && tt.mode == config.DedupLink
+				if linked != (record.DuplicateOf != "") {
+					t.Errorf("%s: duplicate_of = %q", record.SourceFile, record.DuplicateOf)
+				}
+				if linked != (record.Embedding == nil) {
+					t.Errorf("%s: embedding = %v", record.SourceFile, record.Embedding)
+				}
+				if linked && record.DuplicateOf != records[0].ID {
+					t.Errorf("duplicate_of = %q, want the id of a.txt %q", record.DuplicateOf, records[0].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessor_DedupUnwritten(t *testing.T) {
+	files := map[string]string{
+		"a.txt": boilerplateParagraph,
+		"b.txt": boilerplateParagraph,
+	}
+	p := newTestProcessor(t, config.Config{ChunkSize: 200, Dedup: config.DedupSkip}, files)
+
+	// The first chunk fails to embed, so its copy is the first one written
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/embeddings" {
+			if requests++; requests == 1 {
+				http.Error(w, "model not loaded", http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(EmbeddingResponse{Embedding: []float64{0.1}})
+		}
+	}))
+	t.Cleanup(server.Close)
+	p.embedder.SetBaseURL(server.URL)
+	p.embedder.retries = 0
+
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if p.stats.TotalErrors != 1 || p.stats.Duplicates != 0 {
+		t.Errorf("got %d errors and %d duplicates, want 1 and 0", p.stats.TotalErrors, p.stats.Duplicates)
+	}
+	records := readRecords(t, p)
+	if len(records) != 1 || records[0].SourceFile != "b.txt" {
+		t.Errorf("got %d records, want only the copy in b.txt", len(records))
+	}
+}
+
+func TestProcessor_Encoding(t *testing.T) {
+	text := "Le café coûte 5€. « Déjà vu », dit-il.\r\nUne deuxième ligne suit."
+	files := map[string]string{
+		"plain.txt":   text,
+		"legacy.txt":  "Le caf\xe9 co\xfbte 5\x80. \xab D\xe9j\xe0 vu \xbb, dit-il.\r\nUne deuxi\xe8me ligne suit.",
+		"utf16.md":    encodeUTF16(text, binary.LittleEndian, true),
+		"damaged.txt": strings.Replace(text, "suit", "su\xefit", 1),
+	}
+	wantEncoding := map[string]string{
+		"plain.txt":   config.EncodingUTF8,
+		"legacy.txt":  config.EncodingWindows1252,
+		"utf16.md":    config.EncodingUTF16LE,
+		"damaged.txt": config.EncodingUTF8,
+	}
+
+	for _, parentSize := range []int{0, 12} {
+		p := newTestProcessor(t, config.Config{ChunkSize: 6, ParentSize: parentSize}, files)
+		if err := p.Process(); err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		if p.stats.Transcoded != 2 || p.stats.InvalidUTF8 != 1 {
+			t.Errorf("parent size %d: transcoded %d and replaced invalid UTF-8 in %d files, want 2 and 1",
+				parentSize, p.stats.Transcoded, p.stats.InvalidUTF8)
+		}
+
+		for _, record := range readRecords(t, p) {
+			if record.DetectedEncoding != wantEncoding[record.SourceFile] {
+				t.Errorf("%s: detected_encoding = %q, want %q",
+					record.SourceFile, record.DetectedEncoding, wantEncoding[record.SourceFile])
+			}
+			if record.SourceFile == "damaged.txt" {
+				continue
+			}
+
+			// Text is UTF-8 and its offsets cover the encoded bytes
+			if !strings.Contains(strings.ReplaceAll(text, "\r\n", "\n"), record.Text) {
+				t.Errorf("%s: chunk %q is not part of the text", record.SourceFile, record.Text)
+			}
+			data := files[record.SourceFile][record.StartByte:record.EndByte]
+			enc := fileEncoding{name: record.DetectedEncoding}
+			if decoded, _ := decodeText([]byte(data), enc); strings.ReplaceAll(decoded, "\r\n", "\n") != record.Text {
+				t.Errorf("%s: bytes %d-%d decode to %q, want %q",
+					record.SourceFile, record.StartByte, record.EndByte, decoded, record.Text)
+			}
+		}
+	}
+}
+
+func TestProcessor_Languages(t *testing.T) {
+	files := map[string]string{
+		"en.txt":      "The committee will publish its report on the new housing policy next month.",
+		"de.txt":      "Der Ausschuss wird seinen Bericht über die neue Wohnungspolitik nächsten Monat veröffentlichen.",
+		"fr.txt":      "Le comité publiera son rapport sur la nouvelle politique du logement le mois prochain.",
+		"numbers.txt": "2024 2025 2026",
+		"invoice.txt": "Invoice overdue. Please pay promptly.",
+		"main.go":     "package main\n\nfunc main() {}\n",
+	}
+
+	p := newTestProcessor(t, config.Config{ChunkSize: 100, CodeFiles: true, Languages: []string{"en", "de"}}, files)
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if p.stats.LanguageSkipped != 1 {
+		t.Errorf("skipped %d chunks, want 1", p.stats.LanguageSkipped)
+	}
+	if got := formatCounts(p.stats.Languages); got != "und=2 de=1 en=1" {
+		t.Errorf("language counts = %q, want %q", got, "und=2 de=1 en=1")
+	}
+
+	// A short chunk detected with little confidence is kept as undetermined
+	// and code carries its programming language apart from the natural one
+	want := map[string]string{"en.txt": "en", "de.txt": "de", "numbers.txt": "", "invoice.txt": "", "main.go": ""}
+	seen := map[string]bool{}
+	for _, record := range readRecords(t, p) {
+		seen[record.SourceFile] = true
+		if record.Language != want[record.SourceFile] {
+			t.Errorf("%s: language = %q, want %q", record.SourceFile, record.Language, want[record.SourceFile])
+		}
+		wantCode := ""
+		if record.SourceFile == "main.go" {
+			wantCode = "go"
+		}
+		if record.CodeLanguage != wantCode {
+			t.Errorf("%s: code_language = %q, want %q", record.SourceFile, record.CodeLanguage, wantCode)
+		}
+		// Only detected languages carry a confidence
+		detected := record.SourceFile == "en.txt" || record.SourceFile == "de.txt"
+		if detected != (record.LanguageConfidence > 0) {
+			t.Errorf("%s: language_confidence = %v", record.SourceFile, record.LanguageConfidence)
+		}
+	}
+	if len(seen) != len(want) {
+		t.Errorf("wrote records for %v, want %v", seen, want)
+	}
+}
+
+func TestProcessor_Redact(t *testing.T) {
+	files := map[string]string{
+		"contact.txt": "Write to jane@example.com or call +49 30 1234567.",
+		"plain.txt":   "Nothing personal in this file.",
+	}
+
+	tests := []struct {
+		mode        string
+		wantText    string
+		wantRecords int
+		wantDropped int
+	}{
+		{config.RedactMask, "Write to [EMAIL] or call [PHONE].", 2, 0},
+		{config.RedactDropChunk, "", 1, 1},
+		{config.RedactReportOnly, files["contact.txt"], 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			p := newTestProcessor(t, config.Config{ChunkSize: 100, RedactMode: tt.mode}, files)
+			if err := p.Process(); err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			if got := formatCounts(p.stats.Redactions); got != "EMAIL=1 PHONE=1" {
+				t.Errorf("redaction counts = %q, want %q", got, "EMAIL=1 PHONE=1")
+			}
+			if p.stats.RedactedDropped != tt.wantDropped {
+				t.Errorf("dropped %d chunks, want %d", p.stats.RedactedDropped, tt.wantDropped)
+			}
+
+			records := readRecords(t, p)
+			if len(records) != tt.wantRecords {
+				t.Fatalf("wrote %d records, want %d", len(records), tt.wantRecords)
+			}
+			for _, record := range records {
+				if record.SourceFile != "contact.txt" {
+					if record.Redactions != nil {
+						t.Errorf("%s: redactions = %v, want none", record.SourceFile, record.Redactions)
+					}
+					continue
+				}
+				if record.Text != tt.wantText {
+					t.Errorf("text = %q, want %q", record.Text, tt.wantText)
+				}
+				if record.CharCount != len([]rune(tt.wantText)) {
+					t.Errorf("char_count = %d, want %d", record.CharCount, len([]rune(tt.wantText)))
+				}
+				want := map[string]int{RedactEmail: 1, RedactPhone: 1}
+				if !reflect.DeepEqual(record.Redactions, want) {
+					t.Errorf("redactions = %v, want %v", record.Redactions, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessor_RedactCountsOnce(t *testing.T) {
+	files := map[string]string{
+		"contact.txt": "Please write to jane@example.com about the invoice for the quarter ending in March.",
+	}
+
+	// The address is in a parent and its children, and in the overlap of
+	// consecutive chunks
+	for _, parentSize := range []int{0, 12} {
+		cfg := config.Config{ChunkSize: 5, ChunkOverlap: 3, ParentSize: parentSize, RedactMode: config.RedactMask}
+		p := newTestProcessor(t, cfg, files)
+		if err := p.Process(); err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+
+		masked := 0
+		for _, record := range readRecords(t, p) {
+			if record.Level != ChunkParent && strings.Contains(record.Text, "[EMAIL]") {
+				masked++
+			}
+		}
+		if masked < 2 {
+			t.Errorf("parent size %d: address masked in %d chunks, want it repeated", parentSize, masked)
+		}
+		if got := formatCounts(p.stats.Redactions); got != "EMAIL=1" {
+			t.Errorf("parent size %d: redaction counts = %q, want %q", parentSize, got, "EMAIL=1")
+		}
+	}
+}
+
+func TestProcessor_ChunksCreated(t *testing.T) {
+	files := map[string]string{
+		"a.txt":       "The same short note about the weekly team meeting.",
+		"b.txt":       "The same short note about the weekly team meeting.",
+		"contact.txt": "Write to jane@example.com for details.",
+	}
+
+	tests := []struct {
+		dedup       string
+		wantRecords int
+	}{
+		{config.DedupSkip, 1},
+		{config.DedupLink, 2},
+	}
+
+	// Only the records written are counted, duplicates linked to the first
+	// copy included
+	for _, tt := range tests {
+		t.Run(tt.dedup, func(t *testing.T) {
+			cfg := config.Config{ChunkSize: 50, RedactMode: config.RedactDropChunk, Dedup: tt.dedup, DedupThreshold: 0.9}
+			p := newTestProcessor(t, cfg, files)
+			if err := p.Process(); err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			records := readRecords(t, p)
+			if len(records) != tt.wantRecords || p.stats.ChunksCreated != tt.wantRecords {
+				t.Errorf("wrote %d records, counted %d, want %d", len(records), p.stats.ChunksCreated, tt.wantRecords)
+			}
+		})
+	}
+}
+
+func TestProcessor_Secrets(t *testing.T) {
+	files := map[string]string{
+		"setup.txt": "Clone the repository first.\n\nThen export AWS_ACCESS_KEY_ID=" + testAWSAccessKey + " before deploying.\n",
+		"plain.txt": "Nothing secret in this file.",
+	}
+
+	tests := []struct {
+		policy      string
+		wantRecords int
+		wantChunks  int
+		wantFiles   int
+		wantText    string
+	}{
+		{config.SecretSkipChunk, 2, 1, 0, ""},
+		{config.SecretSkipFile, 1, 0, 1, ""},
+		{config.SecretMask, 3, 0, 0, "AWS_ACCESS_KEY_ID=[AWS_ACCESS_KEY_ID] before deploying."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			p := newTestProcessor(t, config.Config{ChunkSize: 6, SecretPolicy: tt.policy}, files)
+			if err := p.Process(); err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			if p.stats.SecretChunks != tt.wantChunks || p.stats.SecretFiles != tt.wantFiles {
+				t.Errorf("skipped %d chunks and %d files, want %d and %d",
+					p.stats.SecretChunks, p.stats.SecretFiles, tt.wantChunks, tt.wantFiles)
+			}
+			records := readRecords(t, p)
+			if len(records) != tt.wantRecords {
+				t.Fatalf("wrote %d records, want %d", len(records), tt.wantRecords)
+			}
+			for _, record := range records {
+				if strings.Contains(record.Text, testAWSAccessKey) {
+					t.Errorf("record %d of %s holds the key", record.ChunkIndex, record.SourceFile)
+				}
+				if tt.wantText != "" && strings.Contains(record.Text, "AWS_ACCESS_KEY_ID") && record.Text != tt.wantText {
+					t.Errorf("text = %q, want %q", record.Text, tt.wantText)
+				}
+			}
+
+			// The report names the secret without holding it
+			report, err := os.ReadFile(SecretReportPath(p.config.Output))
+			if err != nil {
+				t.Fatalf("Failed to read secret report: %v", err)
+			}
+			if strings.Contains(string(report), testAWSAccessKey) {
+				t.Error("secret report holds the key")
+			}
+			var finding SecretFinding
+			if err := json.Unmarshal(report, &finding); err != nil {
+				t.Fatalf("Failed to parse secret report %q: %v", report, err)
+			}
+			if finding.SourceFile != "setup.txt" || finding.Type != SecretAWSAccessKey || finding.Line != 3 || finding.Action != tt.policy {
+				t.Errorf("finding = %+v", finding)
+			}
+		})
+	}
+}
+
+func TestProcessor_Formats(t *testing.T) {
+	files := map[string]string{
+		"guide.md":   "---\ntitle: Guide\n---\n# Install\n\nRun the installer.\n",
+		"page.html":  "<html><head><title>Page</title></head><body><h1>About</h1><p>We build tools.</p><script>track()</script></body></html>",
+		"snapshot":   "<!DOCTYPE html><html><body><p>Saved page.</p></body></html>",
+		"notes.rst":  "Notes\n=====\n\nRemember ``make test``.\n",
+		"feed.xml":   "<?xml version=\"1.0\"?><feed><title>Feed</title><entry>First entry</entry></feed>",
+		"readme.txt": "Plain text.",
+		"main.go":    "package main\n",
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		want    map[string]string // Text of each file's single record
+	}{
+		{
+			name: "all formats",
+			want: map[string]string{
+				"guide.md":   "# Install\n\nRun the installer.",
+				"page.html":  "# About\n\nWe build tools.",
+				"snapshot":   "Saved page.",
+				"notes.rst":  "# Notes\n\nRemember make test.",
+				"feed.xml":   "Feed First entry",
+				"readme.txt": "Plain text.",
+			},
+		},
+		{
+			name:    "included extensions",
+			include: []string{"html", ".rst"},
+			want: map[string]string{
+				"page.html": "# About\n\nWe build tools.",
+				"snapshot":  "Saved page.",
+				"notes.rst": "# Notes\n\nRemember make test.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProcessor(t, config.Config{ChunkSize: 100, IncludeExt: tt.include}, files)
+			if err := p.Process(); err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			got := map[string]string{}
+			for _, record := range readRecords(t, p) {
+				got[record.SourceFile] = strings.TrimSpace(record.Text)
+				if title := record.Metadata["title"]; record.SourceFile == "page.html" && title != "Page" {
+					t.Errorf("page.html: title = %q, want %q", title, "Page")
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessor_PDF(t *testing.T) {
+	files := map[string]string{
+		"report.pdf": string(buildPDF(pdfPages(pdfHelvetica,
+			"BT /F1 12 Tf 72 720 Td (Revenue grew in the first quarter.) Tj ET",
+			"BT /F1 12 Tf 72 720 Td (Costs fell in the second quarter.) Tj ET",
+		), "/Info << /Title (Annual Report) >>")),
+		"locked.pdf":  string(buildPDF(pdfPages(pdfHelvetica, "BT /F1 12 Tf (Hidden) Tj ET"), "/Encrypt << /Filter /Standard >>")),
+		"scanned.pdf": string(buildPDF(pdfPages(pdfHelvetica, "q 612 0 0 792 0 0 cm /Im1 Do Q"), "")),
+		"keys.pdf":    string(buildPDF(pdfPages(pdfHelvetica, "BT /F1 12 Tf 72 720 Td (token=) Tj ("+testGitHubToken+") Tj ET"), "")),
+	}
+
+	p := newTestProcessor(t, config.Config{ChunkSize: 100, SecretPolicy: config.SecretSkipFile}, files)
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if got := formatCounts(p.stats.SkipReasons); got != "encrypted=1 image-only=1" {
+		t.Errorf("skip reasons = %q, want %q", got, "encrypted=1 image-only=1")
+	}
+	if p.stats.FilesSkipped != 2 || p.stats.TotalErrors != 0 || p.stats.SecretFiles != 1 {
+		t.Errorf("skipped %d files with %d errors and %d for secrets, want 2, 0 and 1",
+			p.stats.FilesSkipped, p.stats.TotalErrors, p.stats.SecretFiles)
+	}
+
+	records := readRecords(t, p)
+	if len(records) != 2 {
+		t.Fatalf("wrote %d records, want 2", len(records))
+	}
+	for i, record := range records {
+		if record.SourceFile != "report.pdf" || record.ChunkIndex != i || record.PageNumber != i+1 {
+			t.Errorf("record %d: %s chunk %d on page %d", i, record.SourceFile, record.ChunkIndex, record.PageNumber)
+		}
+		if record.Metadata["title"] != "Annual Report" || record.DetectedEncoding != "" {
+			t.Errorf("record %d: metadata = %v, detected_encoding = %q", i, record.Metadata, record.DetectedEncoding)
+		}
+	}
+	if records[1].Text != "Costs fell in the second quarter." {
+		t.Errorf("text = %q", records[1].Text)
+	}
+
+	// Secrets found in extracted text are located by page
+	report, err := os.ReadFile(SecretReportPath(p.config.Output))
+	if err != nil {
+		t.Fatalf("Failed to read secret report: %v", err)
+	}
+	var finding SecretFinding
+	if err := json.Unmarshal(report, &finding); err != nil {
+		t.Fatalf("Failed to parse secret report %q: %v", report, err)
+	}
+	if finding.SourceFile != "keys.pdf" || finding.PageNumber != 1 || finding.Line != 1 {
+		t.Errorf("finding = %+v", finding)
+	}
+}
+
+func TestProcessor_Office(t *testing.T) {
+	rows := `<row r="1"><c r="A1" t="inlineStr"><is><t>Task</t></is></c></row>`
+	for i := 2; i <= 9; i++ {
+		rows += fmt.Sprintf(`<row r="%d"><c r="A%d" t="inlineStr"><is><t>Review the release notes for item %d</t></is></c></row>`, i, i, i)
+	}
+	deck := buildOffice(map[string]string{
+		"ppt/slides/slide1.xml": pptxSlide("Intro", "Welcome to the launch."),
+		"ppt/slides/slide2.xml": pptxSlide("Plan", "Ship the release in May."),
+	})
+	files := map[string]string{
+		"deck.pptx":  string(deck),
+		"tasks.xlsx": string(buildOffice(xlsxWorkbook(nil, "Tasks="+rows))),
+	}
+
+	p := newTestProcessor(t, config.Config{ChunkSize: 20}, files)
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if p.stats.FilesProcessed != 2 || p.stats.TotalErrors != 0 {
+		t.Fatalf("processed %d files with %d errors, want 2 and 0", p.stats.FilesProcessed, p.stats.TotalErrors)
+	}
+
+	var slides, sheetRows []string
+	for _, record := range readRecords(t, p) {
+		switch record.SourceFile {
+		case "deck.pptx":
+			slides = append(slides, record.Metadata["slide"]+" "+record.Section)
+		case "tasks.xlsx":
+			if record.Metadata["sheet"] != "Tasks" || !strings.Contains(record.Text, "item") {
+				t.Errorf("record = %q with metadata %v", record.Text, record.Metadata)
+			}
+			sheetRows = append(sheetRows, record.Metadata["row"])
+		}
+	}
+	if got := strings.Join(slides, ","); got != "1 Intro,2 Plan" {
+		t.Errorf("slides = %q, want %q", got, "1 Intro,2 Plan")
+	}
+	// A chunk that ends or starts within a row counts it
+	if got := strings.Join(sheetRows, ","); got != "2-4,4-6,7-9,9" {
+		t.Errorf("rows = %q, want %q", got, "2-4,4-6,7-9,9")
+	}
+}
+
+func TestProcessor_Rows(t *testing.T) {
+	long := strings.Repeat("Solid oak with a hand rubbed finish. ", 6)
+	files := map[string]string{
+		"products.csv":  "sku,name,description,category\nA1,Desk," + long + ",furniture\nA2,Lamp,Brass reading lamp,lighting\n",
+		"tickets.jsonl": `{"sku": null, "name": "Refund", "description": "Card charged twice", "category": "billing"}` + "\n",
+	}
+
+	cfg := config.Config{
+		ChunkSize:       20,
+		TextColumns:     "{{.name}}: {{.description}}",
+		IDColumn:        "sku",
+		MetadataColumns: []string{"category"},
+	}
+	p := newTestProcessor(t, cfg, files)
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if p.stats.FilesProcessed != 2 || p.stats.TotalErrors != 0 {
+		t.Fatalf("processed %d files with %d errors, want 2 and 0", p.stats.FilesProcessed, p.stats.TotalErrors)
+	}
+
+	var got []string
+	for _, record := range readRecords(t, p) {
+		if record.SourceFile == "tickets.jsonl" {
+			// Rows with an empty ID get random IDs
+			if record.ID == "" || record.Text != "Refund: Card charged twice" || record.Metadata["category"] != "billing" {
+				t.Errorf("record = %+v", record)
+			}
+			continue
+		}
+		got = append(got, fmt.Sprintf("%s %d %s", record.ID, record.ChunkIndex, record.Metadata["category"]))
+	}
+
+	// A row longer than the chunk size is still chunked
+	want := "A1 0 furniture,A1#2 1 furniture,A1#3 2 furniture,A2 3 lighting"
+	if strings.Join(got, ",") != want {
+		t.Errorf("records = %q, want %q", strings.Join(got, ","), want)
+	}
+}
+
+func TestProcessor_EmailMetadata(t *testing.T) {
+	files := map[string]string{
+		"key.eml": "From: Jane <jane@example.com>\nSubject: New key " + testAWSAccessKey + "\n\nThe deploy key was rotated today.\n",
+	}
+
+	t.Run("masked", func(t *testing.T) {
+		cfg := config.Config{ChunkSize: 100, RedactMode: config.RedactMask, SecretPolicy: config.SecretMask}
+		p := newTestProcessor(t, cfg, files)
+		if err := p.Process(); err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+
+		records := readRecords(t, p)
+		if len(records) != 1 {
+			t.Fatalf("wrote %d records, want 1", len(records))
+		}
+		want := map[string]string{"from": "Jane <[EMAIL]>", "subject": "New key [AWS_ACCESS_KEY_ID]"}
+		if !reflect.DeepEqual(records[0].Metadata, want) {
+			t.Errorf("metadata = %v, want %v", records[0].Metadata, want)
+		}
+		if got := formatCounts(p.stats.Redactions); got != "EMAIL=1" {
+			t.Errorf("redaction counts = %q, want %q", got, "EMAIL=1")
+		}
+
+		report, err := os.ReadFile(SecretReportPath(p.config.Output))
+		if err != nil {
+			t.Fatalf("Failed to read secret report: %v", err)
+		}
+		var finding SecretFinding
+		if err := json.Unmarshal(report, &finding); err != nil {
+			t.Fatalf("Failed to parse secret report %q: %v", report, err)
+		}
+		if finding.Field != "subject" || finding.Type != SecretAWSAccessKey || finding.Action != config.SecretMask {
+			t.Errorf("finding = %+v", finding)
+		}
+	})
+
+	t.Run("skip-file", func(t *testing.T) {
+		p := newTestProcessor(t, config.Config{ChunkSize: 100, SecretPolicy: config.SecretSkipFile}, files)
+		if err := p.Process(); err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		if p.stats.SecretFiles != 1 || p.stats.ChunksCreated != 0 {
+			t.Errorf("skipped %d files and created %d chunks, want 1 and 0", p.stats.SecretFiles, p.stats.ChunksCreated)
+		}
+	})
+}
+
+func TestProcessor_SkipsOwnOutput(t *testing.T) {
+	files := map[string]string{
+		"rows.jsonl": `{"note": "A row of data."}` + "\n",
+		"setup.txt":  "Export AWS_ACCESS_KEY_ID=" + testAWSAccessKey + " before deploying.",
+	}
+	p := newTestProcessor(t, config.Config{ChunkSize: 50, SecretPolicy: config.SecretMask}, files)
+	p.config.Output = filepath.Join(p.config.Directory, "storage", "vectors.jsonl")
+
+	// The records, manifest and secret report of the first run sit in the
+	// input directory of the second
+	for run := 0; run < 2; run++ {
+		if err := p.Process(); err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+	}
+	for _, path := range []string{ManifestPath(p.config.Output), SecretReportPath(p.config.Output)} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("missing output: %v", err)
+		}
+	}
+
+	discovered, err := p.discoverFiles()
+	if err != nil {
+		t.Fatalf("discoverFiles() error = %v", err)
+	}
+	var got []string
+	for _, file := range discovered {
+		got = append(got, filepath.Base(file.path))
+	}
+	if want := "rows.jsonl,setup.txt"; strings.Join(got, ",") != want {
+		t.Errorf("discovered %q, want %q", strings.Join(got, ","), want)
+	}
+	if records := readRecords(t, p); len(records) != 4 {
+		t.Errorf("got %d records after two runs, want 4", len(records))
+	}
+}
+
+func TestProcessor_RowMetadata(t *testing.T) {
+	files := map[string]string{
+		"accounts.csv": "name,owner,token\nBilling,jane@example.com,\nDeploy,ops," + testAWSAccessKey + "\n",
+	}
+
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want []string // Metadata of each record
+	}{
+		{
+			name: "masked",
+			cfg:  config.Config{RedactMode: config.RedactMask, SecretPolicy: config.SecretMask},
+			want: []string{"[EMAIL]|", "ops|[AWS_ACCESS_KEY_ID]"},
+		},
+		{
+			name: "dropped and skipped",
+			cfg:  config.Config{RedactMode: config.RedactDropChunk, SecretPolicy: config.SecretSkipChunk},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.ChunkSize = 20
+			tt.cfg.TextColumns = "{{.name}} account"
+			tt.cfg.MetadataColumns = []string{"owner", "token"}
+			p := newTestProcessor(t, tt.cfg, files)
+			if err := p.Process(); err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			var got []string
+			if tt.want != nil {
+				for _, record := range readRecords(t, p) {
+					got = append(got, record.Metadata["owner"]+"|"+record.Metadata["token"])
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("metadata = %q, want %q", got, tt.want)
+			}
+			if p.stats.Redactions[RedactEmail] != 1 || p.stats.Secrets[SecretAWSAccessKey] != 1 {
+				t.Errorf("redactions = %v, secrets = %v, want one email and one access key", p.stats.Redactions, p.stats.Secrets)
+			}
+		})
+	}
+}
+
+func TestProcessor_Email(t *testing.T) {
+	files := map[string]string{
+		"support.mbox": "From a@example.com Mon Jun  3 09:15:00 2024\n" +
+			"From: Ana <a@example.com>\nTo: help@example.com\nSubject: Login fails\nDate: Mon, 3 Jun 2024 09:15:00 +0000\nMessage-ID: <1@example.com>\n\n" +
+			"The login page times out.\n\n" +
+			"From b@example.com Mon Jun  3 10:00:00 2024\n" +
+			"From: help@example.com\nTo: Ana <a@example.com>\nSubject: Re: Login fails\nMessage-ID: <2@example.com>\n\n" +
+			"Fixed now, please retry.\n\nOn Mon, Ana wrote:\n> The login page times out.\n",
+		"note.eml": "From: c@example.com\nSubject: Hello\nContent-Type: text/plain; charset=windows-1252\nContent-Transfer-Encoding: 8bit\n\nCaf\xe9 opens at nine.\n",
+	}
+
+	p := newTestProcessor(t, config.Config{ChunkSize: 100}, files)
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if p.stats.FilesProcessed != 2 || p.stats.TotalErrors != 0 {
+		t.Fatalf("processed %d files with %d errors, want 2 and 0", p.stats.FilesProcessed, p.stats.TotalErrors)
+	}
+
+	var got []string
+	for _, record := range readRecords(t, p) {
+		got = append(got, fmt.Sprintf("%s %d %s|%s|%s|%s|%s", record.SourceFile, record.ChunkIndex, record.Metadata["message_id"],
+			record.Metadata["from"], record.Metadata["to"], record.Metadata["subject"], record.Text))
+	}
+	want := []string{
+		"note.eml 0 |c@example.com||Hello|Café opens at nine.",
+		"support.mbox 0 1@example.com|Ana <a@example.com>|help@example.com|Login fails|The login page times out.",
+		"support.mbox 1 2@example.com|help@example.com|Ana <a@example.com>|Re: Login fails|Fixed now, please retry.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("records = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,192 @@
+package ingest
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// recursiveStrategy splits text on the coarsest separator that brings every
+// piece within the budget and character limit, trying paragraph breaks, then
+// line breaks, then sentence ends, then word boundaries, and finally cutting
+// inside over-long words. Neighboring pieces are then merged back up to the
+// limits.
+type recursiveStrategy struct {
+	budget   *budget
+	maxChars int // Character limit of a chunk, or 0 for none
+}
+
+// separators split a region into the non-blank parts between separators,
+// from the coarsest to the finest
+var separators = []func(text string, region span) []span{
+	splitParagraphs,
+	splitLineSpans,
+	splitSentenceSpans,
+	splitWords,
+}
+
+// piece is a region of text with its size
+type piece struct {
+	span
+	words int
+	cost  int
+}
+
+// Split implements Strategy
+func (s *recursiveStrategy) Split(text string) []Chunk {
+	var chunks []Chunk
+	var group []piece
+	groupWords, groupCost := 0, 0
+
+	flush := func() {
+		// Separators and symbols alone do not make a chunk
+		if groupWords > 0 {
+			chunks = append(chunks, Chunk{
+				Text:      text[group[0].start:group[len(group)-1].end],
+				WordCount: groupWords,
+				StartByte: group[0].start,
+				EndByte:   group[len(group)-1].end,
+			})
+		}
+		group = group[:0]
+		groupWords, groupCost = 0, 0
+	}
+
+	for _, p := range s.split(text, span{0, len(text)}, 0) {
+		if len(group) > 0 && !s.fits(text, span{group[0].start, p.end}, groupCost+p.cost) {
+			flush()
+		}
+		group = append(group, p)
+		groupWords += p.words
+		groupCost += p.cost
+	}
+	flush()
+
+	return chunks
+}
+
+// split breaks region into pieces that fit, starting with separator level
+func (s *recursiveStrategy) split(text string, region span, level int) []piece {
+	p := s.measure(text, region)
+	if s.fits(text, region, p.cost) {
+		return []piece{p}
+	}
+
+	for ; level < len(separators); level++ {
+		parts := separators[level](text, region)
+		if len(parts) < 2 {
+			continue
+		}
+
+		var pieces []piece
+		for _, part := range parts {
+			pieces = append(pieces, s.split(text, part, level+1)...)
+		}
+		return pieces
+	}
+
+	// A single word over the limit is cut by characters
+	return s.cutChars(text, region)
+}
+
+// measure counts the words of a region and the budget they consume
+func (s *recursiveStrategy) measure(text string, region span) piece {
+	words := wordSpans(text, region)
+	return piece{span: region, words: len(words), cost: sum(s.budget.spanCosts(text, words))}
+}
+
+// fits reports whether a region of the given cost is within both limits
+func (s *recursiveStrategy) fits(text string, region span, cost int) bool {
+	if cost > s.budget.size {
+		return false
+	}
+	return s.maxChars == 0 || utf8.RuneCountInString(text[region.start:region.end]) <= s.maxChars
+}
+
+// cutChars splits region every maxChars characters
+func (s *recursiveStrategy) cutChars(text string, region span) []piece {
+	if s.maxChars == 0 {
+		return []piece{s.measure(text, region)}
+	}
+
+	var pieces []piece
+	start, count := region.start, 0
+	for i := range text[region.start:region.end] {
+		if count == s.maxChars {
+			pieces = append(pieces, s.measure(text, span{start, region.start + i}))
+			start, count = region.start+i, 0
+		}
+		count++
+	}
+	return append(pieces, s.measure(text, span{start, region.end}))
+}
+
+// splitParagraphs returns the parts of region separated by blank lines
+func splitParagraphs(text string, region span) []span {
+	var parts []span
+	start := region.start
+	for _, line := range splitLines(text[region.start:region.end]) {
+		if strings.TrimSpace(text[region.start+line.start:region.start+line.end]) == "" {
+			parts = appendPart(parts, text, span{start, region.start + line.start})
+			start = region.start + line.end
+		}
+	}
+	return appendPart(parts, text, span{start, region.end})
+}
+
+// splitLineSpans returns the lines of region
+func splitLineSpans(text string, region span) []span {
+	var parts []span
+	for _, line := range splitLines(text[region.start:region.end]) {
+		parts = appendPart(parts, text, span{region.start + line.start, region.start + line.end})
+	}
+	return parts
+}
+
+// splitSentenceSpans returns the sentences of region
+func splitSentenceSpans(text string, region span) []span {
+	var parts []span
+	for _, sentence := range splitSentences(text[region.start:region.end]) {
+		parts = appendPart(parts, text, span{region.start + sentence.start, region.start + sentence.end})
+	}
+	return parts
+}
+
+// splitWords returns the words of region, each carrying the whitespace-free
+// punctuation that follows it so no text is lost between words
+func splitWords(text string, region span) []span {
+	words := wordSpans(text, region)
+	if len(words) == 0 {
+		return nil
+	}
+
+	var parts []span
+	start := region.start
+	for i := 1; i < len(words); i++ {
+		parts = appendPart(parts, text, span{start, words[i].start})
+		start = words[i].start
+	}
+	return appendPart(parts, text, span{start, region.end})
+}
+
+// appendPart appends part trimmed of surrounding whitespace, unless it is blank
+func appendPart(parts []span, text string, part span) []span {
+	for part.start < part.end {
+		r, size := utf8.DecodeRuneInString(text[part.start:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		part.start += size
+	}
+	for part.end > part.start {
+		r, size := utf8.DecodeLastRuneInString(text[:part.end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		part.end -= size
+	}
+	if part.start < part.end {
+		parts = append(parts, part)
+	}
+	return parts
+}
@@ -0,0 +1,111 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"wafer/internal/config"
+)
+
+const recursiveSample = "Intro paragraph with a few words.\n\n" +
+	"Second paragraph, first line.\nSecond line of it.\n\n" +
+	"Third paragraph is one long sentence that keeps going well past any small limit we set. And a short one."
+
+func TestRecursiveStrategy_Limits(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		maxChars   int
+		wantChunks []string
+	}{
+		{
+			name:     "whole document fits",
+			size:     100,
+			maxChars: 0,
+			wantChunks: []string{
+				recursiveSample,
+			},
+		},
+		{
+			name:     "paragraphs merge up to the character limit",
+			size:     100,
+			maxChars: 90,
+			wantChunks: []string{
+				"Intro paragraph with a few words.\n\nSecond paragraph, first line.\nSecond line of it.",
+				"Third paragraph is one long sentence that keeps going well past any small limit we set.",
+				"And a short one.",
+			},
+		},
+		{
+			name:     "word budget splits long sentences at spaces",
+			size:     8,
+			maxChars: 0,
+			wantChunks: []string{
+				"Intro paragraph with a few words.",
+				"Second paragraph, first line.\nSecond line of it.",
+				"Third paragraph is one long sentence that keeps",
+				"going well past any small limit we set.",
+				"And a short one.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunker := NewChunker(&config.Config{
+				ChunkSize:     tt.size,
+				ChunkStrategy: config.StrategyRecursive,
+				MaxChars:      tt.maxChars,
+			})
+			chunks := chunker.ChunkText(recursiveSample)
+
+			if len(chunks) != len(tt.wantChunks) {
+				t.Fatalf("ChunkText() got %d chunks, want %d: %q", len(chunks), len(tt.wantChunks), chunkTexts(chunks))
+			}
+			for i, chunk := range chunks {
+				if chunk.Text != tt.wantChunks[i] {
+					t.Errorf("chunk %d = %q, want %q", i, chunk.Text, tt.wantChunks[i])
+				}
+				if chunk.CharCount != utf8.RuneCountInString(chunk.Text) {
+					t.Errorf("chunk %d reports %d characters", i, chunk.CharCount)
+				}
+				if tt.maxChars > 0 && chunk.CharCount > tt.maxChars {
+					t.Errorf("chunk %d has %d characters, over the limit", i, chunk.CharCount)
+				}
+				if chunk.WordCount > tt.size {
+					t.Errorf("chunk %d has %d words, over the budget", i, chunk.WordCount)
+				}
+			}
+		})
+	}
+}
+
+func TestRecursiveStrategy_CutsLongWords(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 100, ChunkStrategy: config.StrategyRecursive, MaxChars: 10})
+
+	url := "https://example.com/" + strings.Repeat("ü", 25)
+	chunks := chunker.ChunkText("See " + url)
+
+	var rebuilt strings.Builder
+	for _, chunk := range chunks {
+		if chunk.CharCount > 10 {
+			t.Errorf("chunk %d has %d characters, over the limit", chunk.Index, chunk.CharCount)
+		}
+		if !utf8.ValidString(chunk.Text) {
+			t.Errorf("chunk %d cuts a character in half: %q", chunk.Index, chunk.Text)
+		}
+		rebuilt.WriteString(chunk.Text)
+	}
+	if rebuilt.String() != "See"+url {
+		t.Errorf("chunks do not cover the text: %q", rebuilt.String())
+	}
+}
+
+func chunkTexts(chunks []Chunk) []string {
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+	return texts
+}
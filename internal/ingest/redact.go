@@ -0,0 +1,404 @@
+package ingest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"maps"
+	"math/big"
+	"net/netip"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Placeholder types of the built-in redaction rules
+const (
+	RedactEmail      = "EMAIL"
+	RedactPhone      = "PHONE"
+	RedactCreditCard = "CREDIT_CARD"
+	RedactIBAN       = "IBAN"
+	RedactIPAddress  = "IP_ADDRESS"
+)
+
+// redactRule replaces the matches of a pattern with a typed placeholder
+type redactRule struct {
+	kind    string                                 // Placeholder type, such as EMAIL for "[EMAIL]"
+	re      *regexp.Regexp                         // Finds candidates; the first group, if any, is what gets replaced
+	valid   func(string) bool                      // Confirms a candidate, or nil to accept every match
+	context func(text string, start, end int) bool // Confirms the text around a candidate, or nil to accept any
+}
+
+// builtinRedactRules find personal data. Patterns that must not start inside
+// a longer word or number match the character before outside the group; the
+// character after is checked by the rule's context instead, so it is left to
+// start the next match when two values are separated by a single character.
+var builtinRedactRules = []redactRule{
+	{
+		kind: RedactEmail,
+		re:   regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	},
+	{
+		kind:  RedactIBAN,
+		re:    regexp.MustCompile(`\b([A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?)\b`),
+		valid: validIBAN,
+	},
+	{
+		kind:    RedactCreditCard,
+		re:      regexp.MustCompile(`(?:^|[^\w])(\d{4}[ -]?\d{4}[ -]?\d{4}[ -]?\d{1,7}|\d{4}[ -]?\d{6}[ -]?\d{5})`),
+		valid:   validLuhn,
+		context: standalone,
+	},
+	{
+		kind:    RedactIPAddress,
+		re:      regexp.MustCompile(`\b((?:\d{1,3}\.){3}\d{1,3})\b`),
+		valid:   validIP,
+		context: ipContext,
+	},
+	{
+		kind:  RedactIPAddress,
+		re:    regexp.MustCompile(`(?i)(?:^|[^\w:])((?:[0-9a-f]{1,4})?(?::[0-9a-f]{0,4}){2,7})`),
+		valid: validIPv6,
+	},
+	{
+		kind:    RedactPhone,
+		re:      regexp.MustCompile(`(?:^|[^\w+])(\+?\(?\d[\d ().-]{5,18}\d)`),
+		valid:   validPhone,
+		context: phoneContext,
+	},
+}
+
+// ruleNameRE matches the placeholder type of a custom rule
+var ruleNameRE = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// Redactor replaces the matches of its rules in chunk text, such as personal
+// data, with typed placeholders
+type Redactor struct {
+	rules []redactRule
+}
+
+// NewRedactor creates a redactor with the built-in rules and the custom rules
+// of the given file, if any. Each line of the file holds a placeholder type
+// and a regular expression separated by whitespace; blank lines and lines
+// starting with # are ignored.
+func NewRedactor(rulesPath string) (*Redactor, error) {
+	r := &Redactor{}
+	if rulesPath != "" {
+		custom, err := loadRedactRules(rulesPath)
+		if err != nil {
+			return nil, err
+		}
+		r.rules = custom
+	}
+
+	// Custom rules run first so they take precedence over the built-in ones
+	r.rules = append(r.rules, builtinRedactRules...)
+	return r, nil
+}
+
+// loadRedactRules reads custom redaction rules from a file
+func loadRedactRules(path string) ([]redactRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open redaction rules: %w", err)
+	}
+	defer file.Close()
+
+	var rules []redactRule
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		kind, pattern := text, ""
+		if i := strings.IndexAny(text, " \t"); i >= 0 {
+			kind, pattern = text[:i], strings.TrimSpace(text[i:])
+		}
+		if !ruleNameRE.MatchString(kind) || pattern == "" {
+			return nil, fmt.Errorf("invalid redaction rule on line %d: want an uppercase type and a pattern", line)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction rule on line %d: %w", line, err)
+		}
+		rules = append(rules, redactRule{kind: kind, re: re})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read redaction rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Redact returns text with every match replaced by its placeholder, such as
+// "[EMAIL]", and the number of matches of each type
+func (r *Redactor) Redact(text string) (string, map[string]int) {
+	matches := r.find(text)
+	if len(matches) == 0 {
+		return text, nil
+	}
+
+	var b strings.Builder
+	counts := map[string]int{}
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.start])
+		b.WriteString("[" + m.kind + "]")
+		last = m.end
+		counts[m.kind]++
+	}
+	b.WriteString(text[last:])
+	return b.String(), counts
+}
+
+// RedactMetadata returns a copy of metadata with every value redacted and
+// the number of matches of each type, or metadata itself when nothing matches
+func (r *Redactor) RedactMetadata(metadata map[string]string) (map[string]string, map[string]int) {
+	var redacted map[string]string
+	var counts map[string]int
+	for key, value := range metadata {
+		text, found := r.Redact(value)
+		if len(found) == 0 {
+			continue
+		}
+		if redacted == nil {
+			redacted, counts = maps.Clone(metadata), map[string]int{}
+		}
+		redacted[key] = text
+		for kind, n := range found {
+			counts[kind] += n
+		}
+	}
+	if redacted == nil {
+		return metadata, nil
+	}
+	return redacted, counts
+}
+
+// redactMatch is a confirmed match of a rule in a text
+type redactMatch struct {
+	kind       string
+	start, end int
+}
+
+// find returns the confirmed matches in text ordered by position. Where
+// matches overlap, the one of the earlier rule wins.
+func (r *Redactor) find(text string) []redactMatch {
+	var matches []redactMatch
+	for _, rule := range r.rules {
+		for _, m := range rule.find(text) {
+			overlaps := slices.ContainsFunc(matches, func(o redactMatch) bool {
+				return m.start < o.end && o.start < m.end
+			})
+			if !overlaps {
+				matches = append(matches, m)
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].start < matches[j].start
+	})
+	return matches
+}
+
+// find returns the confirmed matches of the rule in text
+func (rule redactRule) find(text string) []redactMatch {
+	var matches []redactMatch
+	for _, m := range rule.re.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		if rule.valid != nil && !rule.valid(text[start:end]) {
+			continue
+		}
+		if rule.context != nil && !rule.context(text, start, end) {
+			continue
+		}
+		matches = append(matches, redactMatch{kind: rule.kind, start: start, end: end})
+	}
+	return matches
+}
+
+// digitsOf returns the decimal digits of s
+func digitsOf(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// validLuhn reports whether a card number passes the Luhn checksum
+func validLuhn(number string) bool {
+	digits := digitsOf(number)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range len(digits) {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// validIBAN reports whether an account number passes the IBAN mod-97 check
+func validIBAN(iban string) bool {
+	iban = strings.ReplaceAll(iban, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	// Move the country code and check digits to the end and spell letters
+	// as numbers, A=10 to Z=35
+	var b strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' && r <= 'Z' {
+			fmt.Fprintf(&b, "%d", r-'A'+10)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	n, ok := new(big.Int).SetString(b.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validIP reports whether s is an IPv4 address
+func validIP(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Is4()
+}
+
+// validIPv6 reports whether s is an IPv6 address of at least three groups,
+// which keeps "::" and scope operators such as "std::string" out
+func validIPv6(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	if err != nil || !addr.Is6() {
+		return false
+	}
+	groups := 0
+	for _, group := range strings.Split(s, ":") {
+		if group != "" {
+			groups++
+		}
+	}
+	return groups >= 3
+}
+
+// datePrefixRE matches a date a phone candidate may start with
+var datePrefixRE = regexp.MustCompile(`^(?:\d{4}-\d{2}-\d{2}|\d{1,2}[./]\d{1,2}[./]\d{4})\b`)
+
+// validPhone reports whether s looks like a phone number: 9 to 15 digits, or
+// 7 to 15 after a leading +, grouped by separators and not a date
+func validPhone(s string) bool {
+	digits := len(digitsOf(s))
+	if strings.HasPrefix(s, "+") {
+		return digits >= 7 && digits <= 15
+	}
+	if digits < 9 || digits > 15 || !strings.ContainsAny(s, " ().-") {
+		return false
+	}
+	return strings.Count(s, "(") == strings.Count(s, ")") && !datePrefixRE.MatchString(s)
+}
+
+// isWordByte reports whether c is an ASCII letter, digit or underscore
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// standalone reports whether the candidate text[start:end] does not run on
+// into a word or number
+func standalone(text string, start, end int) bool {
+	return end == len(text) || !isWordByte(text[end])
+}
+
+// versionPrefixRE matches a version keyword an IPv4 candidate may follow
+var versionPrefixRE = regexp.MustCompile(`(?i)\b(?:v|ver|version|release|build)\.?:?\s*$`)
+
+// ipContext reports whether an IPv4 candidate is an address rather than a
+// version such as "version 1.2.3.4" or part of a longer dotted number
+func ipContext(text string, start, end int) bool {
+	if start >= 2 && text[start-1] == '.' && text[start-2] >= '0' && text[start-2] <= '9' {
+		return false
+	}
+	if end+1 < len(text) && text[end] == '.' && text[end+1] >= '0' && text[end+1] <= '9' {
+		return false
+	}
+	return !versionPrefixRE.MatchString(text[max(0, start-16):start])
+}
+
+// isbnPrefixRE matches the label an ISBN may follow
+var isbnPrefixRE = regexp.MustCompile(`(?i)\bISBN(?:-?1[03])?:?\s*$`)
+
+// phoneContext reports whether a phone candidate stands alone and is not an
+// ISBN, labelled as such or recognized by its shape and checksum. The "X"
+// check digit an ISBN-10 may end with is not part of the candidate.
+func phoneContext(text string, start, end int) bool {
+	if !standalone(text, start, end) {
+		return false
+	}
+	if isbnPrefixRE.MatchString(text[max(0, start-16):start]) {
+		return false
+	}
+	candidate := text[start:end]
+	if rest := text[end:]; len(rest) >= 2 && (rest[0] == '-' || rest[0] == ' ') && (rest[1] == 'X' || rest[1] == 'x') && (len(rest) == 2 || !isWordByte(rest[2])) {
+		candidate = text[start : end+2]
+	}
+	return !isbnLike(candidate)
+}
+
+// isbnLike reports whether s is an ISBN that passes its checksum: 13 digits
+// starting with 978 or 979, or 10 digits in four groups
+func isbnLike(s string) bool {
+	digits := digitsOf(s)
+	switch {
+	case len(digits) == 13 && (strings.HasPrefix(digits, "978") || strings.HasPrefix(digits, "979")):
+		sum := 0
+		for i := range len(digits) {
+			d := int(digits[i] - '0')
+			if i%2 == 1 {
+				d *= 3
+			}
+			sum += d
+		}
+		return sum%10 == 0
+	case strings.HasSuffix(s, "X") || strings.HasSuffix(s, "x"):
+		digits += "X"
+	}
+	groups := strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == ' ' })
+	if len(digits) != 10 || len(groups) != 4 {
+		return false
+	}
+	sum := 0
+	for i := range len(digits) {
+		d := 10
+		if digits[i] != 'X' {
+			d = int(digits[i] - '0')
+		}
+		sum += (10 - i) * d
+	}
+	return sum%11 == 0
+}
+
+// redactingEmbedder redacts text before passing it to another embedder, so
+// the sentences embedded while chunking never leave the machine unredacted
+type redactingEmbedder struct {
+	embedder TextEmbedder
+	redactor *Redactor
+}
+
+// GetEmbedding embeds the redacted text
+func (e *redactingEmbedder) GetEmbedding(ctx context.Context, text string) ([]float64, error) {
+	text, _ = e.redactor.Redact(text)
+	return e.embedder.GetEmbedding(ctx, text)
+}
@@ -0,0 +1,242 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		want       string
+		wantCounts map[string]int
+	}{
+		{
+			name:       "email",
+			text:       "Write to jane.doe+news@mail.example.co.uk today.",
+			want:       "Write to [EMAIL] today.",
+			wantCounts: map[string]int{RedactEmail: 1},
+		},
+		{
+			name:       "credit card",
+			text:       "Card 4111 1111 1111 1111 and 5500-0000-0000-0004 on file.",
+			want:       "Card [CREDIT_CARD] and [CREDIT_CARD] on file.",
+			wantCounts: map[string]int{RedactCreditCard: 2},
+		},
+		{
+			name:       "amex grouping",
+			text:       "Amex 3782 822463 10005 or 378282246310005.",
+			want:       "Amex [CREDIT_CARD] or [CREDIT_CARD].",
+			wantCounts: map[string]int{RedactCreditCard: 2},
+		},
+		{
+			name: "card failing luhn",
+			text: "Order 4111 1111 1111 1112 shipped.",
+			want: "Order 4111 1111 1111 1112 shipped.",
+		},
+		{
+			name:       "iban",
+			text:       "Pay to DE89 3704 0044 0532 0130 00 or GB82WEST12345698765432.",
+			want:       "Pay to [IBAN] or [IBAN].",
+			wantCounts: map[string]int{RedactIBAN: 2},
+		},
+		{
+			name: "iban failing checksum",
+			text: "Reference DE89 3704 0044 0532 0130 01 is invalid.",
+			want: "Reference DE89 3704 0044 0532 0130 01 is invalid.",
+		},
+		{
+			name:       "ip addresses",
+			text:       "Hosts 192.168.1.20 and 2001:db8::8a2e:370:7334 answered.",
+			want:       "Hosts [IP_ADDRESS] and [IP_ADDRESS] answered.",
+			wantCounts: map[string]int{RedactIPAddress: 2},
+		},
+		{
+			name: "version numbers and scopes",
+			text: "Version 1.2.3.999 uses std::string and a::b::c.",
+			want: "Version 1.2.3.999 uses std::string and a::b::c.",
+		},
+		{
+			name:       "phone numbers",
+			text:       "Call +49 30 1234567 or (555) 123-4567.",
+			want:       "Call [PHONE] or [PHONE].",
+			wantCounts: map[string]int{RedactPhone: 2},
+		},
+		{
+			name:       "adjacent phone numbers",
+			text:       "555-123-4567\n555-987-6543",
+			want:       "[PHONE]\n[PHONE]",
+			wantCounts: map[string]int{RedactPhone: 2},
+		},
+		{
+			name:       "adjacent card numbers",
+			text:       "4111 1111 1111 1111\n5500 0000 0000 0004",
+			want:       "[CREDIT_CARD]\n[CREDIT_CARD]",
+			wantCounts: map[string]int{RedactCreditCard: 2},
+		},
+		{
+			name:       "comma-separated card numbers",
+			text:       "4111111111111111,5500000000000004",
+			want:       "[CREDIT_CARD],[CREDIT_CARD]",
+			wantCounts: map[string]int{RedactCreditCard: 2},
+		},
+		{
+			name: "numbers running into words",
+			text: "Ref 555-123-4567abc and 4111111111111111x.",
+			want: "Ref 555-123-4567abc and 4111111111111111x.",
+		},
+		{
+			name: "version strings",
+			text: "Upgrade to version 1.2.3.4, v. 10.0.0.1 or release 2.0.1.0; schema 1.2.3.4.5.",
+			want: "Upgrade to version 1.2.3.4, v. 10.0.0.1 or release 2.0.1.0; schema 1.2.3.4.5.",
+		},
+		{
+			name: "isbns",
+			text: "See 978-3-16-148410-0, 0-306-40615-2, 0-8044-2957-X and ISBN 1 84356 028 3.",
+			want: "See 978-3-16-148410-0, 0-306-40615-2, 0-8044-2957-X and ISBN 1 84356 028 3.",
+		},
+		{
+			name: "dates and plain numbers",
+			text: "Released 2024-01-15 and 15.01.2024, issue 123456789.",
+			want: "Released 2024-01-15 and 15.01.2024, issue 123456789.",
+		},
+		{
+			name:       "mixed",
+			text:       "Contact bob@example.com, +1 415 555 0100, from 10.0.0.1.",
+			want:       "Contact [EMAIL], [PHONE], from [IP_ADDRESS].",
+			wantCounts: map[string]int{RedactEmail: 1, RedactPhone: 1, RedactIPAddress: 1},
+		},
+	}
+
+	r, err := NewRedactor("")
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, counts := r.Redact(tt.text)
+			if got != tt.want {
+				t.Errorf("Redact() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(counts, tt.wantCounts) {
+				t.Errorf("Redact() counts = %v, want %v", counts, tt.wantCounts)
+			}
+		})
+	}
+}
+
+func TestNewRedactor_Rules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "custom rules",
+			rules: "# Internal identifiers\n\nEMPLOYEE_ID EMP-\\d{6}\nTICKET\t(?i)ticket #(\\d+)\n",
+			text:  "EMP-123456 opened Ticket #42.",
+			want:  "[EMPLOYEE_ID] opened Ticket #[TICKET].",
+		},
+		{
+			name:  "custom rules run first",
+			rules: "STAFF_EMAIL [a-z]+@corp\\.example\\.com\n",
+			text:  "ann@corp.example.com and bob@example.com",
+			want:  "[STAFF_EMAIL] and [EMAIL]",
+		},
+		{
+			name:    "lowercase type",
+			rules:   "employee EMP-\\d+\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing pattern",
+			rules:   "EMPLOYEE_ID\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid pattern",
+			rules:   "EMPLOYEE_ID EMP-(\\d+\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.txt")
+			if err := os.WriteFile(path, []byte(tt.rules), 0644); err != nil {
+				t.Fatalf("Failed to create rules file: %v", err)
+			}
+
+			r, err := NewRedactor(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRedactor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got, _ := r.Redact(tt.text); got != tt.want {
+				t.Errorf("Redact() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewRedactor(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("NewRedactor() with a missing file should fail")
+	}
+}
+
+// recordingEmbedder remembers the texts it is asked to embed
+type recordingEmbedder struct {
+	texts []string
+}
+
+func (e *recordingEmbedder) GetEmbedding(_ context.Context, text string) ([]float64, error) {
+	e.texts = append(e.texts, text)
+	return []float64{1}, nil
+}
+
+func TestRedactingEmbedder(t *testing.T) {
+	r, err := NewRedactor("")
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	inner := &recordingEmbedder{}
+	e := &redactingEmbedder{embedder: inner, redactor: r}
+	if _, err := e.GetEmbedding(context.Background(), "Mail jane@example.com now."); err != nil {
+		t.Fatalf("GetEmbedding() error = %v", err)
+	}
+	if want := []string{"Mail [EMAIL] now."}; !reflect.DeepEqual(inner.texts, want) {
+		t.Errorf("embedded %q, want %q", inner.texts, want)
+	}
+}
+
+func TestRedactor_RedactMetadata(t *testing.T) {
+	r, err := NewRedactor("")
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+
+	metadata := map[string]string{"from": "Jane <jane@example.com>", "subject": "Hello"}
+	got, counts := r.RedactMetadata(metadata)
+	if want := map[string]string{"from": "Jane <[EMAIL]>", "subject": "Hello"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactMetadata() = %v, want %v", got, want)
+	}
+	if want := map[string]int{RedactEmail: 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+	// Metadata is shared by the chunks of a part, so it is left as it was
+	if metadata["from"] != "Jane <jane@example.com>" {
+		t.Errorf("RedactMetadata() changed its input: %v", metadata)
+	}
+
+	clean := map[string]string{"subject": "Hello"}
+	if got, counts := r.RedactMetadata(clean); !reflect.DeepEqual(got, clean) || counts != nil {
+		t.Errorf("RedactMetadata() = %v, %v, want the metadata unchanged", got, counts)
+	}
+}
@@ -0,0 +1,227 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/template"
+
+	"wafer/internal/config"
+)
+
+// rowOptions configures how the rows of a dataset become records
+type rowOptions struct {
+	text     *template.Template // Builds the text of a row from its columns, or nil to list every column
+	id       string             // Column holding the stable ID of a row's records
+	metadata []string           // Columns copied into the metadata of a row's records
+}
+
+// newRowOptions parses the template that builds the text of a dataset row,
+// written with text/template syntax over the columns of the row, such as
+// "{{.name}}: {{.description}}"
+func newRowOptions(cfg *config.Config) (*rowOptions, error) {
+	o := &rowOptions{id: cfg.IDColumn, metadata: cfg.MetadataColumns}
+	if cfg.TextColumns != "" {
+		tmpl, err := template.New("text").Option("missingkey=zero").Parse(escapes.Replace(cfg.TextColumns))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse text columns template: %w", err)
+		}
+		o.text = tmpl
+	}
+	return o, nil
+}
+
+// datasetRow is a row of a dataset, with its columns in order
+type datasetRow struct {
+	columns []string
+	values  map[string]string
+}
+
+// rowText builds the text of a row with the template or, without one, as a
+// "column: value" line for each column that has a value
+func (o *rowOptions) rowText(row datasetRow) (string, error) {
+	if o.text == nil {
+		var lines []string
+		for _, column := range row.columns {
+			if value := strings.TrimSpace(row.values[column]); value != "" {
+				lines = append(lines, column+": "+value)
+			}
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+
+	var b strings.Builder
+	if err := o.text.Execute(&b, row.values); err != nil {
+		return "", fmt.Errorf("failed to render text columns template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// rowExtractor extracts each row of a CSV, TSV or JSON Lines dataset as a
+// part of its own, so each row becomes its own record rather than being
+// chunked along with its neighbors. A row too long for the chunk size is
+// still split by the chunker. The first row of a delimited file names its
+// columns; JSON lines are objects whose keys name them, nested values kept
+// as JSON. With an ID column, every row must have it and no two rows may
+// share a value.
+type rowExtractor struct {
+	comma   rune // Field separator of delimited rows, 0 for JSON lines
+	options *rowOptions
+}
+
+// Extract implements Extractor
+func (e rowExtractor) Extract(data []byte) ([]ExtractedText, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	read := e.jsonRows
+	if e.comma != 0 {
+		read = e.delimitedRows
+	}
+
+	var parts []ExtractedText
+	ids := map[string]int{} // Row of each ID seen so far
+	rows := 0
+	err := read(data, func(row datasetRow) error {
+		rows++
+		text, err := e.options.rowText(row)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+
+		part := ExtractedText{Text: text}
+		if e.options.id != "" {
+			part.ID = strings.TrimSpace(row.values[e.options.id])
+			if first, ok := ids[part.ID]; ok {
+				return fmt.Errorf("duplicate ID %q in rows %d and %d", part.ID, first, rows)
+			}
+			if part.ID != "" {
+				ids[part.ID] = rows
+			}
+		}
+		for _, column := range e.options.metadata {
+			if value := row.values[column]; value != "" {
+				if part.Metadata == nil {
+					part.Metadata = map[string]string{}
+				}
+				part.Metadata[column] = value
+			}
+		}
+		parts = append(parts, part)
+		return nil
+	})
+	return parts, err
+}
+
+// delimitedRows reads the rows of a CSV or TSV file after its header row.
+// Rows may have fewer or more fields than the header; extra fields are
+// ignored.
+func (e rowExtractor) delimitedRows(data []byte, fn func(datasetRow) error) error {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = e.comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	if e.options.id != "" && !slices.Contains(header, e.options.id) {
+		return fmt.Errorf("missing ID column: %s", e.options.id)
+	}
+
+	for {
+		fields, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read row: %w", err)
+		}
+		row := datasetRow{columns: header, values: make(map[string]string, len(header))}
+		for i, column := range header {
+			if i < len(fields) {
+				row.values[column] = fields[i]
+			}
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// jsonRows reads the objects of a JSON Lines file, one per line, keeping
+// their keys in order. Blank lines are skipped.
+func (e rowExtractor) jsonRows(data []byte, fn func(datasetRow) error) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		row, err := parseJSONRow(text)
+		if err != nil {
+			return fmt.Errorf("failed to parse line %d: %w", line, err)
+		}
+		if _, ok := row.values[e.options.id]; e.options.id != "" && !ok {
+			return fmt.Errorf("missing ID column on line %d: %s", line, e.options.id)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parseJSONRow parses a JSON object into a row. Strings are kept as they
+// are, null as "" and other values as compact JSON.
+func parseJSONRow(data []byte) (datasetRow, error) {
+	row := datasetRow{values: map[string]string{}}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return row, errors.New("not a JSON object")
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return row, err
+		}
+		key, _ := token.(string)
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return row, err
+		}
+
+		var value string
+		switch {
+		case raw[0] == '"':
+			json.Unmarshal(raw, &value)
+		case string(raw) != "null":
+			var b bytes.Buffer
+			json.Compact(&b, raw)
+			value = b.String()
+		}
+		if _, ok := row.values[key]; !ok {
+			row.columns = append(row.columns, key)
+		}
+		row.values[key] = value
+	}
+	if _, err := decoder.Token(); err != nil {
+		return row, err
+	}
+	return row, nil
+}
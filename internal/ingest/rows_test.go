@@ -0,0 +1,145 @@
+package ingest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"wafer/internal/config"
+)
+
+func TestRowExtractor(t *testing.T) {
+	tests := []struct {
+		name      string
+		comma     rune
+		cfg       config.Config
+		data      string
+		want      []string // Text of each row
+		wantIDs   []string
+		wantMeta  []map[string]string
+		wantError bool
+	}{
+		{
+			name:  "csv with every column",
+			comma: ',',
+			data:  "\ufeffsku, name ,price\nA1,\"Desk, oak\",120\nA2,,\n\nA3,Lamp\n",
+			want:  []string{"sku: A1\nname: Desk, oak\nprice: 120", "sku: A2", "sku: A3\nname: Lamp"},
+		},
+		{
+			name:     "csv with a template, id and metadata",
+			comma:    ',',
+			cfg:      config.Config{TextColumns: `{{.name}}\n{{index . "long text"}}{{.missing}}`, IDColumn: "sku", MetadataColumns: []string{"category", "unknown"}},
+			data:     "sku,name,long text,category\nA1,Desk,\"Solid oak,\nhand finished\",furniture\n,Lamp,Brass,\n",
+			want:     []string{"Desk\nSolid oak,\nhand finished", "Lamp\nBrass"},
+			wantIDs:  []string{"A1", ""},
+			wantMeta: []map[string]string{{"category": "furniture"}, nil},
+		},
+		{
+			name:      "missing id column",
+			comma:     ',',
+			cfg:       config.Config{IDColumn: "id"},
+			data:      "sku,name\nA1,Desk\n",
+			wantError: true,
+		},
+		{
+			name:    "tsv",
+			comma:   '\t',
+			cfg:     config.Config{TextColumns: "{{.subject}}: {{.body}}", IDColumn: "ticket"},
+			data:    "ticket\tsubject\tbody\n101\tLogin fails\tReset link \"expired\"\n",
+			want:    []string{"Login fails: Reset link \"expired\""},
+			wantIDs: []string{"101"},
+		},
+		{
+			name:     "json lines keep key order and nested values",
+			cfg:      config.Config{IDColumn: "id", MetadataColumns: []string{"tags"}},
+			data:     "{\"id\": 7, \"title\": \"Refund\", \"tags\": [\"billing\", \"urgent\"], \"closed\": true, \"note\": null}\n\n{\"id\": null, \"title\": \"Hello\"}\n",
+			want:     []string{"id: 7\ntitle: Refund\ntags: [\"billing\",\"urgent\"]\nclosed: true", "title: Hello"},
+			wantIDs:  []string{"7", ""},
+			wantMeta: []map[string]string{{"tags": `["billing","urgent"]`}, nil},
+		},
+		{
+			name:      "json line without the id column",
+			cfg:       config.Config{IDColumn: "id"},
+			data:      "{\"id\": 7, \"title\": \"Refund\"}\n{\"title\": \"Hello\"}\n",
+			wantError: true,
+		},
+		{
+			name:      "duplicate csv ids",
+			comma:     ',',
+			cfg:       config.Config{IDColumn: "sku"},
+			data:      "sku,name\nA1,Desk\nA2,Lamp\n A1 ,Chair\n",
+			wantError: true,
+		},
+		{
+			name:      "duplicate json ids",
+			cfg:       config.Config{IDColumn: "id"},
+			data:      "{\"id\": 7, \"title\": \"Refund\"}\n{\"id\": \"7\", \"title\": \"Hello\"}\n",
+			wantError: true,
+		},
+		{
+			name:    "empty ids may repeat",
+			comma:   ',',
+			cfg:     config.Config{IDColumn: "sku"},
+			data:    "sku,name\n,Desk\n,Lamp\n",
+			want:    []string{"name: Desk", "name: Lamp"},
+			wantIDs: []string{"", ""},
+		},
+		{
+			name:      "json line that is not an object",
+			data:      "{\"title\": \"Refund\"}\n[1, 2]\n",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, err := newRowOptions(&tt.cfg)
+			if err != nil {
+				t.Fatalf("newRowOptions() error = %v", err)
+			}
+			parts, err := rowExtractor{comma: tt.comma, options: options}.Extract([]byte(tt.data))
+			if tt.wantError {
+				if err == nil {
+					t.Error("Extract() should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+
+			var got, ids []string
+			var meta []map[string]string
+			for _, part := range parts {
+				got = append(got, part.Text)
+				ids = append(ids, part.ID)
+				meta = append(meta, part.Metadata)
+			}
+			if strings.Join(got, "\f") != strings.Join(tt.want, "\f") {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+			if tt.wantIDs != nil && fmt.Sprint(ids) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("ids = %q, want %q", ids, tt.wantIDs)
+			}
+			if tt.wantMeta != nil && fmt.Sprint(meta) != fmt.Sprint(tt.wantMeta) {
+				t.Errorf("metadata = %v, want %v", meta, tt.wantMeta)
+			}
+		})
+	}
+
+	if _, err := newRowOptions(&config.Config{TextColumns: "{{.name"}); err == nil {
+		t.Error("newRowOptions() with a malformed template should fail")
+	}
+}
+
+func TestExtractedText_ChunkID(t *testing.T) {
+	part := ExtractedText{ID: "A1"}
+	for n, want := range []string{"A1", "A1#2", "A1#3"} {
+		if got := part.chunkID(n); got != want {
+			t.Errorf("chunkID(%d) = %q, want %q", n, got, want)
+		}
+	}
+	if got := (ExtractedText{}).chunkID(1); got != "" {
+		t.Errorf("chunkID() without an ID = %q", got)
+	}
+}
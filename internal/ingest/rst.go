@@ -0,0 +1,219 @@
+package ingest
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// rstAdornments are the characters reStructuredText section titles may be
+// underlined and overlined with
+const rstAdornments = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+var (
+	rstDirectiveRE = regexp.MustCompile(`^\.\.\s+([\w:+-]+)::\s*(.*)$`)
+	rstOptionRE    = regexp.MustCompile(`^:[\w -]+:`)
+	rstLiteralRE   = regexp.MustCompile("``([^`]+)``")
+	rstRoleRE      = regexp.MustCompile(":[\\w.+-]+:`([^`]*?)(?:\\s*<[^>]*>)?`")
+	rstLinkRE      = regexp.MustCompile("`([^`<]*?)\\s*<[^>]*>`_{1,2}")
+	rstReferenceRE = regexp.MustCompile("`([^`]+)`_{0,2}")
+	rstStrongRE    = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	rstEmphasisRE  = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+)
+
+// rstCodeDirectives hold source code, kept as fenced code blocks
+var rstCodeDirectives = map[string]bool{
+	"code":           true,
+	"code-block":     true,
+	"sourcecode":     true,
+	"doctest":        true,
+	"parsed-literal": true,
+}
+
+// rstTextDirectives hold body text, such as admonitions, kept as paragraphs
+var rstTextDirectives = map[string]bool{
+	"admonition": true, "attention": true, "caution": true, "danger": true,
+	"error": true, "hint": true, "important": true, "note": true,
+	"tip": true, "warning": true, "seealso": true, "topic": true,
+	"sidebar": true, "rubric": true, "epigraph": true, "figure": true,
+	"deprecated": true, "versionadded": true, "versionchanged": true,
+}
+
+// rstExtractor converts reStructuredText to Markdown: section titles become
+// Markdown headings, so chunks get their section, literal blocks and code
+// directives become fenced code, and comments, targets and directives
+// without text are dropped. The first title is kept as metadata.
+type rstExtractor struct{}
+
+// Extract implements Extractor
+func (rstExtractor) Extract(data []byte) ([]ExtractedText, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	var out []string
+	var styles []string // Adornment styles in the order titles use them
+	var title string
+
+	heading := func(text, style string) {
+		level := 0
+		for level < len(styles) && styles[level] != style {
+			level++
+		}
+		if level == len(styles) {
+			styles = append(styles, style)
+		}
+		text = rstInline(strings.TrimSpace(text))
+		if title == "" {
+			title = text
+		}
+		out = append(out, "", strings.Repeat("#", min(level+1, 6))+" "+text, "")
+	}
+	fence := func(block []string) {
+		out = append(out, "", "```")
+		out = append(out, block...)
+		out = append(out, "```", "")
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+
+		// Titles with an overline and an underline, then underlined titles
+		if isRSTAdornment(line) && i+2 < len(lines) && strings.TrimRight(lines[i+2], " \t") == line &&
+			strings.TrimSpace(lines[i+1]) != "" {
+			heading(lines[i+1], line[:1]+"/")
+			i += 2
+			continue
+		}
+		if line != "" && !isIndented(line) && !isRSTAdornment(line) && i+1 < len(lines) &&
+			(i == 0 || strings.TrimSpace(lines[i-1]) == "") {
+			next := strings.TrimRight(lines[i+1], " \t")
+			if isRSTAdornment(next) && len(next) >= utf8.RuneCountInString(line) {
+				heading(line, next[:1])
+				i++
+				continue
+			}
+		}
+
+		// Explicit markup: directives, comments, targets and substitutions
+		if strings.HasPrefix(line, "..") && (len(line) == 2 || line[2] == ' ') {
+			block, end := rstIndentedBlock(lines, i+1)
+			i = end - 1
+			m := rstDirectiveRE.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			name := strings.ToLower(m[1])
+			switch {
+			case rstCodeDirectives[name]:
+				fence(rstBody(block))
+			case rstTextDirectives[name]:
+				if args := strings.TrimSpace(m[2]); args != "" && name != "figure" {
+					out = append(out, "", rstInline(args))
+				}
+				out = append(out, "")
+				for _, body := range rstBody(block) {
+					out = append(out, rstInline(body))
+				}
+				out = append(out, "")
+			}
+			continue
+		}
+
+		// A paragraph ending in "::" introduces a literal block
+		if strings.HasSuffix(line, "::") {
+			block, end := rstIndentedBlock(lines, i+1)
+			if len(block) > 0 {
+				if text := strings.TrimSpace(strings.TrimSuffix(line, ":")); text != ":" {
+					out = append(out, rstInline(text))
+				}
+				fence(dedent(block))
+				i = end - 1
+				continue
+			}
+		}
+
+		out = append(out, rstInline(line))
+	}
+
+	part := ExtractedText{Text: cleanLines(strings.Join(out, "\n")), Markdown: true}
+	if title != "" {
+		part.Metadata = map[string]string{"title": title}
+	}
+	return []ExtractedText{part}, nil
+}
+
+// isRSTAdornment reports whether line is a run of at least two of the same
+// adornment character
+func isRSTAdornment(line string) bool {
+	if len(line) < 2 || !strings.ContainsRune(rstAdornments, rune(line[0])) {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+// isIndented reports whether line starts with whitespace
+func isIndented(line string) bool {
+	return line != "" && (line[0] == ' ' || line[0] == '\t')
+}
+
+// rstIndentedBlock returns the lines from start that are indented or blank,
+// without leading and trailing blank lines, and the index of the first line
+// after them
+func rstIndentedBlock(lines []string, start int) ([]string, int) {
+	end := start
+	for end < len(lines) && (isIndented(lines[end]) || strings.TrimSpace(lines[end]) == "") {
+		end++
+	}
+	last := end
+	for last > start && strings.TrimSpace(lines[last-1]) == "" {
+		last--
+	}
+	first := start
+	for first < last && strings.TrimSpace(lines[first]) == "" {
+		first++
+	}
+	return lines[first:last], last
+}
+
+// rstBody returns the content of a directive block, dedented and without
+// its options or the blank line after them
+func rstBody(block []string) []string {
+	body := dedent(block)
+	for len(body) > 0 && rstOptionRE.MatchString(body[0]) {
+		body = body[1:]
+	}
+	for len(body) > 0 && body[0] == "" {
+		body = body[1:]
+	}
+	return body
+}
+
+// dedent removes the indentation the non-blank lines share
+func dedent(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			line = line[indent:]
+		}
+		out[i] = strings.TrimRight(line, " \t")
+	}
+	return out
+}
+
+// rstInline strips inline markup, keeping the text it marks up
+func rstInline(line string) string {
+	line = rstLiteralRE.ReplaceAllString(line, "$1")
+	line = rstRoleRE.ReplaceAllString(line, "$1")
+	line = rstLinkRE.ReplaceAllString(line, "$1")
+	line = rstReferenceRE.ReplaceAllString(line, "$1")
+	line = rstStrongRE.ReplaceAllString(line, "$1")
+	return rstEmphasisRE.ReplaceAllString(line, "$1")
+}
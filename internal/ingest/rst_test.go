@@ -0,0 +1,54 @@
+package ingest
+
+import "testing"
+
+func TestRSTExtractor(t *testing.T) {
+	tests := []struct {
+		name      string
+		rst       string
+		want      string
+		wantTitle string
+	}{
+		{
+			name:      "section titles",
+			rst:       "=====\nGuide\n=====\n\nIntro text.\n\nInstall\n-------\n\nSteps.\n\nLinux\n~~~~~\n\nUse apt.\n\nUsage\n-------\n\nRun it.\n",
+			want:      "# Guide\n\nIntro text.\n\n## Install\n\nSteps.\n\n### Linux\n\nUse apt.\n\n## Usage\n\nRun it.",
+			wantTitle: "Guide",
+		},
+		{
+			name: "inline markup",
+			rst:  "Call ``ingest()`` with **care**, see :ref:`the setup <setup>` and `Go <https://go.dev>`_ or *docs*.\n",
+			want: "Call ingest() with care, see the setup and Go or docs.",
+		},
+		{
+			name: "literal block",
+			rst:  "Example::\n\n    wafer ingest ./docs\n      --model x\n\nDone.\n",
+			want: "Example:\n\n```\nwafer ingest ./docs\n  --model x\n```\n\nDone.",
+		},
+		{
+			name: "directives and comments",
+			rst:  ".. _setup:\n\n.. note:: Back up first.\n\n   It takes a while.\n\n.. code-block:: bash\n   :linenos:\n\n   make build\n\n.. image:: logo.png\n   :alt: Logo\n\n.. A comment\n   spanning lines\n\nText.\n",
+			want: "Back up first.\n\nIt takes a while.\n\n```\nmake build\n```\n\nText.",
+		},
+		{
+			name: "transition is not a title",
+			rst:  "Before.\n\n----------\n\nAfter.\n",
+			want: "Before.\n\n----------\n\nAfter.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := rstExtractor{}.Extract([]byte(tt.rst))
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if got := parts[0].Text; got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+			if got := parts[0].Metadata["title"]; got != tt.wantTitle {
+				t.Errorf("title = %q, want %q", got, tt.wantTitle)
+			}
+		})
+	}
+}
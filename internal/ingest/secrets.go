@@ -0,0 +1,207 @@
+package ingest
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Placeholder types of the secret detectors
+const (
+	SecretPrivateKey    = "PRIVATE_KEY"
+	SecretAWSAccessKey  = "AWS_ACCESS_KEY_ID"
+	SecretAWSSecretKey  = "AWS_SECRET_ACCESS_KEY"
+	SecretGitHubToken   = "GITHUB_TOKEN"
+	SecretJWT           = "JWT"
+	SecretGenericSecret = "GENERIC_SECRET"
+)
+
+// pemLabel matches the label of a PEM private key, such as "RSA PRIVATE KEY"
+const pemLabel = `(?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?`
+
+// secretRules find credentials. A private key is matched from its BEGIN line
+// to its END line, or to the end of a chunk that cuts it off; a chunk that
+// starts inside a key is matched up to its END line.
+var secretRules = []redactRule{
+	{
+		kind: SecretPrivateKey,
+		re:   regexp.MustCompile(`-----BEGIN ` + pemLabel + `-----(?:[\s\S]*?-----END ` + pemLabel + `-----|[\s\S]*)`),
+	},
+	{
+		kind: SecretPrivateKey,
+		re:   regexp.MustCompile(`\A\s*(?:[A-Za-z0-9+/=]{16,}\s+)+-----END ` + pemLabel + `-----`),
+	},
+	{
+		kind:  SecretAWSAccessKey,
+		re:    regexp.MustCompile(`\b((?:AKIA|ASIA|ABIA|ACCA)[A-Z0-9]{16})\b`),
+		valid: minEntropy(3),
+	},
+	{
+		kind:  SecretAWSSecretKey,
+		re:    regexp.MustCompile(`(?i)aws[\w.-]{0,20}?(?:secret|private)[\w.-]{0,20}?["']?\s*[:=]\s*["']?([A-Za-z0-9/+]{40})(?:[^A-Za-z0-9/+=]|$)`),
+		valid: minEntropy(4),
+	},
+	{
+		kind:  SecretGitHubToken,
+		re:    regexp.MustCompile(`\b((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,251}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+		valid: minEntropy(3.5),
+	},
+	{
+		kind:  SecretJWT,
+		re:    regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{16,})`),
+		valid: validJWT,
+	},
+	{
+		kind:  SecretGenericSecret,
+		re:    regexp.MustCompile(`(?i)(?:api[_-]?key|client[_-]?secret|secret[_-]?key|access[_-]?token|auth[_-]?token|password|passwd|secret|token)["']?\s*[:=]\s*["']?([A-Za-z0-9/+_.~-]{16,})`),
+		valid: validGenericSecret,
+	},
+}
+
+// NewSecretScanner creates a redactor that finds credentials rather than
+// personal data
+func NewSecretScanner() *Redactor {
+	return &Redactor{rules: secretRules}
+}
+
+// shannonEntropy returns the entropy of the characters of s in bits per
+// character; random keys score high, words and placeholders low
+func shannonEntropy(s string) float64 {
+	counts := map[rune]int{}
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// minEntropy confirms candidates whose entropy reaches bits per character
+func minEntropy(bits float64) func(string) bool {
+	return func(s string) bool {
+		return shannonEntropy(s) >= bits
+	}
+}
+
+// validJWT reports whether s is a JSON Web Token: its header must decode to a
+// JSON object naming a signing algorithm
+func validJWT(s string) bool {
+	header, err := base64.RawURLEncoding.DecodeString(s[:strings.IndexByte(s, '.')])
+	if err != nil {
+		return false
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(header, &fields); err != nil {
+		return false
+	}
+	_, ok := fields["alg"]
+	return ok
+}
+
+// validGenericSecret reports whether a value assigned to a secret-sounding
+// name looks random: mixed letters and digits of high entropy
+func validGenericSecret(s string) bool {
+	return strings.ContainsAny(s, "0123456789") &&
+		strings.ContainsFunc(s, func(r rune) bool { return r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' }) &&
+		shannonEntropy(s) >= 3.5
+}
+
+// scanSecretLines returns the secrets found in r, read one line at a time,
+// with the 1-based line each one starts on
+func scanSecretLines(scanner *Redactor, r io.Reader) ([]SecretFinding, error) {
+	var findings []SecretFinding
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		text, err := reader.ReadString('\n')
+		for _, m := range scanner.find(text) {
+			findings = append(findings, newSecretFinding(m.kind, text[m.start:m.end], line))
+		}
+		if err == io.EOF {
+			return findings, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan file for secrets: %w", err)
+		}
+	}
+}
+
+// SecretFinding is one secret recorded in the secret report. It names the
+// secret's type and fingerprint but never holds the secret itself.
+type SecretFinding struct {
+	SourceFile  string `json:"source_file"`
+	ChunkIndex  *int   `json:"chunk_index,omitempty"`
+	PageNumber  int    `json:"page_number,omitempty"`
+	Line        int    `json:"line"`
+	Field       string `json:"field,omitempty"` // Metadata key the secret was found in, "" for the text
+	Type        string `json:"type"`
+	Fingerprint string `json:"fingerprint"`
+	Action      string `json:"action"`
+	FoundAt     string `json:"found_at"`
+}
+
+// newSecretFinding describes a secret found on a line
+func newSecretFinding(kind, secret string, line int) SecretFinding {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(secret)))
+	return SecretFinding{
+		Line:        line,
+		Type:        kind,
+		Fingerprint: "sha256:" + hex.EncodeToString(sum[:8]),
+		FoundAt:     time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// SecretReportPath returns the secret report kept next to an output file,
+// such as "storage/vectors.secrets.jsonl" for "storage/vectors.jsonl"
+func SecretReportPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".secrets.jsonl"
+}
+
+// SecretReport appends secret findings to a JSONL file readable only by its
+// owner, keeping them out of the logs
+type SecretReport struct {
+	file *os.File
+}
+
+// NewSecretReport opens the secret report at path for appending
+func NewSecretReport(path string) (*SecretReport, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create secret report directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open secret report: %w", err)
+	}
+	return &SecretReport{file: file}, nil
+}
+
+// Write appends a finding to the report
+func (r *SecretReport) Write(finding SecretFinding) error {
+	data, err := json.Marshal(finding)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secret finding: %w", err)
+	}
+	if _, err := r.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write secret finding: %w", err)
+	}
+	return nil
+}
+
+// Close closes the report file
+func (r *SecretReport) Close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,108 @@
+package ingest
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Word classes used to segment text written without spaces
+const (
+	classNone     = iota // Punctuation and symbols, which attach to a neighboring word
+	classMark            // Combining marks, which extend the character before them
+	classLetter          // Letters and digits of space-delimited scripts
+	classKatakana        // Katakana, whose runs form a single word
+	classIsolated        // Han, hiragana and South-East Asian scripts, one word per character
+)
+
+// complexScripts are written without spaces between words and, lacking a
+// dictionary, are segmented one character cluster at a time
+var complexScripts = []*unicode.RangeTable{
+	unicode.Han, unicode.Hiragana, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar,
+}
+
+// wordSpans returns the words of text[region.start:region.end] that contain at
+// least one letter or digit, as offsets into text. Words are separated by
+// whitespace and, following UAX #29, at every Han ideograph or hiragana and
+// around katakana runs, so Chinese and Japanese split without spaces.
+func wordSpans(text string, region span) []span {
+	var words []span
+	start := -1
+
+	for i, r := range text[region.start:region.end] {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				words = appendSegments(words, text, span{region.start + start, region.start + i})
+			}
+			start = -1
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = appendSegments(words, text, span{region.start + start, region.end})
+	}
+
+	return words
+}
+
+// appendSegments appends the valid words of a run of text without whitespace
+func appendSegments(words []span, text string, run span) []span {
+	start := run.start
+	prev := classNone // Class of the last letter in the current word
+
+	for i := run.start; i < run.end; {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		class := wordClass(r)
+
+		if class > classMark {
+			// Leading punctuation stays with the first letter that follows it
+			if prev != classNone && (class == classIsolated || prev == classIsolated || class != prev) {
+				if isValidWord(text[start:i]) {
+					words = append(words, span{start, i})
+				}
+				start = i
+			}
+			prev = class
+		}
+		i += size
+	}
+
+	if isValidWord(text[start:run.end]) {
+		words = append(words, span{start, run.end})
+	}
+	return words
+}
+
+// wordClass classifies a rune for word segmentation
+func wordClass(r rune) int {
+	switch {
+	case r < utf8.RuneSelf:
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return classLetter
+		}
+		return classNone
+	case unicode.In(r, unicode.Mn, unicode.Mc, unicode.Me):
+		return classMark
+	case unicode.Is(unicode.Katakana, r) || r == 'ー' || r == 'ｰ':
+		return classKatakana
+	case unicode.In(r, complexScripts...):
+		return classIsolated
+	case unicode.IsLetter(r) || unicode.IsDigit(r):
+		return classLetter
+	}
+	return classNone
+}
+
+// joinWords joins words with a single space where whitespace or punctuation
+// separated them in text, and without one where they were written together
+func joinWords(text string, words []span) string {
+	var b strings.Builder
+	for i, word := range words {
+		if i > 0 && words[i-1].end < word.start {
+			b.WriteByte(' ')
+		}
+		b.WriteString(text[word.start:word.end])
+	}
+	return b.String()
+}
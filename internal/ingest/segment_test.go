@@ -0,0 +1,72 @@
+package ingest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"wafer/internal/config"
+)
+
+func TestWordSpans_Scripts(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"latin keeps punctuation", "Hello, world! It's 3.14.", []string{"Hello,", "world!", "It's", "3.14."}},
+		{"chinese per ideograph", "我爱北京。", []string{"我", "爱", "北", "京。"}},
+		{"japanese kana", "カタカナとひらがな", []string{"カタカナ", "と", "ひ", "ら", "が", "な"}},
+		{"prolonged sound mark", "コーヒーを飲む", []string{"コーヒー", "を", "飲", "む"}},
+		{"mixed latin and han", "GPT-4を使う「テスト」", []string{"GPT-4", "を", "使", "う「", "テスト」"}},
+		{"leading bracket", "「東京」", []string{"「東", "京」"}},
+		{"thai clusters", "ที่นี่", []string{"ที่", "นี่"}},
+		{"korean uses spaces", "한국어 문장", []string{"한국어", "문장"}},
+		{"digits before han", "2024年", []string{"2024", "年"}},
+		{"punctuation only", "— ... 。", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tokenizeWords(tt.text)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenizeWords(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinWords(t *testing.T) {
+	text := "東京は  大きい。 Big  city"
+	if got, want := joinWords(text, wordSpans(text, span{0, len(text)})), "東京は 大きい。 Big city"; got != want {
+		t.Errorf("joinWords() = %q, want %q", got, want)
+	}
+}
+
+func TestChunker_ChunkText_CJK(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 10})
+
+	text := strings.Repeat("日本語の文章には空白がありません。", 5)
+	chunks := chunker.ChunkText(text)
+
+	if len(chunks) < 8 {
+		t.Fatalf("ChunkText() got %d chunks, want the text split by characters", len(chunks))
+	}
+
+	var rebuilt strings.Builder
+	for _, chunk := range chunks {
+		if chunk.WordCount > 10 {
+			t.Errorf("chunk %d has %d words, exceeding the budget", chunk.Index, chunk.WordCount)
+		}
+		if strings.Contains(chunk.EmbeddingText, " ") {
+			t.Errorf("chunk %d embedding text gained spaces: %q", chunk.Index, chunk.EmbeddingText)
+		}
+		rebuilt.WriteString(chunk.Text)
+	}
+	if rebuilt.String() != text {
+		t.Errorf("chunks do not cover the text: %q", rebuilt.String())
+	}
+}
@@ -0,0 +1,157 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+)
+
+// TextEmbedder produces an embedding vector for a text
+type TextEmbedder interface {
+	GetEmbedding(ctx context.Context, text string) ([]float64, error)
+}
+
+// semanticStrategy embeds every sentence together with its neighbors and
+// cuts wherever the cosine distance between consecutive sentence windows
+// rises above a percentile of all distances in the document. Chunks still
+// never exceed the budget, and a topic shift only ends a chunk of at least
+// minSize.
+type semanticStrategy struct {
+	budget     *budget
+	embedder   TextEmbedder
+	percentile float64 // Distance percentile that marks a topic shift
+	window     int     // Neighboring sentences on each side embedded with a sentence
+	minSize    int     // Smallest chunk cost a topic shift may end
+}
+
+// Split implements Strategy
+func (s *semanticStrategy) Split(text string) []Chunk {
+	var sentences []sentenceSpan
+	for _, sentence := range splitSentences(text) {
+		words := wordSpans(text, sentence)
+		if len(words) == 0 {
+			continue
+		}
+		sentences = append(sentences, sentenceSpan{
+			span:  sentence,
+			words: len(words),
+			cost:  sum(s.budget.spanCosts(text, words)),
+		})
+	}
+
+	shifts, err := s.topicShifts(text, sentences)
+	if err != nil {
+		slog.Warn("Semantic chunking unavailable, packing sentences by size", "error", err)
+	}
+
+	var chunks []Chunk
+	var current []sentenceSpan
+	currentCost := 0
+
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		chunk := Chunk{
+			Text:      text[current[0].start:current[len(current)-1].end],
+			StartByte: current[0].start,
+			EndByte:   current[len(current)-1].end,
+		}
+		for _, sentence := range current {
+			chunk.WordCount += sentence.words
+		}
+		chunks = append(chunks, chunk)
+		current, currentCost = current[:0], 0
+	}
+
+	for i, sentence := range sentences {
+		// Only a sentence that cannot fit on its own is split mid-sentence
+		if sentence.cost > s.budget.size {
+			flush()
+			words := wordSpans(text, sentence.span)
+			costs := s.budget.spanCosts(text, words)
+			chunks = append(chunks, packWords(text, sentence.span, words, costs, s.budget.size, 0)...)
+			continue
+		}
+
+		if len(current) > 0 &&
+			(currentCost+sentence.cost > s.budget.size || (shifts[i] && currentCost >= s.minSize)) {
+			flush()
+		}
+
+		current = append(current, sentence)
+		currentCost += sentence.cost
+	}
+	flush()
+
+	return chunks
+}
+
+// topicShifts reports, for each sentence, whether the distance from the
+// sentence before it is above the percentile threshold. Without an embedder
+// or with too few sentences to compare, no shifts are found.
+func (s *semanticStrategy) topicShifts(text string, sentences []sentenceSpan) ([]bool, error) {
+	shifts := make([]bool, len(sentences))
+	if s.embedder == nil || len(sentences) < 3 {
+		return shifts, nil
+	}
+
+	vectors := make([][]float64, len(sentences))
+	for i := range sentences {
+		first := max(0, i-s.window)
+		last := min(len(sentences)-1, i+s.window)
+
+		vector, err := s.embedder.GetEmbedding(context.Background(), text[sentences[first].start:sentences[last].end])
+		if err != nil {
+			return shifts, fmt.Errorf("failed to embed sentence %d: %w", i, err)
+		}
+		vectors[i] = vector
+	}
+
+	distances := make([]float64, len(sentences)-1)
+	for i := range distances {
+		distances[i] = 1 - cosineSimilarity(vectors[i], vectors[i+1])
+	}
+
+	threshold := percentile(distances, s.percentile)
+	for i, distance := range distances {
+		if distance > threshold {
+			shifts[i+1] = true
+		}
+	}
+
+	return shifts, nil
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors, or 0
+// when either is empty or zero
+func cosineSimilarity(a, b []float64) float64 {
+	var dot, normA, normB float64
+	for i := 0; i < len(a) && i < len(b); i++ {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// percentile returns the p-th percentile of values, interpolating linearly
+// between the closest ranks
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
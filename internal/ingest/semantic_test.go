@@ -0,0 +1,128 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"wafer/internal/config"
+)
+
+// topicEmbedder embeds text as counts of cat and market words, so sentences
+// about the same topic point the same way
+type topicEmbedder struct {
+	calls int
+	err   error
+}
+
+func (e *topicEmbedder) GetEmbedding(_ context.Context, text string) ([]float64, error) {
+	e.calls++
+	if e.err != nil {
+		return nil, e.err
+	}
+	text = strings.ToLower(text)
+	return []float64{
+		float64(strings.Count(text, "cat")),
+		float64(strings.Count(text, "market")),
+	}, nil
+}
+
+const topicText = "The cat sleeps on the sofa. A cat likes warm places. Every cat purrs when happy. " +
+	"The market fell sharply today. Investors fear the market will slide. Analysts expect the market to recover."
+
+func semanticChunker(cfg config.Config, embedder TextEmbedder) *Chunker {
+	cfg.ChunkStrategy = config.StrategySemantic
+	chunker := NewChunker(&cfg)
+	chunker.SetEmbedder(embedder)
+	return chunker
+}
+
+func TestSemanticStrategy_CutsAtTopicShift(t *testing.T) {
+	embedder := &topicEmbedder{}
+	chunks := semanticChunker(config.Config{ChunkSize: 100}, embedder).ChunkText(topicText)
+
+	if len(chunks) != 2 {
+		t.Fatalf("ChunkText() got %d chunks, want 2: %+v", len(chunks), chunks)
+	}
+	if !strings.HasSuffix(chunks[0].Text, "purrs when happy.") || !strings.HasPrefix(chunks[1].Text, "The market fell") {
+		t.Errorf("cut in the wrong place: %q | %q", chunks[0].Text, chunks[1].Text)
+	}
+	if embedder.calls != 6 {
+		t.Errorf("embedded %d sentence windows, want 6", embedder.calls)
+	}
+}
+
+func TestSemanticStrategy_Guards(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.Config
+		wantChunks int
+	}{
+		{"max size still cuts within a topic", config.Config{ChunkSize: 12}, 4},
+		{"min size suppresses an early shift", config.Config{ChunkSize: 100, SemanticMinSize: 20}, 1},
+		{"threshold of 100 never cuts", config.Config{ChunkSize: 100, SemanticThreshold: 100}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := semanticChunker(tt.cfg, &topicEmbedder{}).ChunkText(topicText)
+			if len(chunks) != tt.wantChunks {
+				t.Errorf("ChunkText() got %d chunks, want %d", len(chunks), tt.wantChunks)
+			}
+			for _, chunk := range chunks {
+				if chunk.WordCount > tt.cfg.ChunkSize {
+					t.Errorf("chunk %d has %d words, over the budget", chunk.Index, chunk.WordCount)
+				}
+			}
+		})
+	}
+}
+
+func TestSemanticStrategy_EmbedderFailure(t *testing.T) {
+	chunks := semanticChunker(config.Config{ChunkSize: 100}, &topicEmbedder{err: errors.New("offline")}).ChunkText(topicText)
+
+	if len(chunks) != 1 || chunks[0].Text != topicText {
+		t.Errorf("ChunkText() should fall back to packing by size, got %+v", chunks)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{0.4, 0.1, 0.3, 0.2, 0.5}
+
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 0.1},
+		{50, 0.3},
+		{90, 0.46},
+		{100, 0.5},
+	}
+
+	for _, tt := range tests {
+		if got := percentile(values, tt.p); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"same direction", []float64{1, 2}, []float64{2, 4}, 1},
+		{"orthogonal", []float64{1, 0}, []float64{0, 3}, 0},
+		{"opposite", []float64{1, 1}, []float64{-1, -1}, -1},
+		{"zero vector", []float64{0, 0}, []float64{1, 1}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := cosineSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: cosineSimilarity() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package ingest
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// abbreviations never end a sentence even when followed by a capitalized word
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "sr": true,
+	"jr": true, "st": true, "mt": true, "vs": true, "cf": true, "e.g": true,
+	"i.e": true, "fig": true, "vol": true, "approx": true,
+	"dept": true, "est": true, "inc": true, "ltd": true, "co": true, "corp": true,
+	"jan": true, "feb": true, "mar": true, "apr": true, "jun": true, "jul": true,
+	"aug": true, "sep": true, "sept": true, "oct": true, "nov": true, "dec": true,
+}
+
+// ambiguousAbbreviations end a sentence only when the next word is capitalized
+var ambiguousAbbreviations = map[string]bool{
+	"etc": true, "al": true, "u.s": true, "u.k": true, "a.m": true, "p.m": true,
+}
+
+// span marks a region of text by byte offsets
+type span struct {
+	start int
+	end   int
+}
+
+// sentenceStrategy packs whole sentences into chunks up to the budget,
+// starting each chunk with the last overlap sentences of the previous one
+type sentenceStrategy struct {
+	budget *budget
+}
+
+// sentenceSpan is a sentence together with its size
+type sentenceSpan struct {
+	span
+	words int
+	cost  int
+}
+
+// Split implements Strategy
+func (s *sentenceStrategy) Split(text string) []Chunk {
+	var chunks []Chunk
+	var current []sentenceSpan
+	currentCost := 0
+	carried := 0 // Leading sentences of current repeated from the previous chunk
+
+	flush := func() {
+		if len(current) == carried {
+			return
+		}
+
+		chunk := Chunk{
+			Text:      text[current[0].start:current[len(current)-1].end],
+			StartByte: current[0].start,
+			EndByte:   current[len(current)-1].end,
+		}
+		for i, sentence := range current {
+			chunk.WordCount += sentence.words
+			if i < carried {
+				chunk.OverlapWords += sentence.words
+			}
+		}
+		if carried > 0 {
+			chunk.OverlapChars = utf8.RuneCountInString(text[current[0].start:current[carried-1].end])
+		}
+		chunks = append(chunks, chunk)
+
+		// Carry the tail of this chunk into the next one
+		keep := s.budget.overlap
+		if keep > len(current) {
+			keep = len(current)
+		}
+		current = append(current[:0:0], current[len(current)-keep:]...)
+		carried = len(current)
+		currentCost = 0
+		for _, sentence := range current {
+			currentCost += sentence.cost
+		}
+	}
+
+	for _, sentence := range splitSentences(text) {
+		words := wordSpans(text, sentence)
+		if len(words) == 0 {
+			continue
+		}
+		costs := s.budget.spanCosts(text, words)
+		cost := sum(costs)
+
+		// Only a sentence that cannot fit on its own is split mid-sentence
+		if cost > s.budget.size {
+			flush()
+			pieces := packWords(text, sentence, words, costs, s.budget.size, 0)
+			for i := 1; i < len(pieces); i++ {
+				pieces[i].continues = true
+			}
+			chunks = append(chunks, pieces...)
+			current, carried, currentCost = nil, 0, 0
+			continue
+		}
+
+		if currentCost+cost > s.budget.size {
+			flush()
+		}
+
+		// Drop carried sentences until the new sentence fits the budget
+		for carried > 0 && currentCost+cost > s.budget.size {
+			currentCost -= current[0].cost
+			current = current[1:]
+			carried--
+		}
+
+		current = append(current, sentenceSpan{span: sentence, words: len(words), cost: cost})
+		currentCost += cost
+	}
+	flush()
+
+	return chunks
+}
+
+// splitSentences returns the byte spans of the sentences in text
+func splitSentences(text string) []span {
+	var sentences []span
+	start := skipSpace(text, 0)
+
+	for i := start; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !isTerminator(r) {
+			i += size
+			continue
+		}
+
+		end := consumeTerminators(text, i)
+		if isSentenceEnd(text, start, i, end) {
+			sentences = append(sentences, span{start: start, end: end})
+			start = skipSpace(text, end)
+			i = start
+			continue
+		}
+		i = end
+	}
+
+	if start < len(text) {
+		end := len(text)
+		for end > start {
+			r, size := utf8.DecodeLastRuneInString(text[:end])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			end -= size
+		}
+		if end > start {
+			sentences = append(sentences, span{start: start, end: end})
+		}
+	}
+
+	return sentences
+}
+
+// isSentenceEnd decides whether the terminator run text[pos:end] closes the
+// sentence that began at start
+func isSentenceEnd(text string, start, pos, end int) bool {
+	if end >= len(text) {
+		return true
+	}
+
+	// Full-width terminators are not followed by spaces in CJK text
+	r, _ := utf8.DecodeRuneInString(text[pos:])
+	if r == '。' || r == '！' || r == '？' {
+		return true
+	}
+
+	// Decimals, URLs and version numbers have no space after the period
+	next, _ := utf8.DecodeRuneInString(text[end:])
+	if !unicode.IsSpace(next) {
+		return false
+	}
+
+	nextWord := text[skipSpace(text, end):]
+	run := text[pos:end]
+
+	// Ellipses only end a sentence when a new one visibly begins
+	if strings.Contains(run, "...") || strings.ContainsRune(run, '…') {
+		return startsCapitalized(nextWord)
+	}
+
+	if strings.ContainsAny(run, "!?") {
+		return true
+	}
+
+	word := strings.ToLower(lastWord(text[start:pos]))
+	switch {
+	case abbreviations[word]:
+		return false
+	case ambiguousAbbreviations[word]:
+		return startsCapitalized(nextWord)
+	case isInitial(word):
+		return false
+	}
+
+	return true
+}
+
+// consumeTerminators returns the offset just past a run of terminators and
+// any closing quotes or brackets that follow them
+func consumeTerminators(text string, i int) int {
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !isTerminator(r) && !isCloser(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// lastWord returns the final whitespace-separated word of s without
+// leading punctuation such as quotes or brackets
+func lastWord(s string) string {
+	if idx := strings.LastIndexFunc(s, unicode.IsSpace); idx >= 0 {
+		s = s[idx+1:]
+	}
+	return strings.TrimLeftFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// isInitial reports whether word is a single letter such as the "J" in "J. Smith"
+func isInitial(word string) bool {
+	return utf8.RuneCountInString(word) == 1 && unicode.IsLetter([]rune(word)[0])
+}
+
+// startsCapitalized reports whether s begins, after any opening quotes, with
+// an upper-case letter or digit
+func startsCapitalized(s string) bool {
+	for _, r := range s {
+		if strings.ContainsRune("\"'“‘([", r) {
+			continue
+		}
+		return unicode.IsUpper(r) || unicode.IsDigit(r)
+	}
+	return false
+}
+
+// skipSpace returns the offset of the first non-space rune at or after i
+func skipSpace(text string, i int) int {
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+func isTerminator(r rune) bool {
+	switch r {
+	case '.', '!', '?', '…', '。', '！', '？':
+		return true
+	}
+	return false
+}
+
+func isCloser(r rune) bool {
+	switch r {
+	case '"', '\'', '”', '’', ')', ']', '}', '»':
+		return true
+	}
+	return false
+}
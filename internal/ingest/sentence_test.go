@@ -0,0 +1,157 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+
+	"wafer/internal/config"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "simple sentences",
+			text: "First sentence. Second one! Third?",
+			want: []string{"First sentence.", "Second one!", "Third?"},
+		},
+		{
+			name: "abbreviations",
+			text: "Mr. Smith met Dr. Jones. They talked.",
+			want: []string{"Mr. Smith met Dr. Jones.", "They talked."},
+		},
+		{
+			name: "latin abbreviations",
+			text: "Use tools, e.g. hammers, i.e. simple ones. Done.",
+			want: []string{"Use tools, e.g. hammers, i.e. simple ones.", "Done."},
+		},
+		{
+			name: "decimals",
+			text: "Pi is about 3.14 in value. Version 1.2.3 shipped.",
+			want: []string{"Pi is about 3.14 in value.", "Version 1.2.3 shipped."},
+		},
+		{
+			name: "closing quotes",
+			text: `He said "Stop." Then he left.`,
+			want: []string{`He said "Stop."`, "Then he left."},
+		},
+		{
+			name: "ellipsis mid sentence",
+			text: "Well... maybe not. Ok.",
+			want: []string{"Well... maybe not.", "Ok."},
+		},
+		{
+			name: "ellipsis ending sentence",
+			text: "It faded away… Nobody noticed.",
+			want: []string{"It faded away…", "Nobody noticed."},
+		},
+		{
+			name: "initials",
+			text: "J. R. R. Tolkien wrote books. Many read them.",
+			want: []string{"J. R. R. Tolkien wrote books.", "Many read them."},
+		},
+		{
+			name: "trailing text without terminator",
+			text: "Complete sentence. trailing fragment  ",
+			want: []string{"Complete sentence.", "trailing fragment"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := splitSentences(tt.text)
+			var got []string
+			for _, s := range spans {
+				got = append(got, tt.text[s.start:s.end])
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("splitSentences() got %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("sentence %d: got %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSentenceStrategy_PacksWholeSentences(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 8, ChunkStrategy: config.StrategySentence})
+
+	text := "One two three four. Five six seven. Eight nine ten eleven twelve. Thirteen."
+	chunks := chunker.ChunkText(text)
+
+	want := []string{
+		"One two three four. Five six seven.",
+		"Eight nine ten eleven twelve. Thirteen.",
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("ChunkText() got %d chunks, want %d", len(chunks), len(want))
+	}
+	for i, chunk := range chunks {
+		if chunk.Text != want[i] {
+			t.Errorf("chunk %d: got %q, want %q", i, chunk.Text, want[i])
+		}
+		if chunk.Index != i {
+			t.Errorf("chunk %d has index %d", i, chunk.Index)
+		}
+		if chunk.WordCount > 8 {
+			t.Errorf("chunk %d exceeds budget with %d words", i, chunk.WordCount)
+		}
+	}
+}
+
+func TestSentenceStrategy_SplitsOversizedSentence(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 5, ChunkStrategy: config.StrategySentence})
+
+	text := "Short one. " + strings.TrimSpace(strings.Repeat("long ", 12)) + ". Tail here."
+	chunks := chunker.ChunkText(text)
+
+	// "Short one." | 5 + 5 + 2 words of the long sentence | "Tail here."
+	if len(chunks) != 5 {
+		t.Fatalf("ChunkText() got %d chunks, want 5: %+v", len(chunks), chunks)
+	}
+	if chunks[0].Text != "Short one." {
+		t.Errorf("first chunk = %q", chunks[0].Text)
+	}
+	if chunks[4].Text != "Tail here." {
+		t.Errorf("last chunk = %q", chunks[4].Text)
+	}
+	for _, chunk := range chunks {
+		if chunk.WordCount > 5 {
+			t.Errorf("chunk %d exceeds budget with %d words", chunk.Index, chunk.WordCount)
+		}
+	}
+}
+
+func TestSentenceStrategy_Overlap(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 6, ChunkOverlap: 1, ChunkStrategy: config.StrategySentence})
+
+	chunks := chunker.ChunkText("One two. Three four. Five six. Seven eight.")
+
+	want := []struct {
+		text         string
+		overlapWords int
+		overlapChars int
+	}{
+		{"One two. Three four. Five six.", 0, 0},
+		{"Five six. Seven eight.", 2, len("Five six.")},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("ChunkText() got %d chunks, want %d: %+v", len(chunks), len(want), chunks)
+	}
+	for i, w := range want {
+		if chunks[i].Text != w.text {
+			t.Errorf("chunk %d: got %q, want %q", i, chunks[i].Text, w.text)
+		}
+		if chunks[i].OverlapWords != w.overlapWords || chunks[i].OverlapChars != w.overlapChars {
+			t.Errorf("chunk %d: got overlap %d words/%d chars, want %d/%d",
+				i, chunks[i].OverlapWords, chunks[i].OverlapChars, w.overlapWords, w.overlapChars)
+		}
+	}
+}
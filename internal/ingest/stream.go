@@ -0,0 +1,200 @@
+package ingest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// streamWindow is how much text is read ahead before the chunks whose
+	// boundaries are settled get emitted
+	streamWindow = 256 * 1024
+
+	// streamBlock is the size of each read from the underlying reader
+	streamBlock = 64 * 1024
+)
+
+// windowedStrategy is a Strategy whose chunk boundaries depend only on the
+// text before them, so a document can be split one window at a time
+type windowedStrategy interface {
+	Strategy
+	windowed()
+}
+
+func (s *wordStrategy) windowed()     {}
+func (s *sentenceStrategy) windowed() {}
+
+// ChunkReader splits the text read from r with the configured strategy,
+// calling fn with each chunk as soon as its boundaries are settled. Memory
+// use is bounded by the read-ahead window rather than the size of the input.
+func (c *Chunker) ChunkReader(r io.Reader, fn func(Chunk) error) error {
+	return c.chunkReader(c.strategy, r, fn)
+}
+
+// StreamFile chunks a file incrementally with the strategy for its type,
+// calling fn with each chunk as it becomes available
+func (c *Chunker) StreamFile(filePath string, fn func(Chunk) error) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	return c.chunkReader(c.strategyFor(filePath), file, fn)
+}
+
+// chunkReader streams r through the strategy. Each window is chunked from
+// the start of the last chunk of the previous window, since that chunk may
+// still grow; every chunk before it is final and is emitted.
+func (c *Chunker) chunkReader(strategy Strategy, r io.Reader, fn func(Chunk) error) error {
+	return c.chunkPassages(strategy, r, func(chunk Chunk, _ string) error {
+		return fn(chunk)
+	})
+}
+
+// chunkPassages streams r through the strategy like chunkReader, also
+// passing fn the original bytes each chunk was cut from, line endings and
+// all, which are gone once the window moves on
+func (c *Chunker) chunkPassages(strategy Strategy, r io.Reader, fn func(chunk Chunk, passage string) error) error {
+	if _, ok := strategy.(windowedStrategy); !ok {
+		// Structure-aware strategies need the whole document
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to read text: %w", err)
+		}
+		for _, chunk := range c.chunkText(strategy, string(content)) {
+			if err := fn(chunk, string(content[chunk.StartByte:chunk.EndByte])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var pending []byte // Text from the first chunk not yet emitted
+	base, line := 0, 1 // Original byte offset and line of pending[0]
+	index := 0
+	var held *Chunk // Chunk held back from the previous window
+	block := make([]byte, streamBlock)
+	want := c.window
+	eof := false
+
+	for {
+		for len(pending) < want && !eof {
+			n, err := r.Read(block)
+			pending = append(pending, block[:n]...)
+			if err == io.EOF {
+				eof = true
+			} else if err != nil {
+				return fmt.Errorf("failed to read text: %w", err)
+			}
+		}
+
+		overlap := 0
+		if held != nil {
+			overlap = held.OverlapWords
+		}
+		chunks := c.chunkWindow(strategy, string(pending), overlap, eof)
+		for i := range chunks {
+			chunks[i].StartByte += base
+			chunks[i].EndByte += base
+			chunks[i].StartLine += line - 1
+			chunks[i].EndLine += line - 1
+		}
+		if held != nil && len(chunks) > 0 {
+			chunks = resumeChunks(held, chunks)
+		}
+
+		// Everything before the last chunk is settled, along with the earlier
+		// pieces of a sentence the last chunk continues. The chunk before the
+		// last is held back too when a trailing runt may be merged into it. A
+		// sentence far longer than the window is cut at its last piece to
+		// keep memory bounded.
+		keep := len(chunks)
+		if !eof && keep > 0 {
+			keep--
+			if c.minSize > 0 && keep > 0 {
+				keep--
+			}
+			settled := keep
+			for keep > 0 && chunks[keep].continues {
+				keep--
+			}
+			if keep == 0 && len(pending) >= 4*c.window {
+				keep = settled
+			}
+		}
+
+		for _, chunk := range chunks[:keep] {
+			chunk.Index = index
+			index++
+			if err := fn(chunk, string(pending[chunk.StartByte-base:chunk.EndByte-base])); err != nil {
+				return err
+			}
+		}
+
+		if eof {
+			return nil
+		}
+
+		cut := 0
+		if keep < len(chunks) {
+			held = &chunks[keep]
+			cut = held.StartByte - base
+			line = held.StartLine
+		} else if len(pending) >= 4*c.window {
+			// A long run without words is dropped, keeping only a word that
+			// may still be being read
+			cut = lastSpace(pending) + 1
+			if cut > 0 && cut == len(pending) && pending[cut-1] == '\r' {
+				cut-- // The "\n" of a "\r\n" may be in the next read
+			}
+			line += countLineBreaks(string(pending[:cut]))
+		}
+		base += cut
+		pending = append([]byte(nil), pending[cut:]...)
+		want = len(pending) + c.window
+	}
+}
+
+// resumeChunks reconciles the chunks of a new window with the chunk held
+// back from the previous one. The window restarts at the held chunk, so its
+// first chunk repeats the held overlap, which the window only counts in words.
+func resumeChunks(held *Chunk, chunks []Chunk) []Chunk {
+	first := &chunks[0]
+	if first.StartByte != held.StartByte || held.OverlapWords == 0 {
+		return chunks
+	}
+
+	// Only the repeated sentences fit before the next one, which the
+	// sentence strategy would have dropped rather than emitted on their own
+	if len(first.Text) <= prefixBytes(held.Text, held.OverlapChars) {
+		return chunks[1:]
+	}
+
+	first.OverlapWords = held.OverlapWords
+	first.OverlapChars = held.OverlapChars
+	return chunks
+}
+
+// prefixBytes returns the length in bytes of the first n characters of text
+func prefixBytes(text string, n int) int {
+	offset := 0
+	for i := 0; i < n && offset < len(text); i++ {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// lastSpace returns the offset of the last whitespace byte in text, or -1
+func lastSpace(text []byte) int {
+	for i := len(text) - 1; i >= 0; i-- {
+		if text[i] < utf8.RuneSelf && unicode.IsSpace(rune(text[i])) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,203 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"wafer/internal/config"
+)
+
+// streamSample builds a document with varied sentences, line endings and an
+// over-long sentence so that window edges land in awkward places
+func streamSample() string {
+	var b strings.Builder
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&b, "Sentence %d talks about Dr. Smith and item %d.  ", i, i*7)
+		if i%5 == 0 {
+			b.WriteString("\r\n\r\n")
+		}
+		if i == 17 {
+			b.WriteString(strings.Repeat("endless words without a stop ", 8) + "here. ")
+		}
+		if i%9 == 0 {
+			b.WriteString("Short one! ")
+		}
+		if i == 23 {
+			b.WriteString("“Quoted” ＷＩＤＥ exam-\r\nple text\u200b with   gaps. ")
+		}
+	}
+	return b.String()
+}
+
+func TestChunker_ChunkReader_MatchesChunkText(t *testing.T) {
+	text := streamSample()
+
+	tests := []struct {
+		name string
+		cfg  config.Config
+
+		// Smallest window at which the output must match; below it the
+		// over-long sentence exceeds the read-ahead bound and is cut
+		minWindow int
+	}{
+		{"word", config.Config{ChunkSize: 12}, 0},
+		{"word with overlap", config.Config{ChunkSize: 12, ChunkOverlap: 5}, 0},
+		{"sentence", config.Config{ChunkSize: 20, ChunkStrategy: config.StrategySentence}, 0},
+		{"sentence with overlap", config.Config{ChunkSize: 20, ChunkOverlap: 2, ChunkStrategy: config.StrategySentence}, 0},
+		{"single chunk", config.Config{ChunkSize: 5000}, 0},
+		{"word with runt merge", config.Config{ChunkSize: 12, ChunkOverlap: 3, MinChunkSize: 11}, 0},
+		{"word with runt rebalance", config.Config{ChunkSize: 12, MinChunkSize: 11, MinChunkPolicy: config.MinChunkRebalance}, 0},
+		{"word with overlap and runt rebalance", config.Config{ChunkSize: 12, ChunkOverlap: 6, MinChunkSize: 8,
+			MinChunkPolicy: config.MinChunkRebalance}, 0},
+		{"word with normalization", config.Config{ChunkSize: 12, Normalize: []string{config.NormalizeNFKC, config.NormalizeControls,
+			config.NormalizeQuotes, config.NormalizeDehyphenate, config.NormalizeWhitespace, config.NormalizeLowercase}}, 0},
+		{"sentence with runt merge", config.Config{ChunkSize: 20, ChunkStrategy: config.StrategySentence, MinChunkSize: 15}, 97},
+	}
+
+	for _, tt := range tests {
+		for _, window := range []int{16, 40, 97, 150, 333, 1024} {
+			if window < tt.minWindow {
+				continue
+			}
+			t.Run(fmt.Sprintf("%s/window=%d", tt.name, window), func(t *testing.T) {
+				chunker := NewChunker(&tt.cfg)
+				want := chunker.ChunkText(text)
+
+				chunker.window = window
+				var got []Chunk
+				err := chunker.ChunkReader(iotest.OneByteReader(strings.NewReader(text)), func(chunk Chunk) error {
+					got = append(got, chunk)
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("ChunkReader() error = %v", err)
+				}
+
+				if len(got) != len(want) {
+					t.Fatalf("ChunkReader() got %d chunks, want %d", len(got), len(want))
+				}
+				for i := range want {
+					// Only the exported fields are part of the result
+					got[i].continues, want[i].continues = false, false
+					if !reflect.DeepEqual(got[i], want[i]) {
+						t.Errorf("chunk %d:\n got %+v\nwant %+v", i, got[i], want[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestChunker_ChunkReader_RebalancesResumedOverlap(t *testing.T) {
+	words := make([]string, 20)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%d", i)
+	}
+	text := strings.Join(words, " ")
+
+	// The last window starts at a chunk that repeats the overlap of the one
+	// before it, which rebalancing must leave in place
+	chunker := NewChunker(&config.Config{ChunkSize: 8, ChunkOverlap: 6, MinChunkSize: 3, MinChunkPolicy: config.MinChunkRebalance})
+	want := chunker.ChunkText(text)
+
+	chunker.window = 16
+	var got []Chunk
+	if err := chunker.ChunkReader(strings.NewReader(text), func(chunk Chunk) error {
+		got = append(got, chunk)
+		return nil
+	}); err != nil {
+		t.Fatalf("ChunkReader() error = %v", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("ChunkReader() got %d chunks, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Text != want[i].Text || got[i].OverlapWords != want[i].OverlapWords {
+			t.Errorf("chunk %d = %q (%d repeated), want %q (%d repeated)",
+				i, got[i].Text, got[i].OverlapWords, want[i].Text, want[i].OverlapWords)
+		}
+	}
+}
+
+func TestChunker_ChunkReader_StopsOnCallbackError(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 3})
+	chunker.window = 16
+
+	stop := errors.New("stop")
+	calls := 0
+	err := chunker.ChunkReader(strings.NewReader(strings.Repeat("one two three ", 100)), func(Chunk) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("ChunkReader() error = %v, want %v", err, stop)
+	}
+	if calls != 1 {
+		t.Errorf("callback called %d times after failing, want 1", calls)
+	}
+}
+
+func TestChunker_ChunkReader_ReadError(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 10})
+
+	err := chunker.ChunkReader(iotest.ErrReader(errors.New("disk failure")), func(Chunk) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "disk failure") {
+		t.Errorf("ChunkReader() error = %v, want read error", err)
+	}
+}
+
+func TestChunker_ChunkReader_NoWords(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 10})
+	chunker.window = 8
+
+	text := strings.Repeat("--- *** \r\n", 20) + "finally words"
+	var got []Chunk
+	err := chunker.ChunkReader(iotest.OneByteReader(strings.NewReader(text)), func(chunk Chunk) error {
+		got = append(got, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChunkReader() error = %v", err)
+	}
+
+	// A long run without words is not buffered while waiting for one
+	if len(got) != 1 || !strings.HasSuffix(got[0].Text, "finally words") {
+		t.Fatalf("ChunkReader() got %+v", got)
+	}
+	if got[0].StartByte < len(text)/2 {
+		t.Errorf("chunk starts at byte %d, want the leading run dropped", got[0].StartByte)
+	}
+	if want := 1 + countLineBreaks(text[:got[0].StartByte]); got[0].StartLine != want {
+		t.Errorf("chunk starts on line %d, want %d", got[0].StartLine, want)
+	}
+	if text[got[0].StartByte:got[0].EndByte] != strings.ReplaceAll(got[0].Text, "\n", "\r\n") {
+		t.Errorf("offsets %d-%d do not locate %q", got[0].StartByte, got[0].EndByte, got[0].Text)
+	}
+}
+
+func TestChunker_ChunkReader_EmitsBeforeEOF(t *testing.T) {
+	chunker := NewChunker(&config.Config{ChunkSize: 50})
+	chunker.window = 1024
+
+	// The reader fails after 64KB, so anything emitted came before the end
+	text := strings.Repeat("streaming keeps memory bounded ", 3000)
+	r := io.MultiReader(strings.NewReader(text[:64*1024]), iotest.ErrReader(errors.New("unreachable")))
+
+	emitted := 0
+	err := chunker.ChunkReader(iotest.OneByteReader(r), func(Chunk) error {
+		emitted++
+		return nil
+	})
+	if err == nil {
+		t.Fatal("ChunkReader() expected the read error")
+	}
+	if emitted == 0 {
+		t.Error("ChunkReader() emitted no chunks before reaching the end of the input")
+	}
+}
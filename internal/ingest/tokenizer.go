@@ -0,0 +1,442 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTokenCacheEntries bounds the per-word token cache so long runs over
+// large vocabularies do not grow memory without limit
+const maxTokenCacheEntries = 50000
+
+// Tokenizer splits text into model tokens so chunks can be sized against an
+// embedding model's context window
+type Tokenizer interface {
+	// Tokenize returns the model tokens for text, excluding special tokens
+	Tokenize(text string) []string
+}
+
+// tokenizerFile mirrors the parts of a Hugging Face tokenizer.json we use
+type tokenizerFile struct {
+	Normalizer   *tokenizerStage `json:"normalizer"`
+	PreTokenizer *tokenizerStage `json:"pre_tokenizer"`
+	Model        struct {
+		Type                    string            `json:"type"`
+		Vocab                   map[string]int    `json:"vocab"`
+		Merges                  []json.RawMessage `json:"merges"`
+		UnkToken                *string           `json:"unk_token"`
+		ContinuingSubwordPrefix *string           `json:"continuing_subword_prefix"`
+		MaxInputCharsPerWord    int               `json:"max_input_chars_per_word"`
+		ByteFallback            bool              `json:"byte_fallback"`
+	} `json:"model"`
+}
+
+// tokenizerStage describes a normalizer or pre-tokenizer, possibly a sequence
+type tokenizerStage struct {
+	Type          string           `json:"type"`
+	Lowercase     *bool            `json:"lowercase"`
+	Normalizers   []tokenizerStage `json:"normalizers"`
+	PreTokenizers []tokenizerStage `json:"pretokenizers"`
+}
+
+// has reports whether the stage or any stage nested in a sequence has the given type
+func (s *tokenizerStage) has(stageType string) bool {
+	if s == nil {
+		return false
+	}
+	if s.Type == stageType {
+		return true
+	}
+	for i := range s.Normalizers {
+		if s.Normalizers[i].has(stageType) {
+			return true
+		}
+	}
+	for i := range s.PreTokenizers {
+		if s.PreTokenizers[i].has(stageType) {
+			return true
+		}
+	}
+	return false
+}
+
+// lowercases reports whether the normalizer lowercases its input
+func (s *tokenizerStage) lowercases() bool {
+	if s == nil {
+		return false
+	}
+	if s.Type == "Lowercase" || (s.Type == "BertNormalizer" && (s.Lowercase == nil || *s.Lowercase)) {
+		return true
+	}
+	for i := range s.Normalizers {
+		if s.Normalizers[i].lowercases() {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadTokenizer reads a Hugging Face tokenizer.json file backed by a
+// WordPiece or BPE model
+func LoadTokenizer(path string) (Tokenizer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokenizer file %s: %w", path, err)
+	}
+
+	var file tokenizerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tokenizer file %s: %w", path, err)
+	}
+
+	if len(file.Model.Vocab) == 0 {
+		return nil, fmt.Errorf("tokenizer file %s has an empty vocabulary", path)
+	}
+
+	base := baseTokenizer{
+		lowercase: file.Normalizer.lowercases(),
+		cache:     make(map[string][]string),
+	}
+
+	switch file.Model.Type {
+	case "WordPiece":
+		return newWordPieceTokenizer(base, &file), nil
+	case "BPE":
+		return newBPETokenizer(base, &file)
+	default:
+		return nil, fmt.Errorf("unsupported tokenizer model type %q (want WordPiece or BPE)", file.Model.Type)
+	}
+}
+
+// baseTokenizer holds the normalization and caching shared by all models
+type baseTokenizer struct {
+	lowercase bool
+	cache     map[string][]string
+}
+
+// tokenizeWord returns the cached tokens for word, computing them with fn on a miss
+func (b *baseTokenizer) tokenizeWord(word string, fn func(string) []string) []string {
+	if tokens, ok := b.cache[word]; ok {
+		return tokens
+	}
+	if len(b.cache) >= maxTokenCacheEntries {
+		b.cache = make(map[string][]string)
+	}
+	tokens := fn(word)
+	b.cache[word] = tokens
+	return tokens
+}
+
+// wordPieceTokenizer implements BERT-style greedy longest-match-first WordPiece
+type wordPieceTokenizer struct {
+	baseTokenizer
+	vocab    map[string]int
+	unk      string
+	prefix   string
+	maxChars int
+}
+
+func newWordPieceTokenizer(base baseTokenizer, file *tokenizerFile) *wordPieceTokenizer {
+	t := &wordPieceTokenizer{
+		baseTokenizer: base,
+		vocab:         file.Model.Vocab,
+		unk:           "[UNK]",
+		prefix:        "##",
+		maxChars:      file.Model.MaxInputCharsPerWord,
+	}
+	if file.Model.UnkToken != nil {
+		t.unk = *file.Model.UnkToken
+	}
+	if file.Model.ContinuingSubwordPrefix != nil {
+		t.prefix = *file.Model.ContinuingSubwordPrefix
+	}
+	if t.maxChars <= 0 {
+		t.maxChars = 100
+	}
+	return t
+}
+
+// Tokenize implements Tokenizer
+func (t *wordPieceTokenizer) Tokenize(text string) []string {
+	if t.lowercase {
+		text = strings.ToLower(text)
+	}
+
+	var tokens []string
+	for _, word := range splitBertWords(text) {
+		tokens = append(tokens, t.tokenizeWord(word, t.wordPiece)...)
+	}
+	return tokens
+}
+
+// wordPiece splits a single pre-token into vocabulary pieces
+func (t *wordPieceTokenizer) wordPiece(word string) []string {
+	runes := []rune(word)
+	if len(runes) > t.maxChars {
+		return []string{t.unk}
+	}
+
+	var pieces []string
+	for start := 0; start < len(runes); {
+		match := ""
+		end := len(runes)
+		for ; end > start; end-- {
+			piece := string(runes[start:end])
+			if start > 0 {
+				piece = t.prefix + piece
+			}
+			if _, ok := t.vocab[piece]; ok {
+				match = piece
+				break
+			}
+		}
+		if match == "" {
+			return []string{t.unk}
+		}
+		pieces = append(pieces, match)
+		start = end
+	}
+	return pieces
+}
+
+// splitBertWords splits on whitespace and isolates punctuation and CJK
+// ideographs, matching BERT's basic tokenizer
+func splitBertWords(text string) []string {
+	var words []string
+	start := -1
+
+	for i, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			if start >= 0 {
+				words = append(words, text[start:i])
+				start = -1
+			}
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.Is(unicode.Han, r):
+			if start >= 0 {
+				words = append(words, text[start:i])
+				start = -1
+			}
+			words = append(words, string(r))
+		default:
+			if start < 0 {
+				start = i
+			}
+		}
+	}
+	if start >= 0 {
+		words = append(words, text[start:])
+	}
+
+	return words
+}
+
+// bpeTokenizer implements byte-pair encoding with GPT-2 byte-level or
+// SentencePiece-style metaspace pre-tokenization
+type bpeTokenizer struct {
+	baseTokenizer
+	vocab        map[string]int
+	ranks        map[[2]string]int
+	unk          string
+	byteLevel    bool
+	metaspace    bool
+	byteFallback bool
+}
+
+func newBPETokenizer(base baseTokenizer, file *tokenizerFile) (*bpeTokenizer, error) {
+	t := &bpeTokenizer{
+		baseTokenizer: base,
+		vocab:         file.Model.Vocab,
+		ranks:         make(map[[2]string]int, len(file.Model.Merges)),
+		byteLevel:     file.PreTokenizer.has("ByteLevel"),
+		metaspace:     file.PreTokenizer.has("Metaspace"),
+		byteFallback:  file.Model.ByteFallback,
+	}
+	if file.Model.UnkToken != nil {
+		t.unk = *file.Model.UnkToken
+	}
+
+	for rank, raw := range file.Model.Merges {
+		pair, err := parseMerge(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid merge at rank %d: %w", rank, err)
+		}
+		if _, exists := t.ranks[pair]; !exists {
+			t.ranks[pair] = rank
+		}
+	}
+
+	return t, nil
+}
+
+// parseMerge accepts both the "a b" and ["a", "b"] merge encodings
+func parseMerge(raw json.RawMessage) ([2]string, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		parts := strings.SplitN(text, " ", 2)
+		if len(parts) != 2 {
+			return [2]string{}, fmt.Errorf("merge %q is not a pair", text)
+		}
+		return [2]string{parts[0], parts[1]}, nil
+	}
+
+	var parts []string
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return [2]string{}, err
+	}
+	if len(parts) != 2 {
+		return [2]string{}, fmt.Errorf("merge %v is not a pair", parts)
+	}
+	return [2]string{parts[0], parts[1]}, nil
+}
+
+// Tokenize implements Tokenizer
+func (t *bpeTokenizer) Tokenize(text string) []string {
+	if t.lowercase {
+		text = strings.ToLower(text)
+	}
+
+	var words []string
+	switch {
+	case t.byteLevel:
+		for _, word := range splitByteLevelWords(text) {
+			words = append(words, toByteLevel(word))
+		}
+	case t.metaspace:
+		for _, word := range strings.Fields(text) {
+			words = append(words, "▁"+word)
+		}
+	default:
+		words = strings.Fields(text)
+	}
+
+	var tokens []string
+	for _, word := range words {
+		tokens = append(tokens, t.tokenizeWord(word, t.bpe)...)
+	}
+	return tokens
+}
+
+// bpe repeatedly merges the lowest-ranked adjacent pair of symbols in word
+func (t *bpeTokenizer) bpe(word string) []string {
+	symbols := make([]string, 0, len(word))
+	for _, r := range word {
+		symbols = append(symbols, string(r))
+	}
+
+	for len(symbols) > 1 {
+		best := -1
+		bestRank := 0
+		for i := 0; i < len(symbols)-1; i++ {
+			rank, ok := t.ranks[[2]string{symbols[i], symbols[i+1]}]
+			if ok && (best < 0 || rank < bestRank) {
+				best = i
+				bestRank = rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		symbols[best] += symbols[best+1]
+		symbols = append(symbols[:best+1], symbols[best+2:]...)
+	}
+
+	var tokens []string
+	for _, symbol := range symbols {
+		switch {
+		case t.hasToken(symbol):
+			tokens = append(tokens, symbol)
+		case t.byteFallback:
+			for i := 0; i < len(symbol); i++ {
+				tokens = append(tokens, fmt.Sprintf("<0x%02X>", symbol[i]))
+			}
+		case t.unk != "":
+			tokens = append(tokens, t.unk)
+		default:
+			tokens = append(tokens, symbol)
+		}
+	}
+	return tokens
+}
+
+func (t *bpeTokenizer) hasToken(token string) bool {
+	_, ok := t.vocab[token]
+	return ok
+}
+
+// splitByteLevelWords approximates the GPT-2 pre-tokenization pattern: runs
+// of letters, digits or other symbols, each keeping one leading space
+func splitByteLevelWords(text string) []string {
+	var words []string
+	i := 0
+
+	for i < len(text) {
+		start := i
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		// A single space attaches to the word that follows it
+		if r == ' ' && i+size < len(text) {
+			next, _ := utf8.DecodeRuneInString(text[i+size:])
+			if !unicode.IsSpace(next) {
+				i += size
+				r, size = next, utf8.RuneLen(next)
+			}
+		}
+
+		class := runeClass(r)
+		i += size
+		for i < len(text) {
+			next, nextSize := utf8.DecodeRuneInString(text[i:])
+			if runeClass(next) != class {
+				break
+			}
+			i += nextSize
+		}
+		words = append(words, text[start:i])
+	}
+
+	return words
+}
+
+// runeClass groups runes the way the GPT-2 pre-tokenizer does
+func runeClass(r rune) int {
+	switch {
+	case unicode.IsLetter(r):
+		return 1
+	case unicode.IsNumber(r):
+		return 2
+	case unicode.IsSpace(r):
+		return 3
+	default:
+		return 4
+	}
+}
+
+// byteLevelAlphabet maps every byte to the printable rune GPT-2 uses for it
+var byteLevelAlphabet = func() [256]rune {
+	var alphabet [256]rune
+	next := rune(256)
+	for b := 0; b < 256; b++ {
+		printable := (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF)
+		if printable {
+			alphabet[b] = rune(b)
+		} else {
+			alphabet[b] = next
+			next++
+		}
+	}
+	return alphabet
+}()
+
+// toByteLevel rewrites the UTF-8 bytes of word into the byte-level alphabet
+func toByteLevel(word string) string {
+	var b strings.Builder
+	for i := 0; i < len(word); i++ {
+		b.WriteRune(byteLevelAlphabet[word[i]])
+	}
+	return b.String()
+}
@@ -0,0 +1,122 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"wafer/internal/config"
+)
+
+const wordPieceTokenizerJSON = `{
+	"normalizer": {"type": "BertNormalizer", "lowercase": true},
+	"pre_tokenizer": {"type": "BertPreTokenizer"},
+	"model": {
+		"type": "WordPiece",
+		"unk_token": "[UNK]",
+		"continuing_subword_prefix": "##",
+		"max_input_chars_per_word": 100,
+		"vocab": {"[UNK]": 0, "hello": 1, "world": 2, "un": 3, "##aff": 4, "##able": 5, ",": 6, "!": 7}
+	}
+}`
+
+const bpeTokenizerJSON = `{
+	"normalizer": null,
+	"pre_tokenizer": {"type": "ByteLevel", "add_prefix_space": false},
+	"model": {
+		"type": "BPE",
+		"vocab": {"h": 0, "e": 1, "l": 2, "o": 3, "Ġ": 4, "w": 5, "r": 6, "d": 7, "hello": 8, "Ġworld": 9},
+		"merges": ["h e", "l l", "he ll", "hell o", ["Ġ", "w"], "o r", "Ġw or", "Ġwor l", "Ġworl d"]
+	}
+}`
+
+// writeTokenizer stores a tokenizer definition in a temporary tokenizer.json
+func writeTokenizer(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tokenizer.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write tokenizer file: %v", err)
+	}
+	return path
+}
+
+func TestLoadTokenizer_WordPiece(t *testing.T) {
+	tokenizer, err := LoadTokenizer(writeTokenizer(t, wordPieceTokenizerJSON))
+	if err != nil {
+		t.Fatalf("LoadTokenizer() error = %v", err)
+	}
+
+	got := tokenizer.Tokenize("Hello, unaffable world! zebra")
+	want := []string{"hello", ",", "un", "##aff", "##able", "world", "!", "[UNK]"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() got %q, want %q", got, want)
+	}
+}
+
+func TestLoadTokenizer_BPE(t *testing.T) {
+	tokenizer, err := LoadTokenizer(writeTokenizer(t, bpeTokenizerJSON))
+	if err != nil {
+		t.Fatalf("LoadTokenizer() error = %v", err)
+	}
+
+	got := tokenizer.Tokenize("hello world")
+	want := []string{"hello", "Ġworld"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() got %q, want %q", got, want)
+	}
+}
+
+func TestLoadTokenizer_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid json", `{`},
+		{"empty vocabulary", `{"model": {"type": "WordPiece", "vocab": {}}}`},
+		{"unsupported model", `{"model": {"type": "Unigram", "vocab": {"a": 0}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadTokenizer(writeTokenizer(t, tt.content)); err == nil {
+				t.Error("LoadTokenizer() expected error")
+			}
+		})
+	}
+
+	if _, err := LoadTokenizer("/non/existent/tokenizer.json"); err == nil {
+		t.Error("LoadTokenizer() expected error for missing file")
+	}
+}
+
+func TestChunker_TokenBudget(t *testing.T) {
+	tokenizer, err := LoadTokenizer(writeTokenizer(t, wordPieceTokenizerJSON))
+	if err != nil {
+		t.Fatalf("LoadTokenizer() error = %v", err)
+	}
+
+	chunker := NewChunker(&config.Config{ChunkSize: 6, ChunkUnit: config.UnitTokens})
+	chunker.SetTokenizer(tokenizer)
+
+	// Each "unaffable" costs three tokens, so only two fit in a chunk
+	chunks := chunker.ChunkText("unaffable unaffable unaffable hello")
+
+	want := []struct {
+		text       string
+		words      int
+		tokenCount int
+	}{
+		{"unaffable unaffable", 2, 6},
+		{"unaffable hello", 2, 4},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("ChunkText() got %d chunks, want %d: %+v", len(chunks), len(want), chunks)
+	}
+	for i, w := range want {
+		if chunks[i].Text != w.text || chunks[i].WordCount != w.words || chunks[i].TokenCount != w.tokenCount {
+			t.Errorf("chunk %d: got %q (%d words, %d tokens), want %q (%d words, %d tokens)",
+				i, chunks[i].Text, chunks[i].WordCount, chunks[i].TokenCount, w.text, w.words, w.tokenCount)
+		}
+	}
+}
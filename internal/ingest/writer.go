@@ -12,13 +12,34 @@ import (
 
 // VectorRecord represents a single record in the JSONL output
 type VectorRecord struct {
-	ID         string    `json:"id"`
-	SourceFile string    `json:"source_file"`
-	ChunkIndex int       `json:"chunk_index"`
-	Text       string    `json:"text"`
-	Embedding  []float64 `json:"embedding"`
-	WordCount  int       `json:"word_count"`
-	CreatedAt  string    `json:"created_at"`
+	ID                 string            `json:"id"`
+	SourceFile         string            `json:"source_file"`
+	ChunkIndex         int               `json:"chunk_index"`
+	Text               string            `json:"text"`
+	Embedding          []float64         `json:"embedding"`
+	WordCount          int               `json:"word_count"`
+	CharCount          int               `json:"char_count"`
+	TokenCount         int               `json:"token_count,omitempty"`
+	Section            string            `json:"section,omitempty"`
+	Symbol             string            `json:"symbol,omitempty"`
+	Language           string            `json:"language,omitempty"`
+	CodeLanguage       string            `json:"code_language,omitempty"`
+	LanguageConfidence float64           `json:"language_confidence,omitempty"`
+	StartByte          int               `json:"start_byte"`
+	EndByte            int               `json:"end_byte"`
+	StartLine          int               `json:"start_line"`
+	EndLine            int               `json:"end_line"`
+	OverlapWords       int               `json:"overlap_words,omitempty"`
+	OverlapChars       int               `json:"overlap_chars,omitempty"`
+	ParentID           string            `json:"parent_id,omitempty"`
+	Level              string            `json:"level,omitempty"`
+	EmbeddedText       string            `json:"embedded_text,omitempty"`
+	DuplicateOf        string            `json:"duplicate_of,omitempty"`
+	DetectedEncoding   string            `json:"detected_encoding,omitempty"`
+	Redactions         map[string]int    `json:"redactions,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+	PageNumber         int               `json:"page_number,omitempty"`
+	CreatedAt          string            `json:"created_at"`
 }
 
 // Writer handles writing JSONL output
@@ -49,15 +70,41 @@ func NewWriter(outputPath string) (*Writer, error) {
 
 // WriteRecord writes a single vector record to the JSONL file
 func (w *Writer) WriteRecord(sourceFile string, chunk Chunk, embedding []float64) error {
+	id := chunk.ID
+	if id == "" {
+		id = uuid.New().String()
+	}
+
 	// Create the record
 	record := VectorRecord{
-		ID:         uuid.New().String(),
-		SourceFile: sourceFile,
-		ChunkIndex: chunk.Index,
-		Text:       chunk.Text,
-		Embedding:  embedding,
-		WordCount:  chunk.WordCount,
-		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+		ID:                 id,
+		SourceFile:         sourceFile,
+		ChunkIndex:         chunk.Index,
+		Text:               chunk.Text,
+		Embedding:          embedding,
+		WordCount:          chunk.WordCount,
+		CharCount:          chunk.CharCount,
+		TokenCount:         chunk.TokenCount,
+		Section:            chunk.Section,
+		Symbol:             chunk.Symbol,
+		Language:           chunk.Language,
+		CodeLanguage:       chunk.CodeLanguage,
+		LanguageConfidence: chunk.LanguageConfidence,
+		StartByte:          chunk.StartByte,
+		EndByte:            chunk.EndByte,
+		StartLine:          chunk.StartLine,
+		EndLine:            chunk.EndLine,
+		OverlapWords:       chunk.OverlapWords,
+		OverlapChars:       chunk.OverlapChars,
+		ParentID:           chunk.ParentID,
+		Level:              chunk.Level,
+		EmbeddedText:       chunk.ContextText,
+		DuplicateOf:        chunk.DuplicateOf,
+		DetectedEncoding:   chunk.Encoding,
+		Redactions:         chunk.Redactions,
+		Metadata:           chunk.Metadata,
+		PageNumber:         chunk.PageNumber,
+		CreatedAt:          time.Now().UTC().Format(time.RFC3339),
 	}
 
 	// Marshal to JSON
@@ -153,3 +153,71 @@ func TestWriter_WriteAfterClose(t *testing.T) {
 		t.Error("WriteRecord() expected error after close")
 	}
 }
+
+func TestWriter_ChunkMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	outputPath := filepath.Join(tmpDir, "test_output.jsonl")
+
+	writer, err := NewWriter(outputPath)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	chunk := Chunk{
+		Text:         "shared tail and new text",
+		WordCount:    5,
+		CharCount:    24,
+		TokenCount:   7,
+		Index:        1,
+		Section:      "Install > Linux",
+		Symbol:       "Chunker.ChunkText",
+		CodeLanguage: "go",
+		StartByte:    120,
+		EndByte:      144,
+		StartLine:    4,
+		EndLine:      5,
+		OverlapWords: 2,
+		OverlapChars: 11,
+		ID:           "child-id",
+		ParentID:     "parent-id",
+		Level:        ChunkChild,
+		ContextText:  "test.txt — Install > Linux\n\nshared tail and new text",
+	}
+	if err := writer.WriteRecord("test.txt", chunk, []float64{0.1}); err != nil {
+		t.Fatalf("WriteRecord() error = %v", err)
+	}
+	writer.Close()
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(content, &record); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"char_count":    24.0,
+		"token_count":   7.0,
+		"section":       "Install > Linux",
+		"symbol":        "Chunker.ChunkText",
+		"code_language": "go",
+		"start_byte":    120.0,
+		"end_byte":      144.0,
+		"start_line":    4.0,
+		"end_line":      5.0,
+		"overlap_words": 2.0,
+		"overlap_chars": 11.0,
+		"id":            "child-id",
+		"parent_id":     "parent-id",
+		"level":         "child",
+		"embedded_text": "test.txt — Install > Linux\n\nshared tail and new text",
+	}
+	for field, value := range want {
+		if record[field] != value {
+			t.Errorf("Expected %s %v, got %v", field, value, record[field])
+		}
+	}
+}
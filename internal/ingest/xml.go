@@ -0,0 +1,78 @@
+package ingest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xmlExtractor extracts the character data of an XML document. Text runs
+// split by markup are joined with a space and the line structure of the
+// document is kept; the first <title> element is kept as metadata.
+type xmlExtractor struct{}
+
+// Extract implements Extractor
+func (xmlExtractor) Extract(data []byte) ([]ExtractedText, error) {
+	decoder := xml.NewDecoder(strings.NewReader(string(data)))
+
+	// The content was decoded to UTF-8 whatever its declaration says
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var b strings.Builder
+	var title strings.Builder
+	inTitle, boundary := 0, false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			boundary = true
+			if strings.EqualFold(t.Name.Local, "title") && title.Len() == 0 {
+				inTitle++
+			} else if inTitle > 0 {
+				inTitle++
+			}
+		case xml.EndElement:
+			boundary = true
+			if inTitle > 0 {
+				inTitle--
+			}
+		case xml.CharData:
+			text := string(t)
+			if inTitle > 0 {
+				title.WriteString(text)
+			}
+			if text == "" {
+				continue
+			}
+
+			// Elements that abut with no whitespace between them still
+			// separate words
+			if boundary && b.Len() > 0 && !isSpace(b.String()[b.Len()-1]) && !isSpace(text[0]) {
+				b.WriteByte(' ')
+			}
+			boundary = false
+			b.WriteString(text)
+		}
+	}
+
+	// Indentation of a pretty-printed document is not part of its text
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	part := ExtractedText{Text: cleanLines(strings.Join(lines, "\n"))}
+	if text := strings.Join(strings.Fields(title.String()), " "); text != "" {
+		part.Metadata = map[string]string{"title": text}
+	}
+	return []ExtractedText{part}, nil
+}
@@ -0,0 +1,48 @@
+package ingest
+
+import "testing"
+
+func TestXMLExtractor(t *testing.T) {
+	tests := []struct {
+		name      string
+		xml       string
+		want      string
+		wantTitle string
+		wantErr   bool
+	}{
+		{
+			name:      "pretty-printed document",
+			xml:       "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<article>\n  <title>Caching &amp; You</title>\n  <para>Some <emphasis>cached</emphasis> text.</para>\n  <!-- draft -->\n  <para><![CDATA[a < b]]></para>\n</article>\n",
+			want:      "Caching & You\nSome cached text.\n\na < b",
+			wantTitle: "Caching & You",
+		},
+		{
+			name: "compact elements",
+			xml:  "<items><item>one</item><item>two</item></items>",
+			want: "one two",
+		},
+		{
+			name:    "malformed",
+			xml:     "<a><b></a>",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := xmlExtractor{}.Extract([]byte(tt.xml))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Extract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := parts[0].Text; got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+			if got := parts[0].Metadata["title"]; got != tt.wantTitle {
+				t.Errorf("title = %q, want %q", got, tt.wantTitle)
+			}
+		})
+	}
+}
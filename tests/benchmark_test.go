@@ -4,12 +4,13 @@ import (
 	"strings"
 	"testing"
 
+	"wafer/internal/config"
 	"wafer/internal/ingest"
 )
 
 // BenchmarkChunker tests the performance of text chunking
 func BenchmarkChunker(b *testing.B) {
-	chunker := ingest.NewChunker(300)
+	chunker := ingest.NewChunker(&config.Config{ChunkSize: 300})
 
 	// Create test text of various sizes
 	testCases := []struct {
@@ -36,7 +37,7 @@ func BenchmarkChunker(b *testing.B) {
 
 // BenchmarkChunkerMemory tests memory allocation during chunking
 func BenchmarkChunkerMemory(b *testing.B) {
-	chunker := ingest.NewChunker(300)
+	chunker := ingest.NewChunker(&config.Config{ChunkSize: 300})
 	text := strings.Repeat("word ", 1000)
 
 	b.ResetTimer()
@@ -56,7 +57,7 @@ func BenchmarkChunkerDifferentSizes(b *testing.B) {
 
 	for _, size := range chunkSizes {
 		b.Run(string(rune(size)), func(b *testing.B) {
-			chunker := ingest.NewChunker(size)
+			chunker := ingest.NewChunker(&config.Config{ChunkSize: size})
 			b.ResetTimer()
 
 			for i := 0; i < b.N; i++ {
@@ -104,7 +105,7 @@ func BenchmarkJSONLWriting(b *testing.B) {
 
 // BenchmarkTextProcessingPipeline tests the complete text processing pipeline
 func BenchmarkTextProcessingPipeline(b *testing.B) {
-	chunker := ingest.NewChunker(300)
+	chunker := ingest.NewChunker(&config.Config{ChunkSize: 300})
 	text := strings.Repeat("This is a benchmark test for the complete text processing pipeline. ", 100)
 
 	tmpDir := b.TempDir()
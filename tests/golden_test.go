@@ -49,6 +49,18 @@ func TestGoldenFiles(t *testing.T) {
 	golden.Run(t, "tests/golden", ".txt", ".jsonl", func(srcPath string) ([]byte, error) {
 		return runWaferOnFile(t, srcPath)
 	})
+
+	golden.Run(t, "tests/golden", ".md", ".jsonl", func(srcPath string) ([]byte, error) {
+		return runWaferOnFile(t, srcPath)
+	})
+
+	golden.Run(t, "tests/golden", ".html", ".jsonl", func(srcPath string) ([]byte, error) {
+		return runWaferOnFile(t, srcPath)
+	})
+
+	golden.Run(t, "tests/golden", ".csv", ".jsonl", func(srcPath string) ([]byte, error) {
+		return runWaferOnFile(t, srcPath)
+	})
 }
 
 func runWaferOnFile(t *testing.T, srcPath string) ([]byte, error) {
@@ -205,7 +217,7 @@ func runWaferOnFile(t *testing.T, srcPath string) ([]byte, error) {
 		}
 
 		// Validate required fields
-		requiredFields := []string{"id", "source_file", "chunk_index", "text", "embedding", "word_count", "created_at"}
+		requiredFields := []string{"id", "source_file", "chunk_index", "text", "embedding", "word_count", "char_count", "start_byte", "end_byte", "start_line", "end_line", "created_at"}
 		for _, field := range requiredFields {
 			if _, exists := record[field]; !exists {
 				return nil, fmt.Errorf("missing required field '%s' on line %d", field, i+1)
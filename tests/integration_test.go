@@ -94,7 +94,7 @@ func TestEndToEndProcessing(t *testing.T) {
 
 	testProcessor := &TestProcessor{
 		config:   cfg,
-		chunker:  ingest.NewChunker(cfg.ChunkSize),
+		chunker:  ingest.NewChunker(cfg),
 		embedder: embedder,
 	}
 
@@ -15,7 +15,7 @@ import (
 )
 
 func TestChunker(t *testing.T) {
-	chunker := ingest.NewChunker(10) // Small chunk size for testing
+	chunker := ingest.NewChunker(&config.Config{ChunkSize: 10}) // Small chunk size for testing
 
 	tests := []struct {
 		name      string
@@ -78,7 +78,7 @@ func TestChunkerFile(t *testing.T) {
 		t.Fatalf("Failed to create test file: %v", err)
 	}
 
-	chunker := ingest.NewChunker(300)
+	chunker := ingest.NewChunker(&config.Config{ChunkSize: 300})
 	chunks, err := chunker.ChunkFile(testFile)
 	if err != nil {
 		t.Fatalf("ChunkFile() error = %v", err)